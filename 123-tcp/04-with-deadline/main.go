@@ -2,48 +2,78 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"log"
-	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"tcp/11-server/server"
 	"time"
 )
 
+// NOTE: Deadlines
+/*
+	SetDeadline(time.Now().Add(10 * time.Second)) once per connection closes it 10 seconds after
+	it was accepted, even in the middle of a conversation. server.Conn moves the deadline instead:
+
+	- idle: a client that sends nothing for IdleTimeout is disconnected
+	- active: once a line starts it has to arrive within ReadTimeout
+	- each reply has to be written within WriteTimeout
+
+	so the scanner below ends when the client goes quiet, and "Code got here" is printed.
+*/
+
 func main() {
-	server, err := net.Listen("tcp", ":8080")
-	if err != nil {
-		log.Fatalln(err)
-	}
-	defer server.Close()
+	srv := server.New(server.Config{
+		MaxConns:     100,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 5 * time.Second,
+		IdleTimeout:  10 * time.Second,
+	}, server.HandlerFunc(handle))
 
+	go func() {
+		err := srv.ListenAndServe(":8080")
+		if !errors.Is(err, server.ErrServerClosed) {
+			log.Fatalln(err)
+		}
+	}()
 	fmt.Println("Server is running on port :8080")
 
-	for {
-		connection, err := server.Accept()
-		if err != nil {
-			log.Println(err)
-		}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
 
-		go handle(connection)
+	// * ctrl+c: stop accepting, close the idle clients and give the others 5 seconds
+	fmt.Println("Shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Println(err)
 	}
 }
 
-func handle(connection net.Conn) {
-	defer connection.Close()
-
-	err := connection.SetDeadline(time.Now().Add(10 * time.Second))
-	if err != nil {
-		log.Fatalln(err)
-	}
-
-	scanner := bufio.NewScanner(connection)
-	for scanner.Scan() {
-		line := scanner.Text()
+func handle(ctx context.Context, connection *server.Conn) {
+	reader := bufio.NewReader(connection)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+		line = strings.TrimRight(line, "\r\n")
 		fmt.Println(line)
 		fmt.Fprintf(connection, "I head you said %s\n", line)
+
+		if ctx.Err() != nil {
+			return
+		}
+		if reader.Buffered() == 0 {
+			// * the next line gets IdleTimeout to start
+			connection.SetIdle()
+		}
 	}
 
-	// * We never get here
-	// * we have an open stream connection
-	// * How does the above reader know when it's done?
 	fmt.Println("Code got here")
 }
@@ -1,69 +1,59 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
-	"net"
-	"strings"
+	"os"
+	"os/signal"
+	"syscall"
+	"tcp/09-http-parser/http1"
+	"tcp/11-server/server"
+	"time"
 )
 
 func main() {
-	listener, err := net.Listen("tcp", ":8080")
-	if err != nil {
-		log.Fatalln(err)
-	}
-	defer listener.Close()
+	// * the server reads the requests of each connection, with its limits and timeouts
+	srv := server.New(server.DefaultConfig(), server.HTTP(http1.HandlerFunc(handle), http1.DefaultLimits()))
 
-	fmt.Println("Server is started at port 8080")
-
-	for {
-		connection, err := listener.Accept()
-		if err != nil {
+	go func() {
+		err := srv.ListenAndServe(":8080")
+		if !errors.Is(err, server.ErrServerClosed) {
 			log.Fatalln(err)
 		}
+	}()
+	fmt.Println("Server is started at port 8080")
 
-		go handle(connection)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	// * the requests in flight are answered before the server exits
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Println(err)
 	}
 }
 
-func handle(connection net.Conn) {
-	defer connection.Close()
-
-	uri := request(connection)
-
-	respond(connection, uri)
+func handle(w http1.ResponseWriter, req *http1.Request) {
+	request(req)
+	respond(w, req.Target)
 }
 
-func request(connection net.Conn) (uri string) {
-	iterator := 0
-	scanner := bufio.NewScanner(connection)
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		fmt.Println(line)
-
-		if iterator == 0 {
-			// * request line
-			fields := strings.Fields(line)
-			method := fields[0]
-			uri = fields[1]
-			fmt.Println("***METHOD", method)
-			fmt.Println("***URI", uri)
-		}
-		if line == "" {
-			// * headers are done
-			break
-		}
-		iterator++
+func request(req *http1.Request) {
+	// * request line
+	fmt.Println(req.Method, req.Target, req.Proto)
+	fmt.Println("***METHOD", req.Method)
+	fmt.Println("***URI", req.Target)
+	for name, values := range req.Header {
+		fmt.Printf("%s: %v\n", name, values)
 	}
-
-	return
 }
 
-func respond(connection net.Conn, uri string) {
+func respond(w http1.ResponseWriter, uri string) {
 	tpl, err := template.New("response").Parse(`
 		<!DOCTYPE html>
 		<html lang="en">
@@ -83,18 +73,9 @@ func respond(connection net.Conn, uri string) {
 		log.Panicln(err)
 	}
 
-	var bodyBuf bytes.Buffer
-	err = tpl.ExecuteTemplate(&bodyBuf, "response", uri)
-	if err != nil {
-		log.Panicln(err)
-	}
-
-	fmt.Fprint(connection, "HTTP/1.1 200 OK\r\n")
-	fmt.Fprintf(connection, "Content-Length: %d\r\n", bodyBuf.Len())
-	fmt.Fprint(connection, "Content-Type: text/html\r\n")
-	fmt.Fprint(connection, "\r\n")
-
-	_, err = bodyBuf.WriteTo(connection)
+	// * the response buffers the body, the server sends it with its Content-Length
+	w.Header().Set("Content-Type", "text/html")
+	err = tpl.ExecuteTemplate(w, "response", uri)
 	if err != nil {
 		log.Panicln(err)
 	}
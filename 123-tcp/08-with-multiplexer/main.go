@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"tcp/09-http-parser/http1"
+	"tcp/10-router/router"
+	"tcp/11-server/server"
+	"time"
+)
+
+var tpl = template.Must(template.New("templates").Parse(`
+	{{define "not-found"}}
+		<h2>The page you're looking for is not found!</h2>
+	{{end}}
+
+	{{define "home"}}
+		<h2>I'm sending this response using a tcp server.</h2>
+	{{end}}
+
+	{{define "user"}}
+		<h2>Hello {{.}}!</h2>
+	{{end}}
+
+	{{define "response"}}
+		<!DOCTYPE html>
+		<html lang="en">
+		<head>
+			<meta charset="UTF-8" />
+			<title>Welcome to my http server!</title>
+		</head>
+		<body>
+			<h1>Http Server</h1>
+			<hr />
+			<h2>Request: {{.URI}}</h2>
+			{{template "page" .}}
+		</body>
+		</html>
+	{{end}}
+`))
+
+func main() {
+	// * the mux resolves the handler of each request, the handlers never see the connection
+	mux := router.New()
+	mux.GET("/", home)
+	mux.GET("/user/:name", user)
+	mux.NotFound = http1.HandlerFunc(notFound)
+
+	srv := server.New(server.DefaultConfig(), server.HTTP(logged(mux), http1.DefaultLimits()))
+
+	go func() {
+		err := srv.ListenAndServe(":8080")
+		if !errors.Is(err, server.ErrServerClosed) {
+			log.Fatalln(err)
+		}
+	}()
+	fmt.Println("Server is started at port 8080")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Println(err)
+	}
+}
+
+// logged prints each request before h handles it
+func logged(h http1.Handler) http1.Handler {
+	return http1.HandlerFunc(func(w http1.ResponseWriter, req *http1.Request) {
+		request(req)
+		h.ServeHTTP(w, req)
+	})
+}
+
+func request(req *http1.Request) {
+	// * request line
+	fmt.Println(req.Method, req.Target, req.Proto)
+	fmt.Println("***METHOD", req.Method)
+	fmt.Println("***URI", req.Target)
+	for name, values := range req.Header {
+		fmt.Printf("%s: %v\n", name, values)
+	}
+}
+
+func home(w http1.ResponseWriter, r *http1.Request, _ router.Params) {
+	respond(w, http1.StatusOK, "home", r.Target, nil)
+}
+
+func user(w http1.ResponseWriter, r *http1.Request, ps router.Params) {
+	respond(w, http1.StatusOK, "user", r.Target, ps.ByName("name"))
+}
+
+func notFound(w http1.ResponseWriter, r *http1.Request) {
+	respond(w, http1.StatusNotFound, "not-found", r.Target, nil)
+}
+
+// respond renders the page template inside the response template
+func respond(w http1.ResponseWriter, status int, page, uri string, data any) {
+	t, err := tpl.Clone()
+	if err == nil {
+		_, err = t.New("page").Parse(`{{template "` + page + `" .Data}}`)
+	}
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http1.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(status)
+	err = t.ExecuteTemplate(w, "response", struct {
+		URI  string
+		Data any
+	}{uri, data})
+	if err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,120 @@
+package http1
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// fixedBody is a body of Content-Length bytes, or none
+type fixedBody struct {
+	br   *bufio.Reader
+	left int64
+}
+
+func (b *fixedBody) Read(p []byte) (int, error) {
+	if b.left <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > b.left {
+		p = p[:b.left]
+	}
+	n, err := b.br.Read(p)
+	b.left -= int64(n)
+	if err == io.EOF && b.left > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// chunkedBody decodes a chunked body:
+//
+//	5;ext=1\r\n
+//	hello\r\n
+//	0\r\n
+//	Trailer-Field: value\r\n
+//	\r\n
+type chunkedBody struct {
+	r     *Reader
+	req   *Request
+	left  int64 // left of the current chunk
+	total int64
+	err   error // sticky, io.EOF once the trailer is read
+}
+
+func (b *chunkedBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	if b.left == 0 {
+		if b.err = b.nextChunk(); b.err != nil {
+			return 0, b.err
+		}
+	}
+
+	if int64(len(p)) > b.left {
+		p = p[:b.left]
+	}
+	n, err := b.r.br.Read(p)
+	b.left -= int64(n)
+	b.total += int64(n)
+	switch {
+	case b.total > b.r.limits.MaxBodyBytes:
+		err = errorf(StatusContentTooLarge, "chunked body over %d bytes", b.r.limits.MaxBodyBytes)
+	case err == io.EOF:
+		err = io.ErrUnexpectedEOF
+	case err == nil && b.left == 0:
+		err = b.chunkEnd()
+	}
+	if err != nil {
+		b.err = err
+		if n > 0 && err != io.EOF {
+			return n, nil // * the error comes with the next Read
+		}
+	}
+	return n, err
+}
+
+// nextChunk reads a chunk size line, and the trailer after the last chunk
+func (b *chunkedBody) nextChunk() error {
+	line, err := b.r.readLine(b.r.limits.MaxLineBytes)
+	if errors.Is(err, errLineTooLong) {
+		return errorf(StatusBadRequest, "chunk size line longer than %d bytes", b.r.limits.MaxLineBytes)
+	}
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	size, _, _ := strings.Cut(string(line), ";") // * extensions are allowed and ignored
+	size = strings.TrimRight(size, " \t")
+	if size == "" || len(size) > 15 || strings.Trim(size, "0123456789abcdefABCDEF") != "" {
+		return errorf(StatusBadRequest, "invalid chunk size %q", line)
+	}
+	n, _ := strconv.ParseInt(size, 16, 64) // * 15 hex digits always fit
+	if n == 0 {
+		b.req.Trailer, err = b.r.readHeader(b.r.limits.MaxHeaderBytes, b.r.limits.MaxHeaders)
+		if err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	b.left = n
+	return nil
+}
+
+// chunkEnd reads the CRLF after the data of a chunk
+func (b *chunkedBody) chunkEnd() error {
+	line, err := b.r.readLine(2)
+	if errors.Is(err, errLineTooLong) || (err == nil && len(line) != 0) {
+		return errorf(StatusBadRequest, "chunk data longer than its size")
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,65 @@
+package http1
+
+import "fmt"
+
+// Error is a request the server has to refuse. Status is the response to send,
+// the connection can't be read any further and is closed after it.
+type Error struct {
+	Status int
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.Status, StatusText(e.Status), e.Reason)
+}
+
+func errorf(status int, format string, args ...any) *Error {
+	return &Error{Status: status, Reason: fmt.Sprintf(format, args...)}
+}
+
+const (
+	StatusOK                          = 200
+	StatusCreated                     = 201
+	StatusNoContent                   = 204
+	StatusMovedPermanently            = 301
+	StatusFound                       = 302
+	StatusSeeOther                    = 303
+	StatusNotModified                 = 304
+	StatusBadRequest                  = 400
+	StatusNotFound                    = 404
+	StatusMethodNotAllowed            = 405
+	StatusRequestTimeout              = 408
+	StatusContentTooLarge             = 413
+	StatusURITooLong                  = 414
+	StatusRequestHeaderFieldsTooLarge = 431
+	StatusInternalServerError         = 500
+	StatusNotImplemented              = 501
+	StatusServiceUnavailable          = 503
+	StatusHTTPVersionNotSupported     = 505
+)
+
+var statusText = map[int]string{
+	StatusOK:                          "OK",
+	StatusCreated:                     "Created",
+	StatusNoContent:                   "No Content",
+	StatusMovedPermanently:            "Moved Permanently",
+	StatusFound:                       "Found",
+	StatusSeeOther:                    "See Other",
+	StatusNotModified:                 "Not Modified",
+	StatusBadRequest:                  "Bad Request",
+	StatusNotFound:                    "Not Found",
+	StatusMethodNotAllowed:            "Method Not Allowed",
+	StatusRequestTimeout:              "Request Timeout",
+	StatusContentTooLarge:             "Content Too Large",
+	StatusURITooLong:                  "URI Too Long",
+	StatusRequestHeaderFieldsTooLarge: "Request Header Fields Too Large",
+	StatusInternalServerError:         "Internal Server Error",
+	StatusNotImplemented:              "Not Implemented",
+	StatusServiceUnavailable:          "Service Unavailable",
+	StatusHTTPVersionNotSupported:     "HTTP Version Not Supported",
+}
+
+// StatusText is the reason phrase of a status code, empty for a code this package doesn't use
+func StatusText(code int) string {
+	return statusText[code]
+}
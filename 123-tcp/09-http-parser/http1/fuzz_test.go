@@ -0,0 +1,112 @@
+package http1
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// FuzzReadRequest reads the requests of a connection. Whatever the bytes, reading never panics,
+// fails only with the documented errors and stays within the limits. A request that parses
+// parses the same once written again with a Content-Length body.
+//
+// The seed corpus is in testdata/fuzz/FuzzReadRequest, run with
+//
+//	go test -fuzz FuzzReadRequest ./http1
+func FuzzReadRequest(f *testing.F) {
+	f.Add([]byte("GET / HTTP/1.1\r\nHost: x\r\n\r\n"))
+	f.Add([]byte("POST /a?b=c HTTP/1.1\r\nHost: x\r\nContent-Length: 3\r\n\r\nabcGET / HTTP/1.0\r\n\r\n"))
+	f.Add([]byte("POST / HTTP/1.1\r\nHost: x\r\nTransfer-Encoding: chunked\r\n\r\n3;x\r\nabc\r\n0\r\nT: 1\r\n\r\n"))
+
+	limits := Limits{MaxLineBytes: 256, MaxHeaderBytes: 1024, MaxHeaders: 16, MaxBodyBytes: 1024}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := NewReader(strings.NewReader(string(data)), limits)
+		for range 8 {
+			req, err := r.ReadRequest()
+			if err != nil {
+				checkError(t, err)
+				return
+			}
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				checkError(t, err)
+				return
+			}
+			checkRequest(t, req, body, limits)
+
+			again, err := NewReader(strings.NewReader(serialize(req, body)), limits).ReadRequest()
+			if err != nil {
+				t.Fatalf("written again %q doesn't parse: %v", serialize(req, body), err)
+			}
+			againBody, _ := io.ReadAll(again.Body)
+			if again.Method != req.Method || again.Path != req.Path || again.RawQuery != req.RawQuery ||
+				again.Close != req.Close || string(againBody) != string(body) {
+				t.Fatalf("written again parses as %+v, want %+v", again, req)
+			}
+		}
+	})
+}
+
+func checkError(t *testing.T, err error) {
+	var herr *Error
+	switch {
+	case err == io.EOF, err == io.ErrUnexpectedEOF:
+	case errors.As(err, &herr):
+		if StatusText(herr.Status) == "" || herr.Status < 400 {
+			t.Fatalf("error with status %d: %v", herr.Status, err)
+		}
+	default:
+		t.Fatalf("unexpected error %T %v", err, err)
+	}
+}
+
+func checkRequest(t *testing.T, req *Request, body []byte, limits Limits) {
+	if !isToken(req.Method) || !strings.HasPrefix(req.Path, "/") || req.Major != 1 {
+		t.Fatalf("parsed an invalid request line: %q %q %q", req.Method, req.Target, req.Proto)
+	}
+	if req.Minor >= 1 && len(req.Header["Host"]) != 1 {
+		t.Fatalf("parsed an HTTP/1.1 request with Host %q", req.Header["Host"])
+	}
+	if int64(len(body)) > limits.MaxBodyBytes {
+		t.Fatalf("read a body of %d bytes, the limit is %d", len(body), limits.MaxBodyBytes)
+	}
+	if req.ContentLength >= 0 && int64(len(body)) != req.ContentLength {
+		t.Fatalf("read %d bytes of a body of %d", len(body), req.ContentLength)
+	}
+	for name, values := range req.Header {
+		for _, v := range values {
+			if !isToken(name) || !validValue(v) || strings.TrimSpace(v) != v {
+				t.Fatalf("parsed an invalid field %q: %q", name, v)
+			}
+		}
+	}
+}
+
+// serialize writes a parsed request back, its body with a Content-Length
+func serialize(req *Request, body []byte) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s\r\n", req.Method, req.Target, req.Proto)
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if name == "Content-Length" || name == "Transfer-Encoding" {
+			continue
+		}
+		for _, v := range req.Header[name] {
+			fmt.Fprintf(&b, "%s: %s\r\n", name, v)
+		}
+	}
+	if len(body) > 0 {
+		fmt.Fprintf(&b, "Content-Length: %d\r\n", len(body))
+	}
+	b.WriteString("\r\n")
+	b.Write(body)
+	return b.String()
+}
@@ -0,0 +1,95 @@
+package http1
+
+import (
+	"strings"
+)
+
+// Header maps canonical field names (Content-Length) to their values in the order received
+type Header map[string][]string
+
+// Get returns the first value of the field, matched case-insensitively
+func (h Header) Get(name string) string {
+	if v := h[CanonicalName(name)]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (h Header) Values(name string) []string {
+	return h[CanonicalName(name)]
+}
+
+func (h Header) Add(name, value string) {
+	name = CanonicalName(name)
+	h[name] = append(h[name], value)
+}
+
+func (h Header) Set(name, value string) {
+	h[CanonicalName(name)] = []string{value}
+}
+
+// tokens returns the comma separated elements of every value of the field, lower cased.
+// Connection and Transfer-Encoding are lists like "keep-alive, Upgrade".
+func (h Header) tokens(name string) []string {
+	var out []string
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				out = append(out, strings.ToLower(t))
+			}
+		}
+	}
+	return out
+}
+
+// CanonicalName upper cases the first letter of the name and of each word after a hyphen,
+// lower cases the rest. A name that isn't a token is returned unchanged.
+func CanonicalName(name string) string {
+	if !isToken(name) {
+		return name
+	}
+	b := []byte(name)
+	upper := true
+	for i, c := range b {
+		switch {
+		case upper && 'a' <= c && c <= 'z':
+			b[i] = c - 'a' + 'A'
+		case !upper && 'A' <= c && c <= 'Z':
+			b[i] = c - 'A' + 'a'
+		}
+		upper = c == '-'
+	}
+	return string(b)
+}
+
+// isToken reports whether s is a token of RFC 9110: methods and field names
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isTokenChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isTokenChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0
+}
+
+// validValue reports whether a field value holds only visible characters, spaces and tabs.
+// Bytes above 0x7f (obs-text) are let through, CR, LF and NUL never are.
+func validValue(v string) bool {
+	for i := 0; i < len(v); i++ {
+		if c := v[i]; (c < 0x20 && c != '\t') || c == 0x7f {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,365 @@
+// Package http1 reads HTTP/1.1 requests off a connection, without net/http. It checks the
+// request line and headers, bounds what a client can make it buffer, decodes Content-Length
+// and chunked bodies and tells whether the connection stays open for another request.
+package http1
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Limits bounds what a client can make the server buffer
+type Limits struct {
+	MaxLineBytes   int   // request line and chunk size lines, 414 above
+	MaxHeaderBytes int   // header lines together, and trailer lines together, 431 above
+	MaxHeaders     int   // header fields, 431 above
+	MaxBodyBytes   int64 // decoded body, 413 above
+}
+
+func DefaultLimits() Limits {
+	return Limits{
+		MaxLineBytes:   8 << 10,
+		MaxHeaderBytes: 64 << 10,
+		MaxHeaders:     100,
+		MaxBodyBytes:   10 << 20,
+	}
+}
+
+type Request struct {
+	Method string
+	Target string // the request-target as sent, like /books?id=1
+	Path   string // decoded path of the target, / for the * of OPTIONS
+	// RawQuery is the query of the target without the ?, see Query
+	RawQuery     string
+	Proto        string // HTTP/1.1
+	Major, Minor int
+
+	Header Header
+	// Trailer holds the fields after a chunked body, once Body is read to the end
+	Trailer Header
+
+	// Body is never nil, it returns io.EOF right away for a request without body.
+	// Reading it past the limits returns an *Error.
+	Body io.Reader
+	// ContentLength is the length of the body, -1 when it is chunked
+	ContentLength int64
+
+	// Close is set when the connection closes after this request:
+	// Connection: close, or an HTTP/1.0 request without Connection: keep-alive
+	Close bool
+}
+
+// Query parses RawQuery, a malformed pair is skipped
+func (r *Request) Query() url.Values {
+	q, _ := url.ParseQuery(r.RawQuery)
+	return q
+}
+
+// Reader reads the requests of one connection, one after the other
+type Reader struct {
+	br     *bufio.Reader
+	limits Limits
+	last   *Request
+}
+
+func NewReader(r io.Reader, limits Limits) *Reader {
+	return &Reader{br: bufio.NewReader(r), limits: limits}
+}
+
+// Buffered is the number of bytes read from the connection and not parsed yet,
+// the start of a pipelined request
+func (r *Reader) Buffered() int {
+	return r.br.Buffered()
+}
+
+// errLineTooLong is turned into the status matching the line by the callers of readLine
+var errLineTooLong = errors.New("line too long")
+
+// ReadRequest reads the next request. What the handler left of the body of the previous
+// request is read and discarded first.
+//
+// It returns io.EOF when the client closed the connection between two requests,
+// io.ErrUnexpectedEOF when it closed it in the middle of one and an *Error for a request
+// to refuse. After an error the connection can't be read any further.
+func (r *Reader) ReadRequest() (*Request, error) {
+	if r.last != nil {
+		last := r.last
+		r.last = nil
+		if _, err := io.Copy(io.Discard, last.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	line, err := r.requestLine()
+	if err != nil {
+		return nil, err
+	}
+	req, err := parseRequestLine(string(line))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header, err = r.readHeader(r.limits.MaxHeaderBytes, r.limits.MaxHeaders)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.setBody(req); err != nil {
+		return nil, err
+	}
+	req.Close = shouldClose(req)
+
+	r.last = req
+	return req, nil
+}
+
+// requestLine skips the empty lines some clients send after a body, as RFC 9112 allows
+func (r *Reader) requestLine() ([]byte, error) {
+	for range 4 {
+		line, err := r.readLine(r.limits.MaxLineBytes)
+		switch {
+		case errors.Is(err, errLineTooLong):
+			return nil, errorf(StatusURITooLong, "request line longer than %d bytes", r.limits.MaxLineBytes)
+		case err != nil:
+			return nil, err
+		case len(line) > 0:
+			return line, nil
+		}
+	}
+	return nil, errorf(StatusBadRequest, "empty lines instead of a request line")
+}
+
+// readLine reads a line ending in CRLF, or a bare LF, of at most limit bytes with the line
+// end. The line end isn't returned. A bare CR inside the line is refused.
+func (r *Reader) readLine(limit int) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := r.br.ReadSlice('\n')
+		if len(line)+len(chunk) > limit {
+			return nil, errLineTooLong
+		}
+		line = append(line, chunk...)
+		if err == nil {
+			break
+		}
+		if err != bufio.ErrBufferFull {
+			if err == io.EOF && len(line) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+
+	line = line[:len(line)-1]
+	if n := len(line); n > 0 && line[n-1] == '\r' {
+		line = line[:n-1]
+	}
+	if bytes.IndexByte(line, '\r') >= 0 {
+		return nil, errorf(StatusBadRequest, "bare CR in a line")
+	}
+	return line, nil
+}
+
+func parseRequestLine(line string) (*Request, error) {
+	parts := strings.Split(line, " ")
+	if len(parts) != 3 {
+		return nil, errorf(StatusBadRequest, "malformed request line %q", line)
+	}
+	req := &Request{Method: parts[0], Target: parts[1], Proto: parts[2]}
+
+	if !isToken(req.Method) {
+		return nil, errorf(StatusBadRequest, "invalid method %q", req.Method)
+	}
+
+	var ok bool
+	if req.Major, req.Minor, ok = parseVersion(req.Proto); !ok {
+		return nil, errorf(StatusBadRequest, "invalid version %q", req.Proto)
+	}
+	if req.Major != 1 {
+		return nil, errorf(StatusHTTPVersionNotSupported, "version %s", req.Proto)
+	}
+
+	if err := parseTarget(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// parseVersion parses HTTP/x.y, with one digit each
+func parseVersion(v string) (major, minor int, ok bool) {
+	if len(v) != 8 || !strings.HasPrefix(v, "HTTP/") || v[6] != '.' || !isDigit(v[5]) || !isDigit(v[7]) {
+		return 0, 0, false
+	}
+	return int(v[5] - '0'), int(v[7] - '0'), true
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+
+// parseTarget sets Path and RawQuery from the origin form (/path?query), the absolute form
+// (http://host/path?query, sent to proxies) or the * of OPTIONS
+func parseTarget(req *Request) error {
+	target := req.Target
+	for i := 0; i < len(target); i++ {
+		if c := target[i]; c <= ' ' || c == 0x7f || c == '#' {
+			return errorf(StatusBadRequest, "invalid character %q in the target", c)
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(target, "/"):
+	case target == "*" && req.Method == "OPTIONS":
+		req.Path = "/"
+		return nil
+	case req.Method == "CONNECT":
+		return errorf(StatusNotImplemented, "CONNECT")
+	default:
+		scheme, rest, ok := strings.Cut(target, "://")
+		if !ok || (!strings.EqualFold(scheme, "http") && !strings.EqualFold(scheme, "https")) {
+			return errorf(StatusBadRequest, "invalid target %q", target)
+		}
+		if i := strings.IndexAny(rest, "/?"); i >= 0 {
+			target = rest[i:]
+		} else {
+			target = "/"
+		}
+		if !strings.HasPrefix(target, "/") {
+			target = "/" + target
+		}
+	}
+
+	path, query, _ := strings.Cut(target, "?")
+	decoded, err := url.PathUnescape(path)
+	if err != nil {
+		return errorf(StatusBadRequest, "invalid escape in the path %q", path)
+	}
+	req.Path, req.RawQuery = decoded, query
+	return nil
+}
+
+// readHeader reads field lines up to the empty line ending them
+func (r *Reader) readHeader(maxBytes, maxFields int) (Header, error) {
+	h := Header{}
+	fields := 0
+	for {
+		line, err := r.readLine(maxBytes)
+		if errors.Is(err, errLineTooLong) {
+			return nil, errorf(StatusRequestHeaderFieldsTooLarge, "header longer than %d bytes", r.limits.MaxHeaderBytes)
+		}
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		maxBytes -= len(line) + 2
+		if len(line) == 0 {
+			return h, nil
+		}
+
+		if fields++; fields > maxFields {
+			return nil, errorf(StatusRequestHeaderFieldsTooLarge, "more than %d header fields", maxFields)
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			return nil, errorf(StatusBadRequest, "folded header line")
+		}
+		name, value, ok := strings.Cut(string(line), ":")
+		if !ok || !isToken(name) {
+			return nil, errorf(StatusBadRequest, "malformed header line %q", line)
+		}
+		value = strings.Trim(value, " \t")
+		if !validValue(value) {
+			return nil, errorf(StatusBadRequest, "invalid character in the value of %s", name)
+		}
+		h.Add(name, value)
+	}
+}
+
+// setBody frames the body as RFC 9112 section 6 does. A request with both Transfer-Encoding
+// and Content-Length, or with Content-Lengths that disagree, is refused: the two ends could
+// disagree on where it stops and read the rest as another request.
+func (r *Reader) setBody(req *Request) error {
+	if req.Minor >= 1 {
+		if hosts := req.Header.Values("Host"); len(hosts) != 1 {
+			return errorf(StatusBadRequest, "%d Host fields, want 1", len(hosts))
+		}
+	}
+
+	te := req.Header.tokens("Transfer-Encoding")
+	cl := req.Header.Values("Content-Length")
+	switch {
+	case len(te) > 0:
+		if len(cl) > 0 {
+			return errorf(StatusBadRequest, "both Transfer-Encoding and Content-Length")
+		}
+		if req.Minor == 0 {
+			return errorf(StatusBadRequest, "Transfer-Encoding in an HTTP/1.0 request")
+		}
+		if te[len(te)-1] != "chunked" {
+			return errorf(StatusBadRequest, "Transfer-Encoding %s doesn't end with chunked", strings.Join(te, ", "))
+		}
+		if len(te) > 1 {
+			return errorf(StatusNotImplemented, "Transfer-Encoding %s", strings.Join(te, ", "))
+		}
+		req.ContentLength = -1
+		req.Body = &chunkedBody{r: r, req: req}
+
+	case len(cl) > 0:
+		n, err := parseContentLength(cl)
+		if err != nil {
+			return err
+		}
+		if n > r.limits.MaxBodyBytes {
+			return errorf(StatusContentTooLarge, "body of %d bytes, the limit is %d", n, r.limits.MaxBodyBytes)
+		}
+		req.ContentLength = n
+		req.Body = &fixedBody{br: r.br, left: n}
+
+	default:
+		req.Body = &fixedBody{}
+	}
+	return nil
+}
+
+// parseContentLength accepts repeated fields, or a list, only when every value is the same
+func parseContentLength(values []string) (int64, error) {
+	var n int64 = -1
+	for _, v := range values {
+		for _, s := range strings.Split(v, ",") {
+			s = strings.TrimSpace(s)
+			if s == "" || strings.TrimLeft(s, "0123456789") != "" {
+				return 0, errorf(StatusBadRequest, "invalid Content-Length %q", v)
+			}
+			m, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return 0, errorf(StatusContentTooLarge, "Content-Length %s", s)
+			}
+			if n >= 0 && m != n {
+				return 0, errorf(StatusBadRequest, "different Content-Lengths %d and %d", n, m)
+			}
+			n = m
+		}
+	}
+	return n, nil
+}
+
+func shouldClose(req *Request) bool {
+	conn := req.Header.tokens("Connection")
+	for _, t := range conn {
+		if t == "close" {
+			return true
+		}
+	}
+	if req.Minor == 0 {
+		for _, t := range conn {
+			if t == "keep-alive" {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+package http1
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func read(t *testing.T, raw string, limits Limits) (*Request, string, error) {
+	t.Helper()
+	req, err := NewReader(strings.NewReader(raw), limits).ReadRequest()
+	if err != nil {
+		return nil, "", err
+	}
+	body, err := io.ReadAll(req.Body)
+	return req, string(body), err
+}
+
+func TestReadRequest(t *testing.T) {
+	req, body, err := read(t, "POST /books/new%20one?sort=title&x=1 HTTP/1.1\r\n"+
+		"Host: localhost:8080\r\n"+
+		"content-type:  text/plain \r\n"+
+		"Accept: text/html\r\n"+
+		"accept: */*\r\n"+
+		"Content-Length: 5\r\n"+
+		"\r\n"+
+		"hello", DefaultLimits())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.Method != "POST" || req.Path != "/books/new one" || req.RawQuery != "sort=title&x=1" || req.Proto != "HTTP/1.1" {
+		t.Errorf("request line parsed as %s %s ? %s %s", req.Method, req.Path, req.RawQuery, req.Proto)
+	}
+	if got := req.Query().Get("sort"); got != "title" {
+		t.Errorf("query sort = %q", got)
+	}
+	if got := req.Header.Get("Content-Type"); got != "text/plain" {
+		t.Errorf("Content-Type = %q, want the value without spaces", got)
+	}
+	if got := req.Header.Values("ACCEPT"); len(got) != 2 || got[1] != "*/*" {
+		t.Errorf("Accept = %q, want both values in order", got)
+	}
+	if body != "hello" || req.ContentLength != 5 || req.Close {
+		t.Errorf("body %q, length %d, close %v", body, req.ContentLength, req.Close)
+	}
+}
+
+func TestRequestTargets(t *testing.T) {
+	cases := []struct{ line, path, query string }{
+		{"GET / HTTP/1.1", "/", ""},
+		{"GET /a/b/?q= HTTP/1.1", "/a/b/", "q="},
+		{"GET http://example.com/a?b=c HTTP/1.1", "/a", "b=c"},
+		{"GET HTTP://example.com HTTP/1.1", "/", ""},
+		{"OPTIONS * HTTP/1.1", "/", ""},
+	}
+	for _, c := range cases {
+		req, _, err := read(t, c.line+"\r\nHost: x\r\n\r\n", DefaultLimits())
+		if err != nil {
+			t.Errorf("%s: %v", c.line, err)
+			continue
+		}
+		if req.Path != c.path || req.RawQuery != c.query {
+			t.Errorf("%s: path %q query %q, want %q %q", c.line, req.Path, req.RawQuery, c.path, c.query)
+		}
+	}
+}
+
+func TestRefusedRequests(t *testing.T) {
+	limits := DefaultLimits()
+	limits.MaxLineBytes = 64
+	limits.MaxHeaderBytes = 128
+	limits.MaxHeaders = 3
+	limits.MaxBodyBytes = 10
+
+	host := "Host: x\r\n"
+	cases := []struct {
+		name   string
+		raw    string
+		status int
+	}{
+		{"two fields", "GET /\r\n\r\n", StatusBadRequest},
+		{"double space", "GET  / HTTP/1.1\r\n" + host + "\r\n", StatusBadRequest},
+		{"lower case version", "GET / http/1.1\r\n" + host + "\r\n", StatusBadRequest},
+		{"invalid method", "G(T / HTTP/1.1\r\n" + host + "\r\n", StatusBadRequest},
+		{"relative target", "GET books HTTP/1.1\r\n" + host + "\r\n", StatusBadRequest},
+		{"fragment", "GET /#top HTTP/1.1\r\n" + host + "\r\n", StatusBadRequest},
+		{"bad escape", "GET /%zz HTTP/1.1\r\n" + host + "\r\n", StatusBadRequest},
+		{"star without OPTIONS", "GET * HTTP/1.1\r\n" + host + "\r\n", StatusBadRequest},
+		{"connect", "CONNECT example.com:443 HTTP/1.1\r\n" + host + "\r\n", StatusNotImplemented},
+		{"http 2", "GET / HTTP/2.0\r\n" + host + "\r\n", StatusHTTPVersionNotSupported},
+		{"long target", "GET /" + strings.Repeat("a", 64) + " HTTP/1.1\r\n" + host + "\r\n", StatusURITooLong},
+		{"bare CR", "GET / HTTP/1.1\r\nHost: x\rEvil: 1\r\n\r\n", StatusBadRequest},
+		{"no Host", "GET / HTTP/1.1\r\n\r\n", StatusBadRequest},
+		{"two Hosts", "GET / HTTP/1.1\r\n" + host + host + "\r\n", StatusBadRequest},
+		{"space before colon", "GET / HTTP/1.1\r\n" + host + "Accept : */*\r\n\r\n", StatusBadRequest},
+		{"folded", "GET / HTTP/1.1\r\n" + host + "Accept: a\r\n b\r\n\r\n", StatusBadRequest},
+		{"no colon", "GET / HTTP/1.1\r\n" + host + "Accept\r\n\r\n", StatusBadRequest},
+		{"control character", "GET / HTTP/1.1\r\n" + host + "Accept: a\x00b\r\n\r\n", StatusBadRequest},
+		{"too many fields", "GET / HTTP/1.1\r\n" + host + "A: 1\r\nB: 2\r\nC: 3\r\n\r\n", StatusRequestHeaderFieldsTooLarge},
+		{"long header", "GET / HTTP/1.1\r\n" + host + "A: " + strings.Repeat("a", 128) + "\r\n\r\n", StatusRequestHeaderFieldsTooLarge},
+		{"TE and CL", "POST / HTTP/1.1\r\n" + host + "Transfer-Encoding: chunked\r\nContent-Length: 1\r\n\r\n", StatusBadRequest},
+		{"TE in 1.0", "POST / HTTP/1.0\r\nTransfer-Encoding: chunked\r\n\r\n", StatusBadRequest},
+		{"TE not chunked", "POST / HTTP/1.1\r\n" + host + "Transfer-Encoding: gzip\r\n\r\n", StatusBadRequest},
+		{"TE gzip chunked", "POST / HTTP/1.1\r\n" + host + "Transfer-Encoding: gzip, chunked\r\n\r\n", StatusNotImplemented},
+		{"negative CL", "POST / HTTP/1.1\r\n" + host + "Content-Length: -1\r\n\r\n", StatusBadRequest},
+		{"CL with sign", "POST / HTTP/1.1\r\n" + host + "Content-Length: +1\r\n\r\n", StatusBadRequest},
+		{"different CLs", "POST / HTTP/1.1\r\n" + host + "Content-Length: 1\r\nContent-Length: 2\r\n\r\n", StatusBadRequest},
+		{"large CL", "POST / HTTP/1.1\r\n" + host + "Content-Length: 11\r\n\r\n", StatusContentTooLarge},
+		{"huge CL", "POST / HTTP/1.1\r\n" + host + "Content-Length: 99999999999999999999\r\n\r\n", StatusContentTooLarge},
+		{"large chunks", "POST / HTTP/1.1\r\n" + host + "Transfer-Encoding: chunked\r\n\r\n6\r\nabcdef\r\n6\r\nabcdef\r\n0\r\n\r\n", StatusContentTooLarge},
+		{"bad chunk size", "POST / HTTP/1.1\r\n" + host + "Transfer-Encoding: chunked\r\n\r\nx\r\n", StatusBadRequest},
+		{"chunk too long", "POST / HTTP/1.1\r\n" + host + "Transfer-Encoding: chunked\r\n\r\n1\r\nab\r\n0\r\n\r\n", StatusBadRequest},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, _, err := read(t, c.raw, limits)
+			var herr *Error
+			if !errors.As(err, &herr) {
+				t.Fatalf("err = %v, want an *Error", err)
+			}
+			if herr.Status != c.status {
+				t.Errorf("status %d (%v), want %d", herr.Status, herr, c.status)
+			}
+		})
+	}
+}
+
+func TestSameContentLengths(t *testing.T) {
+	_, body, err := read(t, "POST / HTTP/1.1\r\nHost: x\r\nContent-Length: 3, 3\r\nContent-Length: 3\r\n\r\nabc", DefaultLimits())
+	if err != nil || body != "abc" {
+		t.Errorf("body %q, err %v", body, err)
+	}
+}
+
+func TestChunkedBody(t *testing.T) {
+	req, body, err := read(t, "POST /upload HTTP/1.1\r\n"+
+		"Host: x\r\n"+
+		"Transfer-Encoding: Chunked\r\n"+
+		"\r\n"+
+		"5;name=value\r\nhello\r\n"+
+		"7 \r\n, world\r\n"+
+		"0\r\n"+
+		"Checksum: abc\r\n"+
+		"\r\n", DefaultLimits())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "hello, world" || req.ContentLength != -1 {
+		t.Errorf("body %q, length %d", body, req.ContentLength)
+	}
+	if got := req.Trailer.Get("checksum"); got != "abc" {
+		t.Errorf("trailer Checksum = %q", got)
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	for _, raw := range []string{
+		"GET / HTTP/1.1\r\nHost: x\r\n",
+		"GET / HTTP",
+		"POST / HTTP/1.1\r\nHost: x\r\nContent-Length: 10\r\n\r\nabc",
+		"POST / HTTP/1.1\r\nHost: x\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nab",
+		"POST / HTTP/1.1\r\nHost: x\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nabcde\r\n",
+	} {
+		if _, _, err := read(t, raw, DefaultLimits()); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("%q: err = %v, want io.ErrUnexpectedEOF", raw, err)
+		}
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	raw := "\r\nGET /1 HTTP/1.1\r\nHost: x\r\n\r\n" +
+		"POST /2 HTTP/1.1\r\nHost: x\r\nContent-Length: 4\r\n\r\nbody" +
+		"POST /3 HTTP/1.1\r\nHost: x\r\nTransfer-Encoding: chunked\r\n\r\n3\r\nabc\r\n0\r\n\r\n" +
+		"GET /4 HTTP/1.0\r\nConnection: Keep-Alive\r\n\r\n" +
+		"GET /5 HTTP/1.1\r\nHost: x\r\nConnection: close\r\n\r\n"
+	r := NewReader(strings.NewReader(raw), DefaultLimits())
+
+	want := []struct {
+		path  string
+		close bool
+	}{{"/1", false}, {"/2", false}, {"/3", false}, {"/4", false}, {"/5", true}}
+	for _, w := range want {
+		// * the bodies are left unread, ReadRequest skips them
+		req, err := r.ReadRequest()
+		if err != nil {
+			t.Fatalf("%s: %v", w.path, err)
+		}
+		if req.Path != w.path || req.Close != w.close {
+			t.Errorf("got %s close %v, want %s close %v", req.Path, req.Close, w.path, w.close)
+		}
+	}
+	if _, err := r.ReadRequest(); err != io.EOF {
+		t.Errorf("after the last request err = %v, want io.EOF", err)
+	}
+
+	req, _, _ := read(t, "GET / HTTP/1.0\r\n\r\n", DefaultLimits())
+	if !req.Close {
+		t.Error("an HTTP/1.0 request without keep-alive leaves the connection open")
+	}
+}
+
+func TestCanonicalName(t *testing.T) {
+	for in, want := range map[string]string{
+		"content-length":  "Content-Length",
+		"X-FORWARDED-FOR": "X-Forwarded-For",
+		"host":            "Host",
+		"bad name":        "bad name",
+	} {
+		if got := CanonicalName(in); got != want {
+			t.Errorf("CanonicalName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package http1
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Handler answers a request. It writes to a ResponseWriter and never sees the connection,
+// so it can be tested with a Response alone.
+type Handler interface {
+	ServeHTTP(w ResponseWriter, r *Request)
+}
+
+type HandlerFunc func(w ResponseWriter, r *Request)
+
+func (f HandlerFunc) ServeHTTP(w ResponseWriter, r *Request) { f(w, r) }
+
+type ResponseWriter interface {
+	Header() Header
+	// WriteHeader sets the status, only the first call counts
+	WriteHeader(status int)
+	// Write appends to the body, with status 200 if none was set
+	Write(p []byte) (int, error)
+}
+
+// Response is a ResponseWriter that buffers the response, Send sends it with its
+// Content-Length once the handler returned
+type Response struct {
+	Status int
+	Body   bytes.Buffer
+	header Header
+}
+
+func NewResponse() *Response {
+	return &Response{header: Header{}}
+}
+
+func (r *Response) Header() Header { return r.header }
+
+func (r *Response) WriteHeader(status int) {
+	if r.Status == 0 {
+		r.Status = status
+	}
+}
+
+func (r *Response) Write(p []byte) (int, error) {
+	r.WriteHeader(StatusOK)
+	return r.Body.Write(p)
+}
+
+// Send writes the response for req. The body is left out for a HEAD request, and for the
+// statuses that have none. Connection: close is added when the connection closes after it,
+// req is nil for a request that couldn't be read.
+func (r *Response) Send(w io.Writer, req *Request) error {
+	status := r.Status
+	if status == 0 {
+		status = StatusOK
+	}
+	noBody := status == 204 || status == 304 || status < 200
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "HTTP/1.1 %03d %s\r\n", status, StatusText(status))
+
+	h := Header{}
+	for name, values := range r.header {
+		h[name] = values
+	}
+	if !noBody {
+		h.Set("Content-Length", strconv.Itoa(r.Body.Len()))
+		if h.Get("Content-Type") == "" && r.Body.Len() > 0 {
+			h.Set("Content-Type", "text/plain; charset=utf-8")
+		}
+	}
+	if req == nil || req.Close {
+		h.Set("Connection", "close")
+	}
+
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if !isToken(name) {
+			continue
+		}
+		for _, v := range h[name] {
+			// * a value with a line break would let the handler's input write its own header lines
+			fmt.Fprintf(bw, "%s: %s\r\n", name, strings.Map(noBreak, v))
+		}
+	}
+	bw.WriteString("\r\n")
+
+	if !noBody && (req == nil || req.Method != "HEAD") {
+		bw.Write(r.Body.Bytes())
+	}
+	return bw.Flush()
+}
+
+func noBreak(r rune) rune {
+	if r == '\r' || r == '\n' || r == 0 {
+		return ' '
+	}
+	return r
+}
@@ -0,0 +1,49 @@
+package http1
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResponseSend(t *testing.T) {
+	cases := []struct {
+		name string
+		req  *Request
+		fill func(w ResponseWriter)
+		want string
+	}{
+		{"text", &Request{Method: "GET"}, func(w ResponseWriter) {
+			w.Write([]byte("hello"))
+			w.WriteHeader(StatusNotFound) // * too late, the body set 200
+		}, "HTTP/1.1 200 OK\r\nContent-Length: 5\r\nContent-Type: text/plain; charset=utf-8\r\n\r\nhello"},
+		{"head", &Request{Method: "HEAD", Close: true}, func(w ResponseWriter) {
+			w.Header().Set("content-type", "text/html")
+			w.Write([]byte("<h1>"))
+		}, "HTTP/1.1 200 OK\r\nConnection: close\r\nContent-Length: 4\r\nContent-Type: text/html\r\n\r\n"},
+		{"no content", &Request{Method: "DELETE"}, func(w ResponseWriter) {
+			w.WriteHeader(StatusNoContent)
+		}, "HTTP/1.1 204 No Content\r\n\r\n"},
+		{"refused", nil, func(w ResponseWriter) {
+			w.WriteHeader(StatusBadRequest)
+		}, "HTTP/1.1 400 Bad Request\r\nConnection: close\r\nContent-Length: 0\r\n\r\n"},
+		{"line break in a value", &Request{Method: "GET"}, func(w ResponseWriter) {
+			w.Header().Set("Location", "/x\r\nSet-Cookie: a=b")
+			w.Header().Set("Bad Name", "x")
+			w.WriteHeader(StatusSeeOther)
+		}, "HTTP/1.1 303 See Other\r\nContent-Length: 0\r\nLocation: /x  Set-Cookie: a=b\r\n\r\n"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			res := NewResponse()
+			c.fill(res)
+			var b strings.Builder
+			if err := res.Send(&b, c.req); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != c.want {
+				t.Errorf("sent\n%q\nwant\n%q", b.String(), c.want)
+			}
+		})
+	}
+}
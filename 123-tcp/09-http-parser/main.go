@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"slices"
+	"tcp/09-http-parser/http1"
+	"time"
+)
+
+// NOTE: Parsing requests
+/*
+	07 and 08 read the request line with a bufio.Scanner and stop at the empty line: a malformed
+	request line panics, headers and bodies are ignored and the connection is closed after one request.
+	http1.Reader reads the whole request, and the next ones of the same connection:
+
+	- request line: METHOD SP target SP HTTP/1.x, anything else is refused with 400 (505 for HTTP/2.0)
+	- header fields: name ":" OWS value OWS, limited in count and total size (431)
+	- body: Content-Length bytes, or chunked with its trailer, limited in size (413)
+	- keep-alive: HTTP/1.1 keeps the connection unless Connection: close, HTTP/1.0 closes it unless Connection: keep-alive
+*/
+
+// NOTE: Try it
+/*
+	```bash
+	curl -v localhost:8080/echo?x=1 -d 'hello'
+	curl -v localhost:8080/a localhost:8080/b    # both on one connection
+	printf 'GET / HTTP/1.1\r\nHost: x\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n0\r\n\r\n' | nc localhost 8080
+	printf 'GET /\r\n\r\n' | nc localhost 8080    # 400
+	```
+*/
+
+func main() {
+	listener, err := net.Listen("tcp", ":8080")
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer listener.Close()
+
+	fmt.Println("Server is started at port 8080")
+
+	for {
+		connection, err := listener.Accept()
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		go handle(connection)
+	}
+}
+
+func handle(connection net.Conn) {
+	defer connection.Close()
+	reader := http1.NewReader(connection, http1.DefaultLimits())
+
+	for {
+		// * an idle keep-alive connection is closed after a minute
+		connection.SetReadDeadline(time.Now().Add(time.Minute))
+
+		req, err := reader.ReadRequest()
+		if err != nil {
+			refuse(connection, err)
+			return
+		}
+
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			refuse(connection, err)
+			return
+		}
+
+		respond(connection, http1.StatusOK, echo(req, body), req.Close)
+		if req.Close {
+			return
+		}
+	}
+}
+
+// echo describes the request as it was parsed
+func echo(req *http1.Request, body []byte) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s %s %s\n", req.Method, req.Path, req.Proto)
+	if req.RawQuery != "" {
+		fmt.Fprintf(&b, "query: %v\n", req.Query())
+	}
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s: %q\n", name, req.Header[name])
+	}
+	fmt.Fprintf(&b, "body (%d bytes): %q\n", len(body), body)
+	return b.String()
+}
+
+// refuse answers a request the parser refused, a connection closed by the client gets no answer
+func refuse(connection net.Conn, err error) {
+	var herr *http1.Error
+	if errors.As(err, &herr) {
+		log.Println(err)
+		respond(connection, herr.Status, herr.Reason+"\n", true)
+	}
+}
+
+func respond(connection net.Conn, status int, body string, close bool) {
+	fmt.Fprintf(connection, "HTTP/1.1 %d %s\r\n", status, http1.StatusText(status))
+	fmt.Fprintf(connection, "Content-Length: %d\r\n", len(body))
+	fmt.Fprint(connection, "Content-Type: text/plain; charset=utf-8\r\n")
+	if close {
+		fmt.Fprint(connection, "Connection: close\r\n")
+	}
+	fmt.Fprint(connection, "\r\n")
+	fmt.Fprint(connection, body)
+}
@@ -0,0 +1,214 @@
+// Package router sends the requests of the TCP server to handlers by method and path, like
+// httprouter does for net/http. Patterns are made of segments:
+//
+//	/books          static, matches itself
+//	/user/:name     a parameter, matches one non-empty segment
+//	/static/*file   a catch-all, matches the rest of the path, last in a pattern
+//
+// A static segment wins over a parameter, a parameter over a catch-all.
+package router
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"tcp/09-http-parser/http1"
+)
+
+// Handle is a handler with the parameters of its pattern
+type Handle func(w http1.ResponseWriter, r *http1.Request, ps Params)
+
+type Param struct {
+	Key, Value string
+}
+
+type Params []Param
+
+// ByName returns the value of the parameter, empty if the pattern has none of that name
+func (ps Params) ByName(name string) string {
+	for _, p := range ps {
+		if p.Key == name {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+// Router is an http1.Handler
+type Router struct {
+	root *node
+
+	// NotFound answers a path no pattern matches, 404 by default
+	NotFound http1.Handler
+	// MethodNotAllowed answers a path matched with another method, 405 by default.
+	// The Allow header is set before it is called.
+	MethodNotAllowed http1.Handler
+}
+
+type node struct {
+	static    map[string]*node
+	param     *node
+	paramName string
+	catchAll  *node
+	handles   map[string]Handle // by method
+}
+
+func New() *Router {
+	return &Router{root: &node{}}
+}
+
+func (r *Router) GET(pattern string, h Handle)     { r.Handle("GET", pattern, h) }
+func (r *Router) POST(pattern string, h Handle)    { r.Handle("POST", pattern, h) }
+func (r *Router) PUT(pattern string, h Handle)     { r.Handle("PUT", pattern, h) }
+func (r *Router) PATCH(pattern string, h Handle)   { r.Handle("PATCH", pattern, h) }
+func (r *Router) DELETE(pattern string, h Handle)  { r.Handle("DELETE", pattern, h) }
+func (r *Router) OPTIONS(pattern string, h Handle) { r.Handle("OPTIONS", pattern, h) }
+
+// Handle registers h for the method and pattern. It panics on an invalid pattern, on a route
+// registered twice and on two parameters of different names at the same place, mistakes that
+// are better found when the server starts.
+func (r *Router) Handle(method, pattern string, h Handle) {
+	if method == "" || h == nil {
+		panic("router: empty method or nil handler for " + pattern)
+	}
+	if !strings.HasPrefix(pattern, "/") {
+		panic("router: pattern " + pattern + " doesn't start with /")
+	}
+
+	n := r.root
+	segments := strings.Split(pattern[1:], "/")
+	for i, seg := range segments {
+		switch {
+		case strings.HasPrefix(seg, ":"):
+			name := seg[1:]
+			if name == "" {
+				panic("router: unnamed parameter in " + pattern)
+			}
+			if n.param == nil {
+				n.param = &node{}
+				n.paramName = name
+			} else if n.paramName != name {
+				panic(fmt.Sprintf("router: parameter :%s of %s conflicts with :%s", name, pattern, n.paramName))
+			}
+			n = n.param
+
+		case strings.HasPrefix(seg, "*"):
+			name := seg[1:]
+			if name == "" || i != len(segments)-1 {
+				panic("router: the catch-all of " + pattern + " needs a name and must be last")
+			}
+			if n.catchAll == nil {
+				n.catchAll = &node{paramName: name}
+			} else if n.catchAll.paramName != name {
+				panic(fmt.Sprintf("router: catch-all *%s of %s conflicts with *%s", name, pattern, n.catchAll.paramName))
+			}
+			n = n.catchAll
+
+		default:
+			if n.static == nil {
+				n.static = map[string]*node{}
+			}
+			if n.static[seg] == nil {
+				n.static[seg] = &node{}
+			}
+			n = n.static[seg]
+		}
+	}
+
+	if n.handles == nil {
+		n.handles = map[string]Handle{}
+	}
+	if _, ok := n.handles[method]; ok {
+		panic(fmt.Sprintf("router: %s %s is registered twice", method, pattern))
+	}
+	n.handles[method] = h
+}
+
+// ServeHTTP calls the handler of the request. Among the patterns matching the path the most
+// specific one with a handler for the method is used, HEAD falls back to GET.
+// A path matched only with other methods is answered 405, and OPTIONS lists them.
+func (r *Router) ServeHTTP(w http1.ResponseWriter, req *http1.Request) {
+	var matches []match
+	r.root.lookup(strings.Split(req.Path[1:], "/"), nil, &matches)
+
+	for _, m := range matches {
+		if h := m.node.handle(req.Method); h != nil {
+			h(w, req, m.params)
+			return
+		}
+	}
+
+	if len(matches) == 0 {
+		r.notFound(w, req)
+		return
+	}
+
+	var allow []string
+	for _, m := range matches {
+		for method := range m.node.handles {
+			allow = append(allow, method)
+		}
+		if m.node.handles["GET"] != nil {
+			allow = append(allow, "HEAD")
+		}
+	}
+	allow = append(allow, "OPTIONS")
+	slices.Sort(allow)
+	w.Header().Set("Allow", strings.Join(slices.Compact(allow), ", "))
+
+	switch {
+	case req.Method == "OPTIONS":
+		w.WriteHeader(http1.StatusNoContent)
+	case r.MethodNotAllowed != nil:
+		r.MethodNotAllowed.ServeHTTP(w, req)
+	default:
+		w.WriteHeader(http1.StatusMethodNotAllowed)
+		fmt.Fprintln(w, http1.StatusText(http1.StatusMethodNotAllowed))
+	}
+}
+
+func (r *Router) notFound(w http1.ResponseWriter, req *http1.Request) {
+	if r.NotFound != nil {
+		r.NotFound.ServeHTTP(w, req)
+		return
+	}
+	w.WriteHeader(http1.StatusNotFound)
+	fmt.Fprintln(w, http1.StatusText(http1.StatusNotFound))
+}
+
+type match struct {
+	node   *node
+	params Params
+}
+
+// lookup appends the nodes with handlers matching the segments, most specific first
+func (n *node) lookup(segments []string, ps Params, matches *[]match) {
+	if len(segments) == 0 {
+		if n.handles != nil {
+			*matches = append(*matches, match{n, ps})
+		}
+		return
+	}
+
+	seg, rest := segments[0], segments[1:]
+	if child := n.static[seg]; child != nil {
+		child.lookup(rest, ps, matches)
+	}
+	if n.param != nil && seg != "" {
+		n.param.lookup(rest, append(slices.Clip(ps), Param{n.paramName, seg}), matches)
+	}
+	if n.catchAll != nil && n.catchAll.handles != nil {
+		value := strings.Join(segments, "/")
+		*matches = append(*matches, match{n.catchAll, append(slices.Clip(ps), Param{n.catchAll.paramName, value})})
+	}
+}
+
+func (n *node) handle(method string) Handle {
+	if h := n.handles[method]; h != nil {
+		return h
+	}
+	if method == "HEAD" {
+		return n.handles["GET"]
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package router
+
+import (
+	"fmt"
+	"strings"
+	"tcp/09-http-parser/http1"
+	"testing"
+)
+
+// named answers with the name of the route and its parameters
+func named(name string) Handle {
+	return func(w http1.ResponseWriter, r *http1.Request, ps Params) {
+		fmt.Fprint(w, name)
+		for _, p := range ps {
+			fmt.Fprintf(w, " %s=%s", p.Key, p.Value)
+		}
+	}
+}
+
+func newTestRouter() *Router {
+	r := New()
+	r.GET("/", named("home"))
+	r.GET("/user/:name", named("user"))
+	r.DELETE("/user/:name", named("delete user"))
+	r.POST("/user/new", named("new user"))
+	r.GET("/user/:name/books/:id", named("book"))
+	r.GET("/static/*file", named("static"))
+	r.GET("/static/index.html", named("index"))
+	r.PUT("/files/*path", named("upload"))
+	return r
+}
+
+func serve(r *Router, method, path string) *http1.Response {
+	res := http1.NewResponse()
+	r.ServeHTTP(res, &http1.Request{Method: method, Path: path})
+	return res
+}
+
+func TestRouter(t *testing.T) {
+	r := newTestRouter()
+	cases := []struct {
+		method, path string
+		status       int
+		body         string
+	}{
+		{"GET", "/", 200, "home"},
+		{"GET", "/user/amir", 200, "user name=amir"},
+		{"HEAD", "/user/amir", 200, "user name=amir"},
+		{"DELETE", "/user/amir", 200, "delete user name=amir"},
+		{"POST", "/user/new", 200, "new user"},
+		{"GET", "/user/new", 200, "user name=new"}, // * the static route has no GET, the parameter one has
+		{"GET", "/user/amir/books/42", 200, "book name=amir id=42"},
+		{"GET", "/static/css/site.css", 200, "static file=css/site.css"},
+		{"GET", "/static/index.html", 200, "index"},
+		{"GET", "/static/", 200, "static file="},
+		{"PUT", "/files/a/b", 200, "upload path=a/b"},
+		{"GET", "/user/", 404, "Not Found\n"},
+		{"GET", "/user/amir/", 404, "Not Found\n"},
+		{"GET", "/static", 404, "Not Found\n"},
+		{"GET", "/nowhere", 404, "Not Found\n"},
+		{"POST", "/user/amir", 405, "Method Not Allowed\n"},
+	}
+
+	for _, c := range cases {
+		res := serve(r, c.method, c.path)
+		if res.Status != c.status || res.Body.String() != c.body {
+			t.Errorf("%s %s = %d %q, want %d %q", c.method, c.path, res.Status, res.Body.String(), c.status, c.body)
+		}
+	}
+}
+
+func TestAllow(t *testing.T) {
+	r := newTestRouter()
+	cases := []struct {
+		method, path string
+		status       int
+		allow        string
+	}{
+		{"PUT", "/user/amir", 405, "DELETE, GET, HEAD, OPTIONS"},
+		{"GET", "/user/new", 200, ""},
+		{"PUT", "/user/new", 405, "DELETE, GET, HEAD, OPTIONS, POST"},
+		{"OPTIONS", "/files/x", 204, "OPTIONS, PUT"},
+		{"GET", "/files/x", 405, "OPTIONS, PUT"},
+	}
+
+	for _, c := range cases {
+		res := serve(r, c.method, c.path)
+		if res.Status != c.status || res.Header().Get("Allow") != c.allow {
+			t.Errorf("%s %s = %d Allow %q, want %d %q", c.method, c.path, res.Status, res.Header().Get("Allow"), c.status, c.allow)
+		}
+	}
+}
+
+func TestCustomErrors(t *testing.T) {
+	r := newTestRouter()
+	r.NotFound = http1.HandlerFunc(func(w http1.ResponseWriter, req *http1.Request) {
+		w.WriteHeader(http1.StatusNotFound)
+		fmt.Fprint(w, "no page at "+req.Path)
+	})
+	r.MethodNotAllowed = http1.HandlerFunc(func(w http1.ResponseWriter, req *http1.Request) {
+		w.WriteHeader(http1.StatusMethodNotAllowed)
+		fmt.Fprint(w, "try "+w.Header().Get("Allow"))
+	})
+
+	if res := serve(r, "GET", "/nowhere"); res.Body.String() != "no page at /nowhere" {
+		t.Errorf("not found body %q", res.Body.String())
+	}
+	if res := serve(r, "POST", "/"); res.Body.String() != "try GET, HEAD, OPTIONS" {
+		t.Errorf("method not allowed body %q", res.Body.String())
+	}
+}
+
+func TestInvalidPatterns(t *testing.T) {
+	cases := []struct {
+		pattern string
+		want    string
+	}{
+		{"user", "doesn't start with /"},
+		{"/user/:", "unnamed parameter"},
+		{"/user/:id", "conflicts with :name"},
+		{"/static/*path", "conflicts with *file"},
+		{"/static/*file/more", "must be last"},
+		{"/user/:name", "registered twice"},
+	}
+
+	for _, c := range cases {
+		func() {
+			defer func() {
+				msg, _ := recover().(string)
+				if !strings.Contains(msg, c.want) {
+					t.Errorf("GET %s panicked with %q, want %q", c.pattern, msg, c.want)
+				}
+			}()
+			newTestRouter().GET(c.pattern, named("x"))
+		}()
+	}
+}
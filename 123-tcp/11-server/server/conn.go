@@ -0,0 +1,98 @@
+package server
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// Conn is a connection with the timeouts of the server. It is idle while it waits for a
+// request and active from the first byte of it, the request is then read within ReadTimeout.
+// SetIdle switches it back once the request is answered, Active starts the next request
+// when its first bytes were read along with the previous one.
+type Conn struct {
+	net.Conn
+	cfg Config
+
+	mu     sync.Mutex
+	idle   bool
+	closed bool
+}
+
+func newConn(nc net.Conn, cfg Config) *Conn {
+	c := &Conn{Conn: nc, cfg: cfg}
+	c.SetIdle()
+	return c
+}
+
+func (c *Conn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.mu.Lock()
+		if c.idle {
+			// * the next request started, it has ReadTimeout from now
+			c.active()
+		}
+		c.mu.Unlock()
+	}
+	return n, err
+}
+
+func (c *Conn) Write(p []byte) (int, error) {
+	if c.cfg.WriteTimeout > 0 {
+		c.Conn.SetWriteDeadline(time.Now().Add(c.cfg.WriteTimeout))
+	}
+	return c.Conn.Write(p)
+}
+
+// SetIdle marks the connection as waiting for the next request: reads wait IdleTimeout for
+// its first byte and Shutdown may close the connection. Call it only when no byte of the
+// next request sits in a read buffer already.
+func (c *Conn) SetIdle() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.idle = true
+	timeout := c.cfg.IdleTimeout
+	if timeout == 0 {
+		timeout = c.cfg.ReadTimeout
+	}
+	c.setReadDeadline(timeout)
+}
+
+// Active starts a request whose first bytes already sit in a read buffer, it has ReadTimeout
+// from now. Without it, the request would inherit the deadline of the previous one.
+func (c *Conn) Active() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.active()
+}
+
+func (c *Conn) active() {
+	c.idle = false
+	c.setReadDeadline(c.cfg.ReadTimeout)
+}
+
+func (c *Conn) Idle() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.idle
+}
+
+func (c *Conn) setReadDeadline(timeout time.Duration) {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	c.Conn.SetReadDeadline(deadline)
+}
+
+// Close closes the connection once, it is safe to call from Shutdown and the handler
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	return c.Conn.Close()
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"os"
+	"tcp/09-http-parser/http1"
+)
+
+// HTTP serves the requests of each connection with h, one after the other, until the client
+// closes it, sends Connection: close or the server shuts down
+func HTTP(h http1.Handler, limits http1.Limits) Handler {
+	return HandlerFunc(func(ctx context.Context, c *Conn) {
+		reader := http1.NewReader(c, limits)
+		for {
+			req, err := reader.ReadRequest()
+			if err != nil {
+				refuse(c, err)
+				return
+			}
+			res := http1.NewResponse()
+			h.ServeHTTP(res, req)
+			if ctx.Err() != nil {
+				req.Close = true // * the last response before the shutdown tells the client
+			}
+			if err := res.Send(c, req); err != nil || req.Close {
+				return
+			}
+
+			if reader.Buffered() > 0 {
+				c.Active() // * pipelined, the next request started already
+			} else {
+				c.SetIdle()
+			}
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	})
+}
+
+// refuse answers a request the parser refused, or one that was too slow to arrive.
+// A connection closed by the client, or timed out between requests, gets no answer.
+func refuse(c *Conn, err error) {
+	status := 0
+	var herr *http1.Error
+	switch {
+	case errors.As(err, &herr):
+		status = herr.Status
+	case errors.Is(err, os.ErrDeadlineExceeded) && !c.Idle():
+		status = http1.StatusRequestTimeout
+	default:
+		return
+	}
+
+	res := http1.NewResponse()
+	res.WriteHeader(status)
+	res.Write([]byte(http1.StatusText(status) + "\n"))
+	res.Send(c, nil)
+}
@@ -0,0 +1,261 @@
+// Package server accepts TCP connections and runs a handler for each, with a cap on open
+// connections, read, write and idle timeouts and a graceful Shutdown.
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"runtime/debug"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrServerClosed is returned by Serve after Shutdown
+var ErrServerClosed = errors.New("server closed")
+
+type Config struct {
+	// MaxConns caps the open connections, 0 for no cap. Above it new clients wait in the
+	// listen backlog of the kernel until a connection closes.
+	MaxConns int
+	// ReadTimeout bounds the reading of a request, from its first byte, 0 for none
+	ReadTimeout time.Duration
+	// WriteTimeout bounds each write, 0 for none
+	WriteTimeout time.Duration
+	// IdleTimeout bounds the wait for the first byte of the next request, see Conn.SetIdle.
+	// 0 uses ReadTimeout.
+	IdleTimeout time.Duration
+	ErrorLog    *log.Logger // log.Default() when nil
+}
+
+// DefaultConfig suits the HTTP servers of the examples
+func DefaultConfig() Config {
+	return Config{
+		MaxConns:     1000,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		IdleTimeout:  time.Minute,
+	}
+}
+
+type Handler interface {
+	// ServeConn serves the connection until it returns, the server closes the connection then.
+	// ctx is cancelled when Shutdown starts, a handler serving several requests returns once
+	// the current one is answered.
+	ServeConn(ctx context.Context, c *Conn)
+}
+
+type HandlerFunc func(ctx context.Context, c *Conn)
+
+func (f HandlerFunc) ServeConn(ctx context.Context, c *Conn) { f(ctx, c) }
+
+type Server struct {
+	cfg     Config
+	handler Handler
+	log     *log.Logger
+
+	ctx    context.Context // cancelled by Shutdown
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[*Conn]struct{}
+	closing   bool
+	serving   sync.WaitGroup // Serve loops
+}
+
+func New(cfg Config, h Handler) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		cfg:       cfg,
+		handler:   h,
+		log:       cfg.ErrorLog,
+		ctx:       ctx,
+		cancel:    cancel,
+		listeners: map[net.Listener]struct{}{},
+		conns:     map[*Conn]struct{}{},
+	}
+	if s.log == nil {
+		s.log = log.Default()
+	}
+	return s
+}
+
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l until Shutdown, it always returns an error and closes l.
+// Errors like too many open files are waited out, with a backoff up to a second.
+func (s *Server) Serve(l net.Listener) error {
+	if !s.track(l) {
+		l.Close()
+		return ErrServerClosed
+	}
+	defer s.serving.Done()
+	defer l.Close()
+
+	var sem chan struct{}
+	if s.cfg.MaxConns > 0 {
+		sem = make(chan struct{}, s.cfg.MaxConns)
+	}
+
+	var backoff time.Duration
+	for {
+		if sem != nil {
+			select {
+			case sem <- struct{}{}:
+			case <-s.ctx.Done():
+				return ErrServerClosed
+			}
+		}
+
+		nc, err := l.Accept()
+		if err != nil {
+			if sem != nil {
+				<-sem
+			}
+			if s.shuttingDown() {
+				return ErrServerClosed
+			}
+			if !temporary(err) {
+				return err
+			}
+			backoff = min(max(2*backoff, 5*time.Millisecond), time.Second)
+			s.log.Printf("server: accept error: %v; retrying in %v", err, backoff)
+			select {
+			case <-time.After(backoff):
+			case <-s.ctx.Done():
+				return ErrServerClosed
+			}
+			continue
+		}
+		backoff = 0
+
+		c := newConn(nc, s.cfg)
+		if !s.add(c) {
+			nc.Close()
+			return ErrServerClosed
+		}
+		go s.serve(c, sem)
+	}
+}
+
+// temporary tells the Accept errors that pass, like running out of file descriptors,
+// from a broken listener
+func temporary(err error) bool {
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	for _, errno := range []syscall.Errno{syscall.EMFILE, syscall.ENFILE, syscall.ENOBUFS, syscall.ENOMEM, syscall.ECONNABORTED, syscall.ECONNRESET} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) serve(c *Conn, sem chan struct{}) {
+	defer func() {
+		if err := recover(); err != nil {
+			s.log.Printf("server: panic serving %v: %v\n%s", c.RemoteAddr(), err, debug.Stack())
+		}
+		c.Close()
+		s.remove(c)
+		if sem != nil {
+			<-sem
+		}
+	}()
+	s.handler.ServeConn(s.ctx, c)
+}
+
+func (s *Server) track(l net.Listener) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closing {
+		return false
+	}
+	s.listeners[l] = struct{}{}
+	s.serving.Add(1)
+	return true
+}
+
+// shuttingDown is set before Shutdown closes the listeners, unlike the cancel of ctx
+func (s *Server) shuttingDown() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closing
+}
+
+func (s *Server) add(c *Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closing {
+		return false
+	}
+	s.conns[c] = struct{}{}
+	return true
+}
+
+func (s *Server) remove(c *Conn) {
+	s.mu.Lock()
+	delete(s.conns, c)
+	s.mu.Unlock()
+}
+
+// OpenConns is the number of connections being served
+func (s *Server) OpenConns() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// Shutdown stops accepting, closes the idle connections and waits for the others to finish.
+// When ctx ends first the remaining connections are closed and ctx.Err() is returned.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closing = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	s.mu.Unlock()
+	s.cancel()
+	s.serving.Wait()
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if s.closeIdle() == 0 {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			s.mu.Lock()
+			for c := range s.conns {
+				c.Close()
+			}
+			s.mu.Unlock()
+			return ctx.Err()
+		}
+	}
+}
+
+// closeIdle closes the connections waiting for a request and returns how many are open
+func (s *Server) closeIdle() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		if c.Idle() {
+			c.Close()
+		}
+	}
+	return len(s.conns)
+}
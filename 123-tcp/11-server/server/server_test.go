@@ -0,0 +1,315 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"tcp/09-http-parser/http1"
+	"testing"
+	"time"
+)
+
+// start serves h on a loopback port, the server is shut down at the end of the test
+func start(t *testing.T, cfg Config, h Handler) (*Server, string, chan error) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ErrorLog == nil {
+		cfg.ErrorLog = log.New(io.Discard, "", 0)
+	}
+	s := New(cfg, h)
+
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(l) }()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		s.Shutdown(ctx)
+	})
+	return s, l.Addr().String(), served
+}
+
+func dial(t *testing.T, addr string) net.Conn {
+	t.Helper()
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// closedWithin reads until the server closes c and returns what it sent
+func closedWithin(t *testing.T, c net.Conn, d time.Duration) string {
+	t.Helper()
+	c.SetReadDeadline(time.Now().Add(d))
+	b, err := io.ReadAll(c)
+	if err != nil {
+		t.Fatalf("connection still open after %v: %v", d, err)
+	}
+	return string(b)
+}
+
+var hello = http1.HandlerFunc(func(w http1.ResponseWriter, r *http1.Request) {
+	fmt.Fprint(w, "hello "+r.Path)
+})
+
+func TestMaxConns(t *testing.T) {
+	release := make(chan struct{})
+	s, addr, _ := start(t, Config{MaxConns: 2}, HandlerFunc(func(ctx context.Context, c *Conn) {
+		<-release
+	}))
+
+	for range 3 {
+		dial(t, addr)
+	}
+	waitFor(t, "2 connections", func() bool { return s.OpenConns() == 2 })
+	time.Sleep(20 * time.Millisecond)
+	if n := s.OpenConns(); n != 2 {
+		t.Fatalf("%d connections open, the cap is 2", n)
+	}
+
+	release <- struct{}{}
+	waitFor(t, "the third connection", func() bool { return s.OpenConns() == 2 })
+	close(release)
+	waitFor(t, "every connection to close", func() bool { return s.OpenConns() == 0 })
+}
+
+func TestHTTPKeepAlive(t *testing.T) {
+	_, addr, _ := start(t, DefaultConfig(), HTTP(hello, http1.DefaultLimits()))
+	c := dial(t, addr)
+
+	fmt.Fprint(c, "GET /a HTTP/1.1\r\nHost: x\r\n\r\nGET /b HTTP/1.1\r\nHost: x\r\nConnection: close\r\n\r\n")
+	got := closedWithin(t, c, time.Second)
+	if !strings.Contains(got, "hello /a") || !strings.HasSuffix(got, "hello /b") || !strings.Contains(got, "Connection: close") {
+		t.Errorf("got %q, want both answers on one connection", got)
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	cfg := Config{ReadTimeout: time.Second, IdleTimeout: 50 * time.Millisecond}
+	_, addr, _ := start(t, cfg, HTTP(hello, http1.DefaultLimits()))
+
+	if got := closedWithin(t, dial(t, addr), time.Second); got != "" {
+		t.Errorf("a connection idle from the start got %q, want no answer", got)
+	}
+
+	c := dial(t, addr)
+	fmt.Fprint(c, "GET / HTTP/1.1\r\nHost: x\r\n\r\n")
+	if got := closedWithin(t, c, time.Second); !strings.HasSuffix(got, "hello /") {
+		t.Errorf("got %q, want the answer then the close", got)
+	}
+}
+
+func TestReadTimeout(t *testing.T) {
+	cfg := Config{ReadTimeout: 50 * time.Millisecond, IdleTimeout: time.Second}
+	_, addr, _ := start(t, cfg, HTTP(hello, http1.DefaultLimits()))
+
+	c := dial(t, addr)
+	fmt.Fprint(c, "GET / HTTP/1.1\r\n") // * and never the rest
+	if got := closedWithin(t, c, time.Second); !strings.HasPrefix(got, "HTTP/1.1 408 Request Timeout\r\n") {
+		t.Errorf("a slow request got %q, want 408", got)
+	}
+}
+
+// A pipelined request gets its own ReadTimeout, not what a slow handler left of the previous one
+func TestReadTimeoutPipelined(t *testing.T) {
+	slow := http1.HandlerFunc(func(w http1.ResponseWriter, r *http1.Request) {
+		if r.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		fmt.Fprint(w, "done "+r.Path)
+	})
+	cfg := Config{ReadTimeout: 100 * time.Millisecond, IdleTimeout: time.Second}
+	_, addr, _ := start(t, cfg, HTTP(slow, http1.DefaultLimits()))
+
+	c := dial(t, addr)
+	fmt.Fprint(c, "GET /slow HTTP/1.1\r\nHost: x\r\n\r\nGET /next HTTP/1.1\r\n")
+	var got []byte
+	buf := make([]byte, 1024)
+	c.SetReadDeadline(time.Now().Add(time.Second))
+	for !strings.HasSuffix(string(got), "done /slow") {
+		n, err := c.Read(buf)
+		if err != nil {
+			t.Fatalf("reading the first answer: %v, got %q", err, got)
+		}
+		got = append(got, buf[:n]...)
+	}
+
+	fmt.Fprint(c, "Host: x\r\nConnection: close\r\n\r\n") // * the rest of the pipelined request
+	if got := closedWithin(t, c, time.Second); !strings.HasPrefix(got, "HTTP/1.1 200") || !strings.HasSuffix(got, "done /next") {
+		t.Errorf("the pipelined request got %q, want its answer", got)
+	}
+}
+
+func TestRefusedRequest(t *testing.T) {
+	_, addr, _ := start(t, DefaultConfig(), HTTP(hello, http1.DefaultLimits()))
+	c := dial(t, addr)
+	fmt.Fprint(c, "GET /\r\n\r\n")
+	if got := closedWithin(t, c, time.Second); !strings.HasPrefix(got, "HTTP/1.1 400 Bad Request\r\n") {
+		t.Errorf("a malformed request got %q, want 400", got)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	inFlight := make(chan struct{})
+	finish := make(chan struct{})
+	slow := http1.HandlerFunc(func(w http1.ResponseWriter, r *http1.Request) {
+		if r.Path == "/slow" {
+			close(inFlight)
+			<-finish
+		}
+		fmt.Fprint(w, "done "+r.Path)
+	})
+	s, addr, served := start(t, DefaultConfig(), HTTP(slow, http1.DefaultLimits()))
+
+	idle := dial(t, addr)
+	fmt.Fprint(idle, "GET /fast HTTP/1.1\r\nHost: x\r\n\r\n")
+	if line, _ := bufio.NewReader(idle).ReadString('\n'); !strings.HasPrefix(line, "HTTP/1.1 200") {
+		t.Fatalf("got %q", line)
+	}
+
+	busy := dial(t, addr)
+	fmt.Fprint(busy, "GET /slow HTTP/1.1\r\nHost: x\r\n\r\n")
+	<-inFlight
+	waitFor(t, "the idle connection", func() bool { return s.OpenConns() == 2 })
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- s.Shutdown(context.Background()) }()
+
+	closedWithin(t, idle, time.Second)
+	if err := <-served; !errors.Is(err, ErrServerClosed) {
+		t.Errorf("Serve returned %v, want ErrServerClosed", err)
+	}
+	if c, err := net.Dial("tcp", addr); err == nil {
+		c.Close()
+		t.Error("the server accepts connections after Shutdown")
+	}
+	select {
+	case err := <-shutdown:
+		t.Fatalf("Shutdown returned %v with a request in flight", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(finish)
+	got := closedWithin(t, busy, time.Second)
+	if !strings.Contains(got, "Connection: close") || !strings.HasSuffix(got, "done /slow") {
+		t.Errorf("the request in flight got %q", got)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("Shutdown returned %v", err)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	s, addr, _ := start(t, DefaultConfig(), HandlerFunc(func(ctx context.Context, c *Conn) {
+		io.Copy(io.Discard, c) // * ignores ctx, until the connection is closed
+	}))
+	c := dial(t, addr)
+	fmt.Fprint(c, "x") // * active, so not closed as idle
+	waitFor(t, "the connection", func() bool { return s.OpenConns() == 1 && !connIdle(s) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown returned %v, want the deadline", err)
+	}
+	closedWithin(t, c, time.Second)
+}
+
+func connIdle(s *Server) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		return c.Idle()
+	}
+	return false
+}
+
+func TestPanicRecovered(t *testing.T) {
+	var logged strings.Builder
+	cfg := DefaultConfig()
+	cfg.ErrorLog = log.New(&logged, "", 0)
+	s, addr, _ := start(t, cfg, HandlerFunc(func(ctx context.Context, c *Conn) {
+		panic("boom")
+	}))
+
+	closedWithin(t, dial(t, addr), time.Second)
+	closedWithin(t, dial(t, addr), time.Second)
+	waitFor(t, "the connections to close", func() bool { return s.OpenConns() == 0 })
+	if !strings.Contains(logged.String(), "panic serving") {
+		t.Errorf("the panic wasn't logged: %q", logged.String())
+	}
+}
+
+// flakyListener fails Accept with its errors, one per call, before accepting its conn
+type flakyListener struct {
+	net.Listener
+	errs []error
+	conn net.Conn
+}
+
+func (l *flakyListener) Accept() (net.Conn, error) {
+	if len(l.errs) > 0 {
+		err := l.errs[0]
+		l.errs = l.errs[1:]
+		return nil, err
+	}
+	if c := l.conn; c != nil {
+		l.conn = nil
+		return c, nil
+	}
+	return nil, errors.New("listener broken")
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "accept timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestAcceptBackoff(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	flaky := &flakyListener{Listener: l, errs: []error{timeoutError{}, timeoutError{}}, conn: server}
+
+	served := make(chan struct{})
+	var logged strings.Builder
+	s := New(Config{ErrorLog: log.New(&logged, "", 0)}, HandlerFunc(func(ctx context.Context, c *Conn) {
+		close(served)
+	}))
+
+	start := time.Now()
+	if err := s.Serve(flaky); err == nil || err.Error() != "listener broken" {
+		t.Errorf("Serve returned %v, want the permanent error", err)
+	}
+	<-served
+	if waited := time.Since(start); waited < 15*time.Millisecond {
+		t.Errorf("retried after %v, want a backoff of 5ms then 10ms", waited)
+	}
+	if n := strings.Count(logged.String(), "retrying"); n != 2 {
+		t.Errorf("logged %d retries, want 2", n)
+	}
+}
@@ -0,0 +1,329 @@
+package chat
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"tcp/11-server/server"
+	"testing"
+	"time"
+)
+
+// start serves a hub on l, the server is shut down at the end of the test
+func start(t *testing.T, cfg server.Config, h *Hub, l net.Listener) {
+	t.Helper()
+	cfg.ErrorLog = log.New(io.Discard, "", 0)
+	s := server.New(cfg, h)
+	go s.Serve(l)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		s.Shutdown(ctx)
+	})
+}
+
+func listen(t *testing.T, cfg server.Config, h *Hub) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start(t, cfg, h, l)
+	return l.Addr().String()
+}
+
+var config = server.Config{ReadTimeout: time.Second, WriteTimeout: time.Second, IdleTimeout: time.Minute}
+
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func connect(t *testing.T, addr, nick string) *testClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return attach(t, conn, nick)
+}
+
+func attach(t *testing.T, conn net.Conn, nick string) *testClient {
+	t.Helper()
+	t.Cleanup(func() { conn.Close() })
+	c := &testClient{t: t, conn: conn, r: bufio.NewReader(conn)}
+	c.expect("* welcome, pick a nickname with /nick <name>")
+	if nick != "" {
+		c.say("/nick " + nick)
+		c.expect("* you are now " + nick)
+	}
+	return c
+}
+
+func (c *testClient) say(line string) {
+	c.t.Helper()
+	c.conn.SetWriteDeadline(time.Now().Add(2 * time.Second))
+	if _, err := fmt.Fprintln(c.conn, line); err != nil {
+		c.t.Fatalf("sending %q: %v", line, err)
+	}
+}
+
+// next is the next line the client is sent
+func (c *testClient) next() string {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		c.t.Fatalf("got %q then %v", line, err)
+	}
+	return strings.TrimSuffix(line, "\n")
+}
+
+func (c *testClient) expect(want string) {
+	c.t.Helper()
+	if got := c.next(); got != want {
+		c.t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+// expectAll reads the lines sent to the client by one broadcast, they come in any order
+func (c *testClient) expectAll(want ...string) {
+	c.t.Helper()
+	pending := map[string]bool{}
+	for _, line := range want {
+		pending[line] = true
+	}
+	for range want {
+		got := c.next()
+		if !pending[got] {
+			c.t.Fatalf("got %q, want one of %q", got, want)
+		}
+		delete(pending, got)
+	}
+}
+
+// closed waits for the server to close the connection
+func (c *testClient) closed() {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if rest, err := io.ReadAll(c.r); err != nil || len(rest) > 0 {
+		c.t.Fatalf("got %q then %v, want the connection closed", rest, err)
+	}
+}
+
+func TestRooms(t *testing.T) {
+	h := New()
+	addr := listen(t, config, h)
+	amir := connect(t, addr, "amir")
+	sara := connect(t, addr, "sara")
+	bob := connect(t, addr, "bob")
+
+	amir.say("/join go")
+	amir.expect("* amir joined #go")
+	sara.say("/join #go")
+	sara.expect("* sara joined #go")
+	amir.expect("* sara joined #go")
+	bob.say("/join rust")
+	bob.expect("* bob joined #rust")
+
+	amir.say("hi gophers")
+	amir.expect("#go amir: hi gophers")
+	sara.expect("#go amir: hi gophers")
+
+	// * sara is in both rooms, plain lines go to the last one joined
+	sara.say("/join rust")
+	sara.expect("* sara joined #rust")
+	bob.expect("* sara joined #rust")
+	sara.say("hi crabs")
+	sara.expect("#rust sara: hi crabs")
+	bob.expect("#rust sara: hi crabs")
+	sara.say("/msg #go back")
+	sara.expect("#go sara: back")
+	amir.expect("#go sara: back")
+
+	bob.say("/msg #go let me in")
+	bob.expect("! you are not in #go")
+
+	sara.say("/leave #rust")
+	sara.expect("* sara left #rust")
+	bob.expect("* sara left #rust")
+	sara.say("still here")
+	sara.expect("#go sara: still here")
+	amir.expect("#go sara: still here")
+
+	if got := strings.Join(h.Rooms(), " "); got != "#go #rust" {
+		t.Errorf("rooms %q", got)
+	}
+
+	bob.say("/quit")
+	bob.expect("* bye")
+	bob.closed()
+	amir.say("/quit")
+	amir.expect("* bye")
+	sara.expect("* amir left #go (quit)")
+
+	if got := strings.Join(h.Rooms(), " "); got != "#go" {
+		t.Errorf("rooms %q after the quits", got)
+	}
+}
+
+func TestPrivate(t *testing.T) {
+	addr := listen(t, config, New())
+	amir := connect(t, addr, "amir")
+	sara := connect(t, addr, "sara")
+
+	amir.say("/msg sara psst")
+	sara.expect("amir -> sara: psst")
+	amir.expect("amir -> sara: psst")
+
+	amir.say("/msg nobody psst")
+	amir.expect("! no one is called nobody")
+}
+
+func TestNicknames(t *testing.T) {
+	addr := listen(t, config, New())
+	anon := connect(t, addr, "")
+	amir := connect(t, addr, "amir")
+
+	anon.say("/join go")
+	anon.expect("! pick a nickname first: /nick <name>")
+	anon.say("/nick amir")
+	anon.expect("! amir is taken")
+	anon.say("/nick not valid")
+	anon.expect("! a nickname is 1 to 16 letters, digits, - or _")
+	anon.say("/nick sara")
+	anon.expect("* you are now sara")
+
+	amir.say("/join go")
+	amir.expect("* amir joined #go")
+	anon.say("/join go")
+	anon.expect("* sara joined #go")
+	amir.expect("* sara joined #go")
+
+	amir.say("/nick amirhossein")
+	amir.expect("* you are now amirhossein")
+	amir.expect("* amir is now amirhossein")
+	anon.expect("* amir is now amirhossein")
+	anon.say("/msg amirhossein hi")
+	amir.expect("sara -> amirhossein: hi")
+
+	amir.say("/shout")
+	amir.expect("! unknown command /shout, try /nick /join /leave /msg /quit")
+}
+
+func TestIdleKick(t *testing.T) {
+	cfg := config
+	cfg.IdleTimeout = 100 * time.Millisecond
+	addr := listen(t, cfg, New())
+	amir := connect(t, addr, "amir")
+	sara := connect(t, addr, "sara")
+	amir.say("/join go")
+	amir.expect("* amir joined #go")
+	sara.say("/join go")
+	sara.expect("* sara joined #go")
+	amir.expect("* sara joined #go")
+
+	// * sara keeps talking, amir says nothing
+	kicked := false
+	deadline := time.Now().Add(cfg.IdleTimeout * 3 / 2)
+	for time.Now().Before(deadline) {
+		sara.say("anyone?")
+		for line := sara.next(); line != "#go sara: anyone?"; line = sara.next() {
+			if line != "* amir left #go (idle)" {
+				t.Fatalf("got %q", line)
+			}
+			kicked = true
+		}
+		time.Sleep(cfg.IdleTimeout / 4)
+	}
+
+	for {
+		line := amir.next()
+		if line == "! idle for too long, bye" {
+			break
+		}
+		if line != "#go sara: anyone?" {
+			t.Fatalf("got %q", line)
+		}
+	}
+	amir.closed()
+	if !kicked {
+		sara.expect("* amir left #go (idle)")
+	}
+}
+
+// pipeListener accepts the server ends of net.Pipe, whose writes wait for the reader
+type pipeListener struct {
+	conns  chan net.Conn
+	closed chan struct{}
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *pipeListener) Close() error {
+	select {
+	case <-l.closed:
+	default:
+		close(l.closed)
+	}
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr { return &net.UnixAddr{Name: "pipe", Net: "pipe"} }
+
+func (l *pipeListener) dial(t *testing.T, nick string) *testClient {
+	server, client := net.Pipe()
+	l.conns <- server
+	return attach(t, client, nick)
+}
+
+func TestSlowClient(t *testing.T) {
+	h := New()
+	h.QueueSize = 2
+	l := &pipeListener{conns: make(chan net.Conn), closed: make(chan struct{})}
+	cfg := config
+	cfg.WriteTimeout = time.Minute // * only the queue gives up on the slow client
+	start(t, cfg, h, l)
+
+	amir := l.dial(t, "amir")
+	slow := l.dial(t, "slow")
+	amir.say("/join go")
+	amir.expect("* amir joined #go")
+	slow.say("/join go")
+	slow.expect("* slow joined #go")
+	amir.expect("* slow joined #go")
+
+	// * slow stops reading: one line waits in its writer, QueueSize in its queue, the next drops it
+	dropped := -1
+	for i := 0; dropped < 0 && i <= h.QueueSize+1; i++ {
+		amir.say(fmt.Sprint("line ", i))
+		for line := amir.next(); line != fmt.Sprint("#go amir: line ", i); line = amir.next() {
+			if line != "* slow left #go (too slow)" {
+				t.Fatalf("got %q", line)
+			}
+			dropped = i
+		}
+	}
+	if dropped < 0 {
+		amir.expect("* slow left #go (too slow)")
+	}
+
+	amir.say("/msg slow are you there")
+	amir.expect("! no one is called slow")
+	slow.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := io.Copy(io.Discard, slow.conn); err != nil {
+		t.Errorf("the slow client wasn't disconnected: %v", err)
+	}
+}
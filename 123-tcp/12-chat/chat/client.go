@@ -0,0 +1,194 @@
+package chat
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"tcp/11-server/server"
+)
+
+type client struct {
+	conn *server.Conn
+	out  chan string // * closed by Hub.drop, the writer then ends
+
+	// * guarded by Hub.mu
+	nick    string
+	rooms   map[string]struct{}
+	current string
+	gone    bool
+}
+
+// joined lists the rooms of c in order, h.mu is held
+func (c *client) joined() []string {
+	rooms := make([]string, 0, len(c.rooms))
+	for room := range c.rooms {
+		rooms = append(rooms, room)
+	}
+	slices.Sort(rooms)
+	return rooms
+}
+
+// ServeConn reads the lines of a client until it quits, goes idle for the IdleTimeout of the
+// server or the server shuts down. Its own goroutine writes what the client is sent.
+func (h *Hub) ServeConn(ctx context.Context, conn *server.Conn) {
+	c := &client{conn: conn, out: make(chan string, max(h.QueueSize, 1)), rooms: map[string]struct{}{}}
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		c.write()
+	}()
+
+	reason := h.read(ctx, c)
+	h.remove(c, reason)
+	<-written
+}
+
+// write sends the queued lines until the queue is closed. Once a write fails the rest are
+// discarded, the reader then fails as well.
+func (c *client) write() {
+	failed := false
+	for line := range c.out {
+		if failed {
+			continue
+		}
+		// * one line at a time, a line buffered here would not count against QueueSize
+		if _, err := io.WriteString(c.conn, line+"\n"); err != nil {
+			failed = true
+			c.conn.Close()
+		}
+	}
+}
+
+// read runs the commands of the client and returns why it stopped
+func (h *Hub) read(ctx context.Context, c *client) string {
+	h.reply(c, "* welcome, pick a nickname with /nick <name>")
+	reader := bufio.NewReaderSize(c.conn, MaxLine)
+	for {
+		line, err := reader.ReadSlice('\n')
+		switch {
+		case errors.Is(err, bufio.ErrBufferFull):
+			h.reply(c, "! line too long, bye")
+			return reasonQuit
+		case errors.Is(err, os.ErrDeadlineExceeded) && c.conn.Idle():
+			h.reply(c, "! idle for too long, bye")
+			return reasonIdle
+		case err != nil && ctx.Err() != nil:
+			return reasonShutdown
+		case err != nil:
+			return reasonQuit
+		}
+
+		if !h.run(c, strings.TrimRight(string(line), "\r\n")) {
+			return reasonQuit
+		}
+		if ctx.Err() != nil {
+			h.reply(c, "* the server is shutting down, bye")
+			return reasonShutdown
+		}
+		if reader.Buffered() == 0 {
+			// * idle until the next line, Shutdown may close the connection meanwhile
+			c.conn.SetIdle()
+		}
+	}
+}
+
+// run runs one line and tells whether the client stays
+func (h *Hub) run(c *client, line string) bool {
+	cmd, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	if !strings.HasPrefix(cmd, "/") {
+		cmd, arg = "", line
+	}
+
+	switch {
+	case cmd == "/quit":
+		h.reply(c, "* bye")
+		return false
+	case cmd == "/nick":
+		if !validName(arg) {
+			h.reply(c, "! a nickname is 1 to 16 letters, digits, - or _")
+		} else if !h.setNick(c, arg) {
+			h.reply(c, "! "+arg+" is taken")
+		}
+	case h.nick(c) == "":
+		h.reply(c, "! pick a nickname first: /nick <name>")
+	case cmd == "/join":
+		room, ok := roomName(arg)
+		if !ok {
+			h.reply(c, "! usage: /join <room>")
+			return true
+		}
+		h.join(c, room)
+	case cmd == "/leave":
+		room := h.current(c)
+		if arg != "" {
+			room, _ = roomName(arg)
+		}
+		if room == "" {
+			h.reply(c, "! usage: /leave <room>")
+			return true
+		}
+		h.leave(c, room, "")
+	case cmd == "/msg":
+		to, text, _ := strings.Cut(arg, " ")
+		if to == "" || strings.TrimSpace(text) == "" {
+			h.reply(c, "! usage: /msg <room|nick> <text>")
+			return true
+		}
+		h.say(c, to, text)
+	case cmd != "":
+		h.reply(c, "! unknown command "+cmd+", try /nick /join /leave /msg /quit")
+	case strings.TrimSpace(arg) == "":
+	default:
+		room := h.current(c)
+		if room == "" {
+			h.reply(c, "! join a room first: /join <room>")
+			return true
+		}
+		h.say(c, room, arg)
+	}
+	return true
+}
+
+func (h *Hub) reply(c *client, line string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.send(c, line)
+}
+
+func (h *Hub) nick(c *client) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return c.nick
+}
+
+func (h *Hub) current(c *client) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return c.current
+}
+
+// roomName adds the # a room name may be given without
+func roomName(name string) (string, bool) {
+	name = strings.TrimPrefix(name, "#")
+	if !validName(name) {
+		return "", false
+	}
+	return "#" + name, true
+}
+
+func validName(name string) bool {
+	if name == "" || len(name) > 16 {
+		return false
+	}
+	for _, r := range name {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
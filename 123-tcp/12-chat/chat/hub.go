@@ -0,0 +1,205 @@
+// Package chat is a line based chat of several rooms, served by a server.Server.
+// Each line a client sends is a message to its current room or a command:
+//
+//	/nick <name>          pick a nickname, needed before anything else
+//	/join <room>          join a room, it becomes the current one
+//	/leave [room]         leave a room, the current one by default
+//	/msg <room|nick> text send to a room of yours, or privately to someone
+//	/quit
+package chat
+
+import (
+	"slices"
+	"strings"
+	"sync"
+)
+
+// DefaultQueueSize is the number of lines a client may fall behind before it is dropped
+const DefaultQueueSize = 64
+
+// MaxLine is the longest line a client may send, newline included
+const MaxLine = 1024
+
+// why a client left, as its rooms are told
+const (
+	reasonQuit     = "quit"
+	reasonIdle     = "idle"
+	reasonSlow     = "too slow"
+	reasonShutdown = "server shutting down"
+)
+
+type Hub struct {
+	// QueueSize is the number of lines waiting to be written to a client. A client whose
+	// queue is full is dropped, so a slow reader never blocks the others.
+	QueueSize int
+
+	mu    sync.Mutex
+	nicks map[string]*client
+	rooms map[string]map[*client]struct{}
+}
+
+func New() *Hub {
+	return &Hub{
+		QueueSize: DefaultQueueSize,
+		nicks:     map[string]*client{},
+		rooms:     map[string]map[*client]struct{}{},
+	}
+}
+
+// Rooms lists the rooms with someone in them
+func (h *Hub) Rooms() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	rooms := make([]string, 0, len(h.rooms))
+	for room := range h.rooms {
+		rooms = append(rooms, room)
+	}
+	slices.Sort(rooms)
+	return rooms
+}
+
+// send queues a line for c without waiting, h.mu is held
+func (h *Hub) send(c *client, line string) {
+	if c.gone {
+		return
+	}
+	select {
+	case c.out <- line:
+	default:
+		h.drop(c, reasonSlow)
+	}
+}
+
+// broadcast sends a line to the members of a room, h.mu is held
+func (h *Hub) broadcast(room, line string) {
+	// * send may drop a member and change the room, the loop runs over a copy
+	members := make([]*client, 0, len(h.rooms[room]))
+	for c := range h.rooms[room] {
+		members = append(members, c)
+	}
+	for _, c := range members {
+		h.send(c, line)
+	}
+}
+
+func (h *Hub) setNick(c *client, nick string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if other, taken := h.nicks[nick]; taken {
+		return other == c
+	}
+
+	old := c.nick
+	delete(h.nicks, old)
+	h.nicks[nick] = c
+	c.nick = nick
+	h.send(c, "* you are now "+nick)
+	for _, room := range c.joined() {
+		h.broadcast(room, "* "+old+" is now "+nick)
+	}
+	return true
+}
+
+func (h *Hub) join(c *client, room string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c.current = room
+	if _, ok := c.rooms[room]; ok {
+		h.send(c, "* you are in "+room)
+		return
+	}
+
+	if h.rooms[room] == nil {
+		h.rooms[room] = map[*client]struct{}{}
+	}
+	h.rooms[room][c] = struct{}{}
+	c.rooms[room] = struct{}{}
+	h.broadcast(room, "* "+c.nick+" joined "+room)
+}
+
+func (h *Hub) leave(c *client, room, reason string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := c.rooms[room]; !ok {
+		h.send(c, "! you are not in "+room)
+		return
+	}
+	h.broadcast(room, leftLine(c, room, reason))
+	h.part(c, room)
+}
+
+// part takes c out of a room, h.mu is held
+func (h *Hub) part(c *client, room string) {
+	delete(h.rooms[room], c)
+	if len(h.rooms[room]) == 0 {
+		delete(h.rooms, room)
+	}
+	delete(c.rooms, room)
+	if c.current == room {
+		c.current = ""
+		if rooms := c.joined(); len(rooms) > 0 {
+			c.current = rooms[0]
+		}
+	}
+}
+
+func leftLine(c *client, room, reason string) string {
+	line := "* " + c.nick + " left " + room
+	if reason != "" {
+		line += " (" + reason + ")"
+	}
+	return line
+}
+
+// say sends text to a room of c, or privately to the client with that nickname
+func (h *Hub) say(c *client, to, text string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if strings.HasPrefix(to, "#") {
+		if _, ok := c.rooms[to]; !ok {
+			h.send(c, "! you are not in "+to)
+			return
+		}
+		h.broadcast(to, to+" "+c.nick+": "+text)
+		return
+	}
+
+	other, ok := h.nicks[to]
+	if !ok {
+		h.send(c, "! no one is called "+to)
+		return
+	}
+	line := c.nick + " -> " + to + ": " + text
+	h.send(other, line)
+	if other != c {
+		h.send(c, line)
+	}
+}
+
+// remove takes c out of the chat and ends its queue, the rooms of c are told why
+func (h *Hub) remove(c *client, reason string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.drop(c, reason)
+}
+
+// drop removes c and closes its queue, h.mu is held. A client dropped for being slow has its
+// connection closed too, so that its reader stops.
+func (h *Hub) drop(c *client, reason string) {
+	if c.gone {
+		return
+	}
+	c.gone = true
+	close(c.out)
+	if reason == reasonSlow {
+		c.conn.Close()
+	}
+
+	if c.nick != "" && h.nicks[c.nick] == c {
+		delete(h.nicks, c.nick)
+	}
+	for _, room := range c.joined() {
+		h.part(c, room)
+		h.broadcast(room, leftLine(c, room, reason))
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+)
+
+// NOTE: The client
+/*
+	05-dial reads what the server sends and 06-dial-write writes to it, the chat client does both
+	at once: one goroutine copies the connection to stdout, main copies stdin to the connection.
+	It ends when the server closes the connection, after /quit or an idle kick.
+*/
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "chat server")
+	nick := flag.String("nick", "", "nickname to pick on connect")
+	flag.Parse()
+
+	connection, err := net.Dial("tcp", *addr)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer connection.Close()
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		io.Copy(os.Stdout, connection)
+	}()
+
+	if *nick != "" {
+		fmt.Fprintf(connection, "/nick %s\n", *nick)
+	}
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				// * end of stdin, the server answers /quit and closes the connection
+				fmt.Fprintln(connection, "/quit")
+				lines = nil
+				continue
+			}
+			fmt.Fprintln(connection, line)
+		case <-closed:
+			fmt.Println("disconnected")
+			return
+		}
+	}
+}
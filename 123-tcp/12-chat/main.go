@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"tcp/11-server/server"
+	"tcp/12-chat/chat"
+	"time"
+)
+
+// NOTE: From echo to chat
+/*
+	03-read-write answers each line to the client that sent it. The chat sends a line to everyone
+	in a room, so the writes to a client no longer follow its reads:
+
+	- each client has a queue of lines and a goroutine writing them, the broadcaster only queues
+	- a client that falls QueueSize lines behind is dropped instead of slowing down the room
+	- a client quiet for IdleTimeout is kicked, server.Conn moves its deadline after each line
+
+	Try it with the client, in a few terminals:
+
+	```bash
+	go run .
+	go run ./client -nick amir
+	```
+*/
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	idle := flag.Duration("idle", 5*time.Minute, "kick a client quiet for this long")
+	flag.Parse()
+
+	srv := server.New(server.Config{
+		MaxConns:     1000,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		IdleTimeout:  *idle,
+	}, chat.New())
+
+	go func() {
+		err := srv.ListenAndServe(*addr)
+		if !errors.Is(err, server.ErrServerClosed) {
+			log.Fatalln(err)
+		}
+	}()
+	fmt.Println("Chat is running on", *addr)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Println(err)
+	}
+}
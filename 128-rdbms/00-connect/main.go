@@ -2,38 +2,41 @@ package main
 
 import (
 	"database/sql"
-	"fmt"
 	"io"
+	"log"
 	"net/http"
 
 	_ "github.com/go-sql-driver/mysql"
 )
 
 var db *sql.DB
-var err error
 
 func main() {
 	// * user:password@tcp(localhost:3306)/db_name?charset=utf8
+	var err error
 	db, err = sql.Open("mysql", "root:123456@tcp(localhost:3306)/go_test?charset=utf8")
-	check(err)
+	if err != nil {
+		log.Fatalln(err)
+	}
 	defer db.Close()
 
-	err = db.Ping()
-	check(err)
+	// * without a database there is nothing to serve, stop here rather than on the first request
+	if err := db.Ping(); err != nil {
+		log.Fatalln(err)
+	}
 
 	http.HandleFunc("/", index)
 	http.Handle("/favicon.ico", http.NotFoundHandler())
-	err = http.ListenAndServe(":8080", nil)
-	check(err)
+	if err := http.ListenAndServe(":8080", nil); err != nil {
+		log.Println(err)
+	}
 }
 
 func index(w http.ResponseWriter, r *http.Request) {
-	_, err := io.WriteString(w, "Successfully completed")
-	check(err)
-}
-
-func check(err error) {
-	if err != nil {
-		fmt.Println(err)
+	if err := db.PingContext(r.Context()); err != nil {
+		log.Println(err)
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
 	}
+	io.WriteString(w, "Successfully completed")
 }
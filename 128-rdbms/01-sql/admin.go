@@ -0,0 +1,105 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// confirmTTL is how long a confirmation token of a destructive statement stays valid
+const confirmTTL = time.Minute
+
+// admin guards the DDL endpoints. A request needs the admin token, and runs in two steps:
+// the first POST returns a confirmation token, a second POST with confirm=<token> within
+// confirmTTL executes. A token is good for one execution of the action it was issued for.
+type admin struct {
+	token string // empty disables the admin endpoints
+
+	mu      sync.Mutex
+	pending map[string]confirmation
+}
+
+type confirmation struct {
+	action  string
+	expires time.Time
+}
+
+func newAdmin(token string) *admin {
+	return &admin{token: token, pending: map[string]confirmation{}}
+}
+
+// guard wraps the handler of a DDL action
+func (a *admin) guard(action string, h handlerFunc) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			return errorf(http.StatusMethodNotAllowed, "use POST")
+		}
+		if err := a.authorize(r); err != nil {
+			return err
+		}
+
+		confirm := r.FormValue("confirm")
+		if confirm == "" {
+			token, err := a.issue(action)
+			if err != nil {
+				return err
+			}
+			w.WriteHeader(http.StatusAccepted)
+			fmt.Fprintf(w, "CONFIRM %s WITH confirm=%s WITHIN %s\n", action, token, confirmTTL)
+			return nil
+		}
+		if !a.redeem(action, confirm) {
+			return errorf(http.StatusForbidden, "confirmation token is invalid, expired or already used")
+		}
+		return h(w, r)
+	}
+}
+
+func (a *admin) authorize(r *http.Request) error {
+	if a.token == "" {
+		return errorf(http.StatusForbidden, "admin endpoints are disabled, set ADMIN_TOKEN")
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return errorf(http.StatusUnauthorized, "admin token required")
+	}
+	if subtle.ConstantTimeCompare([]byte(got), []byte(a.token)) != 1 {
+		return errorf(http.StatusForbidden, "wrong admin token")
+	}
+	return nil
+}
+
+func (a *admin) issue(action string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	for t, c := range a.pending {
+		if now.After(c.expires) {
+			delete(a.pending, t)
+		}
+	}
+	a.pending[token] = confirmation{action: action, expires: now.Add(confirmTTL)}
+	return token, nil
+}
+
+// redeem consumes the token even when it was issued for another action
+func (a *admin) redeem(action, token string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	c, ok := a.pending[token]
+	delete(a.pending, token)
+	return ok && c.action == action && time.Now().Before(c.expires)
+}
@@ -0,0 +1,163 @@
+// Package dialect holds what differs between MySQL, Postgres and SQLite for the queries of
+// this app. Queries are written once with ? placeholders and rebound for the engine.
+package dialect
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Dialect interface {
+	// Name is also the database/sql driver name
+	Name() string
+	// Rebind turns the ? placeholders of query into the ones of the engine
+	Rebind(query string) string
+	// Quote makes an identifier safe to use, even when it is a reserved word
+	Quote(ident string) string
+	// AutoID is the column definition of an auto incremented primary key
+	AutoID(col string) string
+	// Upsert inserts a row or updates the columns of the row with the same conflict columns,
+	// run it with InsertID to get the id of the row either way
+	Upsert(table, idCol string, cols []string, conflict ...string) string
+	// InsertID runs an INSERT and returns the id of the row, also when an upsert updated it
+	InsertID(ctx context.Context, db *sql.DB, idCol, query string, args ...any) (int64, error)
+}
+
+// Lookup returns the dialect of a driver name
+func Lookup(name string) (Dialect, error) {
+	switch name {
+	case "mysql":
+		return MySQL, nil
+	case "postgres":
+		return Postgres, nil
+	case "sqlite3":
+		return SQLite, nil
+	}
+	return nil, fmt.Errorf("dialect: unknown driver %q, want mysql, postgres or sqlite3", name)
+}
+
+var (
+	MySQL    Dialect = mysqlDialect{}
+	Postgres Dialect = postgresDialect{}
+	SQLite   Dialect = sqliteDialect{}
+)
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string               { return "mysql" }
+func (mysqlDialect) Rebind(query string) string { return query }
+func (mysqlDialect) Quote(ident string) string  { return quote(ident, '`') }
+func (d mysqlDialect) AutoID(col string) string {
+	return d.Quote(col) + " INT AUTO_INCREMENT PRIMARY KEY"
+}
+
+// Upsert assigns the id with LAST_INSERT_ID(id), otherwise LastInsertId is 0 after an update.
+// MySQL has no conflict target, any unique key of the table triggers the update.
+func (d mysqlDialect) Upsert(table, idCol string, cols []string, _ ...string) string {
+	sets := []string{fmt.Sprintf("%s = LAST_INSERT_ID(%s)", d.Quote(idCol), d.Quote(idCol))}
+	for _, c := range cols {
+		sets = append(sets, fmt.Sprintf("%s = VALUES(%s)", d.Quote(c), d.Quote(c)))
+	}
+	return insert(d, table, cols) + " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+}
+
+// InsertID uses LastInsertId, MySQL has no RETURNING
+func (d mysqlDialect) InsertID(ctx context.Context, db *sql.DB, _, query string, args ...any) (int64, error) {
+	res, err := db.ExecContext(ctx, d.Rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string               { return "postgres" }
+func (postgresDialect) Rebind(query string) string { return rebindNumbered(query) }
+func (postgresDialect) Quote(ident string) string  { return quote(ident, '"') }
+func (d postgresDialect) AutoID(col string) string { return d.Quote(col) + " SERIAL PRIMARY KEY" }
+func (d postgresDialect) Upsert(table, _ string, cols []string, conflict ...string) string {
+	return onConflict(d, table, cols, conflict)
+}
+
+// InsertID uses RETURNING, lib/pq doesn't support LastInsertId
+func (d postgresDialect) InsertID(ctx context.Context, db *sql.DB, idCol, query string, args ...any) (int64, error) {
+	return returning(ctx, d, db, idCol, query, args)
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string               { return "sqlite3" }
+func (sqliteDialect) Rebind(query string) string { return query }
+func (sqliteDialect) Quote(ident string) string  { return quote(ident, '"') }
+func (d sqliteDialect) AutoID(col string) string {
+	return d.Quote(col) + " INTEGER PRIMARY KEY AUTOINCREMENT"
+}
+func (d sqliteDialect) Upsert(table, _ string, cols []string, conflict ...string) string {
+	return onConflict(d, table, cols, conflict)
+}
+
+// InsertID uses RETURNING, LastInsertId isn't set when an upsert updates (SQLite 3.35+)
+func (d sqliteDialect) InsertID(ctx context.Context, db *sql.DB, idCol, query string, args ...any) (int64, error) {
+	return returning(ctx, d, db, idCol, query, args)
+}
+
+func quote(ident string, q byte) string {
+	s := string(q)
+	return s + strings.ReplaceAll(ident, s, s+s) + s
+}
+
+func insert(d Dialect, table string, cols []string) string {
+	quoted := make([]string, len(cols))
+	for i, c := range cols {
+		quoted[i] = d.Quote(c)
+	}
+	marks := strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", ")
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", d.Quote(table), strings.Join(quoted, ", "), marks)
+}
+
+// onConflict is the upsert of Postgres and SQLite, excluded is the row that failed to insert
+func onConflict(d Dialect, table string, cols, conflict []string) string {
+	keys := make([]string, len(conflict))
+	for i, c := range conflict {
+		keys[i] = d.Quote(c)
+	}
+	sets := make([]string, len(cols))
+	for i, c := range cols {
+		sets[i] = fmt.Sprintf("%s = excluded.%s", d.Quote(c), d.Quote(c))
+	}
+	return fmt.Sprintf("%s ON CONFLICT (%s) DO UPDATE SET %s", insert(d, table, cols), strings.Join(keys, ", "), strings.Join(sets, ", "))
+}
+
+func returning(ctx context.Context, d Dialect, db *sql.DB, idCol, query string, args []any) (int64, error) {
+	var id int64
+	err := db.QueryRowContext(ctx, d.Rebind(query)+" RETURNING "+d.Quote(idCol), args...).Scan(&id)
+	return id, err
+}
+
+// rebindNumbered writes $1, $2, ... for the ? outside of string literals and quoted identifiers
+func rebindNumbered(query string) string {
+	var sb strings.Builder
+	n := 0
+	var inQuote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case inQuote != 0:
+			if c == inQuote {
+				inQuote = 0
+			}
+		case c == '\'' || c == '"':
+			inQuote = c
+		case c == '?':
+			n++
+			sb.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
@@ -0,0 +1,96 @@
+package dialect
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+func TestRebind(t *testing.T) {
+	query := `UPDATE t SET a = ?, b = '?' WHERE "c?" = ? AND d = ?`
+
+	if got := MySQL.Rebind(query); got != query {
+		t.Errorf("mysql: got %s", got)
+	}
+	want := `UPDATE t SET a = $1, b = '?' WHERE "c?" = $2 AND d = $3`
+	if got := Postgres.Rebind(query); got != want {
+		t.Errorf("postgres: got %s, want %s", got, want)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		d    Dialect
+		want string
+	}{
+		{MySQL, "`or``der`"},
+		{Postgres, "\"or`der\""},
+		{SQLite, "\"or`der\""},
+	}
+	for _, tt := range tests {
+		if got := tt.d.Quote("or`der"); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.d.Name(), got, tt.want)
+		}
+	}
+	if got, want := Postgres.Quote(`a"b`), `"a""b"`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	tests := []struct {
+		d    Dialect
+		want string
+	}{
+		{MySQL, "INSERT INTO `users` (`name`, `age`) VALUES (?, ?) ON DUPLICATE KEY UPDATE `id` = LAST_INSERT_ID(`id`), `name` = VALUES(`name`), `age` = VALUES(`age`)"},
+		{Postgres, `INSERT INTO "users" ("name", "age") VALUES (?, ?) ON CONFLICT ("name") DO UPDATE SET "name" = excluded."name", "age" = excluded."age"`},
+		{SQLite, `INSERT INTO "users" ("name", "age") VALUES (?, ?) ON CONFLICT ("name") DO UPDATE SET "name" = excluded."name", "age" = excluded."age"`},
+	}
+	for _, tt := range tests {
+		if got := tt.d.Upsert("users", "id", []string{"name", "age"}, "name"); got != tt.want {
+			t.Errorf("%s:\ngot  %s\nwant %s", tt.d.Name(), got, tt.want)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for _, name := range []string{"mysql", "postgres", "sqlite3"} {
+		d, err := Lookup(name)
+		if err != nil || d.Name() != name {
+			t.Errorf("Lookup(%s): got %v, %v", name, d, err)
+		}
+	}
+	if _, err := Lookup("oracle"); err == nil {
+		t.Error("got no error for an unknown driver")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err  error
+		want error
+	}{
+		{sql.ErrNoRows, ErrNotFound},
+		{&mysql.MySQLError{Number: 1062}, ErrDuplicate},
+		{&mysql.MySQLError{Number: 1213}, ErrConflict},
+		{&mysql.MySQLError{Number: 1146}, ErrNoTable},
+		{&pq.Error{Code: "23505"}, ErrDuplicate},
+		{&pq.Error{Code: "40001"}, ErrConflict},
+		{&pq.Error{Code: "42P07"}, ErrTableExists},
+	}
+	for _, tt := range tests {
+		got := Classify(fmt.Errorf("query: %w", tt.err))
+		if !errors.Is(got, tt.want) || !errors.Is(got, tt.err) {
+			t.Errorf("Classify(%v) = %v, want %v wrapping the driver error", tt.err, got, tt.want)
+		}
+	}
+
+	other := errors.New("connection refused")
+	if got := Classify(other); got != other {
+		t.Errorf("got %v, want the error unchanged", got)
+	}
+}
@@ -0,0 +1,72 @@
+package dialect
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// The kinds of driver errors a caller can act on, whatever the engine
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrDuplicate   = errors.New("duplicate key")
+	ErrConflict    = errors.New("concurrent transaction conflict, retry")
+	ErrNoTable     = errors.New("table does not exist")
+	ErrTableExists = errors.New("table already exists")
+)
+
+// classifiers of the drivers compiled in, sqlite3 adds its own when built with cgo
+var classifiers = []func(err error) error{classifyMySQL, classifyPostgres}
+
+// Classify wraps a driver error with the kind it belongs to, e.g. errors.Is(err, ErrDuplicate)
+// for a unique violation on any engine. Other errors are returned as they are.
+func Classify(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+	for _, classify := range classifiers {
+		if kind := classify(err); kind != nil {
+			return fmt.Errorf("%w: %w", kind, err)
+		}
+	}
+	return err
+}
+
+func classifyMySQL(err error) error {
+	var myErr *mysql.MySQLError
+	if !errors.As(err, &myErr) {
+		return nil
+	}
+	switch myErr.Number {
+	case 1062: // ER_DUP_ENTRY
+		return ErrDuplicate
+	case 1213, 1205: // ER_LOCK_DEADLOCK, ER_LOCK_WAIT_TIMEOUT
+		return ErrConflict
+	case 1146: // ER_NO_SUCH_TABLE
+		return ErrNoTable
+	case 1050: // ER_TABLE_EXISTS_ERROR
+		return ErrTableExists
+	}
+	return nil
+}
+
+func classifyPostgres(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return nil
+	}
+	switch pqErr.Code {
+	case "23505": // unique_violation
+		return ErrDuplicate
+	case "40P01", "40001": // deadlock_detected, serialization_failure
+		return ErrConflict
+	case "42P01": // undefined_table
+		return ErrNoTable
+	case "42P07": // duplicate_table
+		return ErrTableExists
+	}
+	return nil
+}
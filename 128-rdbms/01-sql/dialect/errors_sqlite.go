@@ -0,0 +1,33 @@
+//go:build cgo
+
+package dialect
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+func init() {
+	classifiers = append(classifiers, classifySQLite)
+}
+
+// classifySQLite reads the extended result codes, missing and existing tables only have a message
+func classifySQLite(err error) error {
+	var liteErr sqlite3.Error
+	if !errors.As(err, &liteErr) {
+		return nil
+	}
+	switch {
+	case liteErr.ExtendedCode == sqlite3.ErrConstraintUnique || liteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey:
+		return ErrDuplicate
+	case liteErr.Code == sqlite3.ErrBusy || liteErr.Code == sqlite3.ErrLocked:
+		return ErrConflict
+	case strings.HasPrefix(liteErr.Error(), "no such table"):
+		return ErrNoTable
+	case strings.Contains(liteErr.Error(), "already exists"):
+		return ErrTableExists
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"rdbms/01-sql/dialect"
+)
+
+// handlerFunc is a handler that returns its error instead of writing it, handle turns it into a response
+type handlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// httpError is an error with the status and message to send for it
+type httpError struct {
+	status int
+	msg    string
+}
+
+func (e *httpError) Error() string {
+	return fmt.Sprintf("%d %s", e.status, e.msg)
+}
+
+func errorf(status int, format string, args ...any) error {
+	return &httpError{status: status, msg: fmt.Sprintf(format, args...)}
+}
+
+// handle is the error middleware, every route goes through it
+func handle(h handlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := h(w, r)
+		if err == nil {
+			return
+		}
+
+		// The client is gone, there is nobody to answer
+		if errors.Is(err, context.Canceled) && r.Context().Err() != nil {
+			return
+		}
+
+		status, msg := statusOf(dialect.Classify(err))
+		if status >= http.StatusInternalServerError {
+			log.Printf("%s %s: %v\n", r.Method, r.URL.Path, err)
+		}
+		if status == http.StatusServiceUnavailable {
+			w.Header().Set("Retry-After", "1")
+		}
+		http.Error(w, msg, status)
+	}
+}
+
+// statusOf maps an error to a response. Driver details stay in the log, the client
+// only learns the kind of error.
+func statusOf(err error) (int, string) {
+	var he *httpError
+	switch {
+	case errors.As(err, &he):
+		return he.status, he.msg
+	case errors.Is(err, dialect.ErrNotFound):
+		return http.StatusNotFound, "record not found"
+	case errors.Is(err, dialect.ErrNoTable):
+		return http.StatusNotFound, "table does not exist"
+	case errors.Is(err, dialect.ErrDuplicate):
+		return http.StatusConflict, "record already exists"
+	case errors.Is(err, dialect.ErrTableExists):
+		return http.StatusConflict, "table already exists"
+	case errors.Is(err, dialect.ErrConflict):
+		return http.StatusServiceUnavailable, "conflict with a concurrent request, retry"
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, http.StatusText(http.StatusGatewayTimeout)
+	}
+	return http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"rdbms/01-sql/dialect"
+	"strings"
+)
+
+// server runs the same queries on any engine, they are written with ? placeholders and
+// rebound by the dialect
+type server struct {
+	db    *sql.DB
+	d     dialect.Dialect
+	admin *admin
+}
+
+func newServer(db *sql.DB, d dialect.Dialect, adminToken string) *server {
+	return &server{db: db, d: d, admin: newAdmin(adminToken)}
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", handle(s.index))
+	mux.HandleFunc("/users", handle(s.users))
+	mux.HandleFunc("/insert", handle(s.insert))
+	mux.HandleFunc("/read", handle(s.read))
+	mux.HandleFunc("/update", handle(s.update))
+	mux.HandleFunc("/delete", handle(s.delete))
+	// * DDL only for the admin, see admin.go
+	mux.HandleFunc("/admin/create", handle(s.admin.guard("create", s.create)))
+	mux.HandleFunc("/admin/drop", handle(s.admin.guard("drop", s.drop)))
+	mux.Handle("/favicon.ico", http.NotFoundHandler())
+	return mux
+}
+
+func (s *server) index(w http.ResponseWriter, r *http.Request) error {
+	if r.URL.Path != "/" {
+		return errorf(http.StatusNotFound, "no route %s", r.URL.Path)
+	}
+	_, err := io.WriteString(w, "at index")
+	return err
+}
+
+func (s *server) users(w http.ResponseWriter, r *http.Request) error {
+	rows, err := s.db.QueryContext(r.Context(), "SELECT fName FROM user_table")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// data to be used in query
+	var name string
+	var sb strings.Builder
+	sb.WriteString("RETRIEVED RECORDS:\n")
+
+	// query
+	for rows.Next() {
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		sb.WriteString(name + "\n")
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// * written once everything is read, an error can still become a proper response
+	fmt.Fprintln(w, sb.String())
+	return nil
+}
+
+func (s *server) create(w http.ResponseWriter, r *http.Request) error {
+	// * the id column and the unique name are what the upsert of insert needs
+	query := fmt.Sprintf("CREATE TABLE customer_table (%s, name VARCHAR(20) NOT NULL UNIQUE)", s.d.AutoID("id"))
+	if _, err := s.db.ExecContext(r.Context(), query); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "CREATED TABLE customer_table")
+	return nil
+}
+
+// insert adds a customer, or finds the one with the same name: /insert?name=James
+func (s *server) insert(w http.ResponseWriter, r *http.Request) error {
+	name, err := nameValue(r, "name", "James")
+	if err != nil {
+		return err
+	}
+
+	query := s.d.Upsert("customer_table", "id", []string{"name"}, "name")
+	id, err := s.d.InsertID(r.Context(), s.db, "id", query, name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "INSERTED RECORD", id)
+	return nil
+}
+
+func (s *server) read(w http.ResponseWriter, r *http.Request) error {
+	// * return at most one row, sql.ErrNoRows becomes a 404
+	var name string
+	err := s.db.QueryRowContext(r.Context(), "SELECT name FROM customer_table ORDER BY id").Scan(&name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "RECEIVED RECORD", name)
+	return nil
+}
+
+// update renames a customer: /update?from=James&to=Jimmy. Taking the name of another
+// customer is a unique violation, answered with 409.
+func (s *server) update(w http.ResponseWriter, r *http.Request) error {
+	from, err := nameValue(r, "from", "James")
+	if err != nil {
+		return err
+	}
+	to, err := nameValue(r, "to", "Jimmy")
+	if err != nil {
+		return err
+	}
+
+	res, err := s.db.ExecContext(r.Context(), s.d.Rebind("UPDATE customer_table SET name = ? WHERE name = ?"), to, from)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "UPDATED RECORD", n)
+	return nil
+}
+
+// delete removes a customer: /delete?name=Jimmy
+func (s *server) delete(w http.ResponseWriter, r *http.Request) error {
+	name, err := nameValue(r, "name", "Jimmy")
+	if err != nil {
+		return err
+	}
+
+	res, err := s.db.ExecContext(r.Context(), s.d.Rebind("DELETE FROM customer_table WHERE name = ?"), name)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "DELETED RECORD", n)
+	return nil
+}
+
+func (s *server) drop(w http.ResponseWriter, r *http.Request) error {
+	if _, err := s.db.ExecContext(r.Context(), "DROP TABLE customer_table"); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "DROP TABLE customer_table")
+	return nil
+}
+
+// nameValue reads a customer name, it must fit the VARCHAR(20) column
+func nameValue(r *http.Request, key, fallback string) (string, error) {
+	v := r.FormValue(key)
+	if v == "" {
+		return fallback, nil
+	}
+	if len(v) > 20 {
+		return "", errorf(http.StatusBadRequest, "%s is longer than 20 characters", key)
+	}
+	return v, nil
+}
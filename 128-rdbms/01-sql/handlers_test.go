@@ -0,0 +1,168 @@
+package main
+
+import (
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"rdbms/01-sql/dialect"
+	"strings"
+	"testing"
+)
+
+const testAdminToken = "secret"
+
+// testHandlers runs every handler in order against a database of the dialect, the _test files
+// of each engine call it. The tables are dropped first, the database may keep them from a failed run.
+func testHandlers(t *testing.T, db *sql.DB, d dialect.Dialect) {
+	db.Exec("DROP TABLE customer_table")
+	db.Exec("DROP TABLE user_table")
+	t.Cleanup(func() {
+		db.Exec("DROP TABLE customer_table")
+		db.Exec("DROP TABLE user_table")
+	})
+
+	mustExec(t, db, "CREATE TABLE user_table (fName VARCHAR(20))")
+	mustExec(t, db, d.Rebind("INSERT INTO user_table (fName) VALUES (?), (?)"), "Amir", "Faeze")
+
+	srv := httptest.NewServer(newServer(db, d, testAdminToken).routes())
+	defer srv.Close()
+
+	steps := []struct {
+		path   string
+		status int
+		want   string
+	}{
+		{"/", http.StatusOK, "at index"},
+		{"/users", http.StatusOK, "RETRIEVED RECORDS:\nAmir\nFaeze\n"},
+		{"/read", http.StatusNotFound, "table does not exist"},
+		{"/admin/create", http.StatusOK, "CREATED TABLE customer_table"},
+		{"/admin/create", http.StatusConflict, "table already exists"},
+		{"/read", http.StatusNotFound, "record not found"},
+		{"/insert", http.StatusOK, "INSERTED RECORD 1"},
+		{"/insert?name=Bond", http.StatusOK, "INSERTED RECORD 2"},
+		{"/insert", http.StatusOK, "INSERTED RECORD 1"}, // the upsert finds James again
+		{"/read", http.StatusOK, "RECEIVED RECORD James"},
+		{"/update?to=Bond", http.StatusConflict, "record already exists"},
+		{"/update", http.StatusOK, "UPDATED RECORD 1"},
+		{"/update?from=Nobody&to=X", http.StatusOK, "UPDATED RECORD 0"},
+		{"/update?to=" + strings.Repeat("x", 21), http.StatusBadRequest, "to is longer than 20 characters"},
+		{"/read", http.StatusOK, "RECEIVED RECORD Jimmy"},
+		{"/delete", http.StatusOK, "DELETED RECORD 1"},
+		{"/read", http.StatusOK, "RECEIVED RECORD Bond"},
+		{"/admin/drop", http.StatusOK, "DROP TABLE customer_table"},
+	}
+
+	for _, step := range steps {
+		var res *http.Response
+		if strings.HasPrefix(step.path, "/admin/") {
+			res = confirmed(t, srv.URL+step.path)
+		} else {
+			res = do(t, http.MethodGet, srv.URL+step.path, "", nil)
+		}
+		body := read(t, res)
+
+		if res.StatusCode != step.status || body != strings.TrimSpace(step.want) {
+			t.Fatalf("GET %s: got %d %q, want %d %q", step.path, res.StatusCode, body, step.status, step.want)
+		}
+	}
+}
+
+func TestAdminGuard(t *testing.T) {
+	ran := 0
+	a := newAdmin(testAdminToken)
+	srv := httptest.NewServer(handle(a.guard("drop", func(w http.ResponseWriter, r *http.Request) error {
+		ran++
+		return nil
+	})))
+	defer srv.Close()
+
+	if res := do(t, http.MethodGet, srv.URL, testAdminToken, nil); res.StatusCode != http.StatusMethodNotAllowed || res.Header.Get("Allow") != http.MethodPost {
+		t.Errorf("GET: got %d, want %d with Allow: POST", res.StatusCode, http.StatusMethodNotAllowed)
+	}
+	if res := do(t, http.MethodPost, srv.URL, "", nil); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("no token: got %d, want %d", res.StatusCode, http.StatusUnauthorized)
+	}
+	if res := do(t, http.MethodPost, srv.URL, "guess", nil); res.StatusCode != http.StatusForbidden {
+		t.Errorf("wrong token: got %d, want %d", res.StatusCode, http.StatusForbidden)
+	}
+	if res := do(t, http.MethodPost, srv.URL, testAdminToken, url.Values{"confirm": {"made-up"}}); res.StatusCode != http.StatusForbidden {
+		t.Errorf("made up confirmation: got %d, want %d", res.StatusCode, http.StatusForbidden)
+	}
+
+	// A token of another action is refused, and used up
+	other, _ := a.issue("create")
+	if res := do(t, http.MethodPost, srv.URL, testAdminToken, url.Values{"confirm": {other}}); res.StatusCode != http.StatusForbidden {
+		t.Errorf("token of another action: got %d, want %d", res.StatusCode, http.StatusForbidden)
+	}
+
+	token := issued(t, do(t, http.MethodPost, srv.URL, testAdminToken, nil))
+	if res := do(t, http.MethodPost, srv.URL, testAdminToken, url.Values{"confirm": {token}}); res.StatusCode != http.StatusOK {
+		t.Errorf("confirmed: got %d, want %d", res.StatusCode, http.StatusOK)
+	}
+	if res := do(t, http.MethodPost, srv.URL, testAdminToken, url.Values{"confirm": {token}}); res.StatusCode != http.StatusForbidden {
+		t.Errorf("token used twice: got %d, want %d", res.StatusCode, http.StatusForbidden)
+	}
+	if ran != 1 {
+		t.Errorf("got %d runs, want 1", ran)
+	}
+
+	disabled := httptest.NewServer(handle(newAdmin("").guard("drop", nil)))
+	defer disabled.Close()
+	if res := do(t, http.MethodPost, disabled.URL, "", nil); res.StatusCode != http.StatusForbidden {
+		t.Errorf("without admin token: got %d, want %d", res.StatusCode, http.StatusForbidden)
+	}
+}
+
+// confirmed runs an admin action with both steps
+func confirmed(t *testing.T, u string) *http.Response {
+	token := issued(t, do(t, http.MethodPost, u, testAdminToken, nil))
+	return do(t, http.MethodPost, u, testAdminToken, url.Values{"confirm": {token}})
+}
+
+func issued(t *testing.T, res *http.Response) string {
+	t.Helper()
+	body := read(t, res)
+	_, token, ok := strings.Cut(body, "confirm=")
+	if res.StatusCode != http.StatusAccepted || !ok {
+		t.Fatalf("got %d %q, want a confirmation token", res.StatusCode, body)
+	}
+	token, _, _ = strings.Cut(token, " ")
+	return token
+}
+
+func do(t *testing.T, method, u, token string, form url.Values) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, u, strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+	return res
+}
+
+func read(t *testing.T, res *http.Response) string {
+	t.Helper()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(body))
+}
+
+func mustExec(t *testing.T, db *sql.DB, query string, args ...any) {
+	t.Helper()
+	if _, err := db.Exec(query, args...); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,26 @@
+//go:build mysql
+
+package main
+
+import (
+	"database/sql"
+	"os"
+	"rdbms/01-sql/dialect"
+	"testing"
+)
+
+// go test -tags mysql ./01-sql with MYSQL_DSN, e.g. root:123456@tcp(localhost:3306)/go_test
+func TestHandlersMySQL(t *testing.T) {
+	dsn := os.Getenv("MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("MYSQL_DSN is not set")
+	}
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	testHandlers(t, db, dialect.MySQL)
+}
@@ -0,0 +1,6 @@
+//go:build cgo
+
+package main
+
+// The sqlite3 driver is a cgo package, without a C compiler the app runs on MySQL and Postgres only
+import _ "github.com/mattn/go-sqlite3"
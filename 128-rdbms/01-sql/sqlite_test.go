@@ -0,0 +1,19 @@
+//go:build cgo
+
+package main
+
+import (
+	"database/sql"
+	"rdbms/01-sql/dialect"
+	"testing"
+)
+
+func TestHandlersSQLite(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:"+t.TempDir()+"/test.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	testHandlers(t, db, dialect.SQLite)
+}
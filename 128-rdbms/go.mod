@@ -3,6 +3,9 @@ module rdbms
 go 1.23.1
 
 require (
-	filippo.io/edwards25519 v1.1.0 // indirect
-	github.com/go-sql-driver/mysql v1.9.0 // indirect
+	github.com/go-sql-driver/mysql v1.9.0
+	github.com/lib/pq v1.10.9
+	github.com/mattn/go-sqlite3 v1.14.24
 )
+
+require filippo.io/edwards25519 v1.1.0 // indirect
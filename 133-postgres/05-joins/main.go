@@ -1,5 +1,10 @@
 package main
 
+import (
+	"fmt"
+	"log"
+)
+
 // NOTE: 1. INNER JOIN (JOIN: Default INNER JOIN)
 /*
 	The INNER JOIN returns only the rows that have matching values in both tables.
@@ -227,5 +232,11 @@ package main
 */
 
 func main() {
-
+	for _, j := range joins {
+		sql, args, err := j.q.Build()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Printf("-- %s %v\n%s;\n\n", j.name, args, sql)
+	}
 }
@@ -0,0 +1,33 @@
+package main
+
+import "go/go-examples/133-postgres/18-query-builder/qb"
+
+// The example queries of the notes in main.go, built with qb (see 18-query-builder).
+// queries_test.go keeps the generated SQL in testdata/*.golden.
+var joins = []struct {
+	name string
+	q    qb.SelectQuery
+}{
+	{"inner_join", qb.Select("p.ID", "p.NAME", "ph.PHONE").From("people p").
+		Join("phonenumbers ph", qb.ColEq("p.ID", "ph.PERSON_ID"))},
+	{"left_join", qb.Select("p.ID", "p.NAME", "ph.PHONE").From("people p").
+		LeftJoin("phonenumbers ph", qb.ColEq("p.ID", "ph.PERSON_ID"))},
+	{"left_join_unmatched", qb.Select("p.ID", "p.NAME").From("people p").
+		LeftJoin("phonenumbers ph", qb.ColEq("p.ID", "ph.PERSON_ID")).
+		Where(qb.IsNull("ph.PERSON_ID"))},
+	{"right_join", qb.Select("p.ID", "p.NAME", "ph.PHONE").From("people p").
+		RightJoin("phonenumbers ph", qb.ColEq("p.ID", "ph.PERSON_ID"))},
+	{"full_outer_join", qb.Select("p.ID", "p.NAME", "ph.PHONE").From("people p").
+		FullJoin("phonenumbers ph", qb.ColEq("p.ID", "ph.PERSON_ID"))},
+	{"cross_join", qb.Select("p.NAME", "ph.PHONE").From("people p").
+		CrossJoin("phonenumbers ph")},
+	{"self_join", qb.Select("e1.NAME AS Employee", "e2.NAME AS Manager").From("employees e1").
+		LeftJoin("employees e2", qb.ColEq("e1.MANAGER_ID", "e2.ID"))},
+	{"natural_join", qb.Select().From("people").
+		NaturalJoin("phonenumbers")},
+	// A filtered join, the values become placeholders
+	{"inner_join_filtered", qb.Select("p.NAME", "ph.PHONE").From("people p").
+		Join("phonenumbers ph", qb.ColEq("p.ID", "ph.PERSON_ID")).
+		Where(qb.Or(qb.ILike("p.NAME", "a%"), qb.In("p.ID", 1, 2))).
+		OrderBy("p.NAME").Limit(10)},
+}
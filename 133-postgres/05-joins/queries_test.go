@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// go test -update writes the golden files again after an intended change to the SQL
+func TestJoinsGolden(t *testing.T) {
+	for _, j := range joins {
+		t.Run(j.name, func(t *testing.T) {
+			sql, args, err := j.q.Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := fmt.Sprintf("%s\n-- args: %v\n", sql, args)
+
+			path := filepath.Join("testdata", j.name+".golden")
+			if *update {
+				if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("got\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+// Package rental checks movies out to customers and back in, each change in one transaction
+package rental
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrNoCustomer      = errors.New("no such customer")
+	ErrNoMovie         = errors.New("no such movie")
+	ErrNoRental        = errors.New("no such rental")
+	ErrLimitReached    = errors.New("customer reached the rental limit")
+	ErrUnavailable     = errors.New("every copy of the movie is rented")
+	ErrAlreadyReturned = errors.New("rental was already returned")
+)
+
+type Rental struct {
+	ID         int64
+	CustomerID int64
+	MovieID    int64
+	RentedAt   time.Time
+	ReturnedAt *time.Time // nil while the movie is out
+}
+
+// admit decides a checkout from the counts read in the transaction, a limit of 0 means no limit
+func admit(open, limit, rented, copies int) error {
+	if limit > 0 && open >= limit {
+		return fmt.Errorf("%w: %d of %d movies out", ErrLimitReached, open, limit)
+	}
+	if rented >= copies {
+		return fmt.Errorf("%w: %d of %d copies out", ErrUnavailable, rented, copies)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package rental
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// The join queries of the notes in main.go, each with a row type.
+// TRIM drops the padding of the CHAR(50) columns.
+
+// CustomerMovie is a row of the customers, rentals and movies join
+type CustomerMovie struct {
+	RentalID   int64
+	Customer   string
+	Movie      string
+	RentedAt   time.Time
+	ReturnedAt *time.Time
+}
+
+// Availability is a movie with its copies out and left
+type Availability struct {
+	MovieID   int64
+	Movie     string
+	Copies    int
+	Rented    int
+	Available int
+}
+
+// CustomerCount is a customer with the number of movies rented so far
+type CustomerCount struct {
+	CustomerID int64
+	Customer   string
+	Rentals    int
+	Open       int
+}
+
+// CustomerMovies lists who rented what, the newest rental first. A customerID of 0 lists every customer.
+func (s *Service) CustomerMovies(ctx context.Context, customerID int64, openOnly bool) ([]CustomerMovie, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT r.ID, TRIM(c.FIRST), TRIM(m.MOVIE), r.RENTED_AT, r.RETURNED_AT
+		FROM customers c
+		INNER JOIN rentals r ON c.ID = r.C_ID
+		INNER JOIN movies m ON r.M_ID = m.ID
+		WHERE ($1 = 0 OR c.ID = $1) AND (NOT $2 OR r.RETURNED_AT IS NULL)
+		ORDER BY r.RENTED_AT DESC, r.ID DESC`, customerID, openOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cms := make([]CustomerMovie, 0)
+	for rows.Next() {
+		cm := CustomerMovie{}
+		var returned sql.NullTime
+		if err := rows.Scan(&cm.RentalID, &cm.Customer, &cm.Movie, &cm.RentedAt, &returned); err != nil {
+			return nil, err
+		}
+		if returned.Valid {
+			cm.ReturnedAt = &returned.Time
+		}
+		cms = append(cms, cm)
+	}
+	return cms, rows.Err()
+}
+
+// MovieAvailability lists every movie, rented or not, by title
+func (s *Service) MovieAvailability(ctx context.Context) ([]Availability, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT m.ID, TRIM(m.MOVIE), m.COPIES, count(r.ID)
+		FROM movies m
+		LEFT JOIN rentals r ON r.M_ID = m.ID AND r.RETURNED_AT IS NULL
+		GROUP BY m.ID
+		ORDER BY m.MOVIE`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	avs := make([]Availability, 0)
+	for rows.Next() {
+		av := Availability{}
+		if err := rows.Scan(&av.MovieID, &av.Movie, &av.Copies, &av.Rented); err != nil {
+			return nil, err
+		}
+		av.Available = max(av.Copies-av.Rented, 0)
+		avs = append(avs, av)
+	}
+	return avs, rows.Err()
+}
+
+// TopCustomers lists the limit customers with the most rentals
+func (s *Service) TopCustomers(ctx context.Context, limit int) ([]CustomerCount, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT c.ID, TRIM(c.FIRST), count(r.ID), count(r.ID) FILTER (WHERE r.RETURNED_AT IS NULL)
+		FROM customers c
+		INNER JOIN rentals r ON c.ID = r.C_ID
+		GROUP BY c.ID
+		ORDER BY count(r.ID) DESC, c.FIRST
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ccs := make([]CustomerCount, 0)
+	for rows.Next() {
+		cc := CustomerCount{}
+		if err := rows.Scan(&cc.CustomerID, &cc.Customer, &cc.Rentals, &cc.Open); err != nil {
+			return nil, err
+		}
+		ccs = append(ccs, cc)
+	}
+	return ccs, rows.Err()
+}
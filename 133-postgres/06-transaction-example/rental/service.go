@@ -0,0 +1,158 @@
+package rental
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Postgres error codes of a transaction that lost a race and may succeed when run again
+const (
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+type Options struct {
+	Isolation  sql.IsolationLevel
+	MaxRentals int           // movies a customer may have out at once, 0 means no limit
+	Attempts   int           // runs of a transaction failing with a serialization error
+	Backoff    time.Duration // first wait between runs, doubled on each retry
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Isolation:  sql.LevelSerializable,
+		MaxRentals: 3,
+		Attempts:   5,
+		Backoff:    10 * time.Millisecond,
+	}
+}
+
+type Service struct {
+	db   *sql.DB
+	opts Options
+}
+
+func NewService(db *sql.DB, opts Options) *Service {
+	if opts.Attempts < 1 {
+		opts.Attempts = 1
+	}
+	return &Service{db: db, opts: opts}
+}
+
+// Checkout rents a movie to a customer. The customer and movie rows are written first, so
+// two checkouts competing for the last copy or the last slot of a customer run one after
+// the other even below serializable isolation. A SELECT ... FOR UPDATE would wait as well,
+// but under repeatable read the count after it would still come from the snapshot taken
+// before the wait. Updating a row a concurrent transaction updated fails there instead,
+// with a serialization error that runs the checkout again.
+func (s *Service) Checkout(ctx context.Context, customerID, movieID int64) (Rental, error) {
+	var rt Rental
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, "UPDATE customers SET FIRST = FIRST WHERE ID = $1 RETURNING ID", customerID).Scan(new(int64))
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %d", ErrNoCustomer, customerID)
+		} else if err != nil {
+			return err
+		}
+
+		var copies int
+		err = tx.QueryRowContext(ctx, "UPDATE movies SET COPIES = COPIES WHERE ID = $1 RETURNING COPIES", movieID).Scan(&copies)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %d", ErrNoMovie, movieID)
+		} else if err != nil {
+			return err
+		}
+
+		var open, rented int
+		err = tx.QueryRowContext(ctx, `SELECT
+			count(*) FILTER (WHERE C_ID = $1),
+			count(*) FILTER (WHERE M_ID = $2)
+			FROM rentals WHERE RETURNED_AT IS NULL AND (C_ID = $1 OR M_ID = $2)`,
+			customerID, movieID).Scan(&open, &rented)
+		if err != nil {
+			return err
+		}
+		if err := admit(open, s.opts.MaxRentals, rented, copies); err != nil {
+			return err
+		}
+
+		rt = Rental{CustomerID: customerID, MovieID: movieID}
+		return tx.QueryRowContext(ctx, "INSERT INTO rentals (C_ID, M_ID) VALUES ($1, $2) RETURNING ID, RENTED_AT",
+			customerID, movieID).Scan(&rt.ID, &rt.RentedAt)
+	})
+	return rt, err
+}
+
+// Return checks a rented movie back in
+func (s *Service) Return(ctx context.Context, rentalID int64) (Rental, error) {
+	var rt Rental
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		var returned sql.NullTime
+		err := tx.QueryRowContext(ctx, "SELECT ID, C_ID, M_ID, RENTED_AT, RETURNED_AT FROM rentals WHERE ID = $1 FOR UPDATE", rentalID).
+			Scan(&rt.ID, &rt.CustomerID, &rt.MovieID, &rt.RentedAt, &returned)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %d", ErrNoRental, rentalID)
+		} else if err != nil {
+			return err
+		}
+		if returned.Valid {
+			return fmt.Errorf("%w: %d on %s", ErrAlreadyReturned, rentalID, returned.Time.Format(time.DateTime))
+		}
+
+		var at time.Time
+		err = tx.QueryRowContext(ctx, "UPDATE rentals SET RETURNED_AT = now() WHERE ID = $1 RETURNING RETURNED_AT", rentalID).Scan(&at)
+		rt.ReturnedAt = &at
+		return err
+	})
+	return rt, err
+}
+
+// inTx runs fn in a transaction of the configured isolation level, again from the start when
+// Postgres aborts it in favour of a concurrent one. fn must not have effects outside of tx.
+func (s *Service) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return retry(ctx, s.opts.Attempts, s.opts.Backoff, func() error {
+		tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: s.opts.Isolation})
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+		// Serializable transactions may fail here too, when the conflict is found at commit
+		return tx.Commit()
+	})
+}
+
+func retry(ctx context.Context, attempts int, backoff time.Duration, fn func() error) error {
+	var err error
+	for i := range attempts {
+		if err = fn(); !retryable(err) {
+			return err
+		}
+		if i == attempts-1 {
+			break
+		}
+
+		// Jitter keeps the transactions that collided from colliding again
+		wait := backoff<<i/2 + rand.N(backoff<<i/2+1)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+}
+
+func retryable(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && (pqErr.Code == serializationFailure || pqErr.Code == deadlockDetected)
+}
@@ -0,0 +1,81 @@
+package rental
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestAdmit(t *testing.T) {
+	tests := []struct {
+		name                        string
+		open, limit, rented, copies int
+		want                        error
+	}{
+		{"free", 0, 3, 0, 1, nil},
+		{"no limit", 10, 0, 0, 1, nil},
+		{"limit reached", 3, 3, 0, 1, ErrLimitReached},
+		{"last copy", 0, 3, 2, 3, nil},
+		{"no copy left", 0, 3, 3, 3, ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := admit(tt.open, tt.limit, tt.rented, tt.copies); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	conflict := &pq.Error{Code: serializationFailure}
+	other := errors.New("boom")
+
+	tests := []struct {
+		name     string
+		errs     []error // returned by the runs in order
+		attempts int
+		wantRuns int
+		want     error
+	}{
+		{"success", []error{nil}, 3, 1, nil},
+		{"retried until success", []error{conflict, &pq.Error{Code: deadlockDetected}, nil}, 3, 3, nil},
+		{"other errors aren't retried", []error{other}, 3, 1, other},
+		{"gives up", []error{conflict, conflict, conflict}, 3, 3, conflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs := 0
+			err := retry(context.Background(), tt.attempts, time.Millisecond, func() error {
+				runs++
+				return tt.errs[runs-1]
+			})
+
+			if runs != tt.wantRuns {
+				t.Errorf("got %d runs, want %d", runs, tt.wantRuns)
+			}
+			if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	runs := 0
+	err := retry(ctx, 5, time.Hour, func() error {
+		runs++
+		cancel()
+		return &pq.Error{Code: serializationFailure}
+	})
+
+	if runs != 1 || !errors.Is(err, context.Canceled) {
+		t.Errorf("got %d runs and %v, want 1 run and %v", runs, err, context.Canceled)
+	}
+}
@@ -1,5 +1,20 @@
 package main
 
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"go/go-examples/133-postgres/10-manage-users/provision"
+	"log"
+	"os"
+	"os/signal"
+
+	_ "github.com/lib/pq"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
 // NOTE: users & privileges
 /*
 	see current user
@@ -67,6 +82,65 @@ package main
 	Security Risk			Limited to the specified object.					High (unrestricted access).
 */
 
+// NOTE: Provisioning
+/*
+	The roles of a team are better kept in a file than typed by hand. roles.example.yaml lists the
+	users of these notes and of 134-mongodb/10-managin-users, `plan` prints what differs from the
+	databases and `apply` runs it. Running apply twice changes nothing the second time.
+*/
+
+const usage = `usage:
+  manage-users [flags] plan
+  manage-users [flags] apply`
+
 func main() {
+	file := flag.String("f", "roles.yaml", "the roles file")
+	dsn := flag.String("postgres", os.Getenv("DATABASE_URL"), "postgres connection string, for the postgres section")
+	uri := flag.String("mongo", os.Getenv("MONGO_URI"), "mongodb connection string, for the mongo section")
+	flag.Usage = func() { fmt.Fprintln(flag.CommandLine.Output(), usage); flag.PrintDefaults() }
+	flag.Parse()
+
+	cmd := flag.Arg(0)
+	if flag.NArg() != 1 || (cmd != "plan" && cmd != "apply") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	spec, err := provision.Load(*file)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var p provision.Provisioner
+	if spec.Postgres != nil && *dsn != "" {
+		p.Postgres, err = sql.Open("postgres", *dsn)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer p.Postgres.Close()
+	}
+	if spec.Mongo != nil && *uri != "" {
+		p.Mongo, err = mongo.Connect(ctx, options.Client().ApplyURI(*uri))
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer p.Mongo.Disconnect(context.Background())
+	}
 
+	changes, err := p.Plan(ctx, spec)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err := provision.Print(os.Stdout, changes); err != nil {
+		log.Fatalln(err)
+	}
+	if cmd == "apply" && len(changes) > 0 {
+		if err := p.Apply(ctx, changes); err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Printf("applied %d changes\n", len(changes))
+	}
 }
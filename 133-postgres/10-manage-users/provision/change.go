@@ -0,0 +1,61 @@
+package provision
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type Action string
+
+const (
+	Create Action = "+"
+	Update Action = "~"
+	Remove Action = "-"
+)
+
+// Change is one statement of a plan. Display is what plan prints, it never holds a password.
+type Change struct {
+	Engine  string // postgres or mongo
+	Action  Action
+	Target  string // e.g. role app or user myDatabase.appUser
+	Display string
+
+	stmt string // the SQL of a postgres change
+	db   string // the database a mongo command runs in
+	cmd  bson.D
+}
+
+// Print writes the plan, one line per change
+func Print(w io.Writer, changes []Change) error {
+	if len(changes) == 0 {
+		_, err := fmt.Fprintln(w, "no changes, the databases match the roles file")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, c := range changes {
+		fmt.Fprintf(tw, "%s\t%s %s\t%s\n", c.Engine, c.Action, c.Target, c.Display)
+	}
+	return tw.Flush()
+}
+
+// diffSets returns what to add and remove to go from have to want, sorted
+func diffSets(want, have []string) (add, remove []string) {
+	for _, w := range want {
+		if !slices.Contains(have, w) {
+			add = append(add, w)
+		}
+	}
+	for _, h := range have {
+		if !slices.Contains(want, h) {
+			remove = append(remove, h)
+		}
+	}
+	slices.Sort(add)
+	slices.Sort(remove)
+	return add, remove
+}
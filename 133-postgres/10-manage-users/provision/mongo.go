@@ -0,0 +1,130 @@
+package provision
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// mongoState is the roles of the existing users of the file, keyed by db.name
+type mongoState map[string][]MongoRole
+
+func (r MongoRole) String() string { return r.Role + "@" + r.DB }
+
+// diffMongo is the changes turning state into spec. Users missing from the file are left alone.
+func diffMongo(spec *MongoSpec, state mongoState, password func(env string) (string, error)) ([]Change, error) {
+	var changes []Change
+	add := func(action Action, u MongoUser, display string, cmd bson.D) {
+		changes = append(changes, Change{
+			Engine: "mongo", Action: action, Target: "user " + u.DB + "." + u.Name,
+			Display: display, db: u.DB, cmd: cmd,
+		})
+	}
+
+	for _, u := range spec.Users {
+		have, exists := state[u.DB+"."+u.Name]
+
+		if u.Absent {
+			if exists {
+				add(Remove, u, "dropUser "+u.Name, bson.D{{Key: "dropUser", Value: u.Name}})
+			}
+			continue
+		}
+
+		if !exists {
+			pw, err := password(u.PasswordEnv)
+			if err != nil {
+				return nil, fmt.Errorf("user %s.%s: %w", u.DB, u.Name, err)
+			}
+			if pw == "" {
+				return nil, fmt.Errorf("user %s.%s: a new user needs password_env", u.DB, u.Name)
+			}
+			roles := u.Roles
+			if roles == nil {
+				roles = []MongoRole{} // createUser wants the field even when empty
+			}
+			add(Create, u, fmt.Sprintf("createUser %s roles [%s]", u.Name, joinRoles(roles)), bson.D{
+				{Key: "createUser", Value: u.Name},
+				{Key: "pwd", Value: pw},
+				{Key: "roles", Value: roles},
+			})
+			continue
+		}
+
+		grant, revoke := diffSets(roleKeys(u.Roles), roleKeys(have))
+		if len(grant) > 0 {
+			roles := parseRoles(grant)
+			add(Update, u, fmt.Sprintf("grantRolesToUser %s [%s]", u.Name, joinRoles(roles)), bson.D{
+				{Key: "grantRolesToUser", Value: u.Name},
+				{Key: "roles", Value: roles},
+			})
+		}
+		if len(revoke) > 0 {
+			roles := parseRoles(revoke)
+			add(Update, u, fmt.Sprintf("revokeRolesFromUser %s [%s]", u.Name, joinRoles(roles)), bson.D{
+				{Key: "revokeRolesFromUser", Value: u.Name},
+				{Key: "roles", Value: roles},
+			})
+		}
+	}
+	return changes, nil
+}
+
+func roleKeys(roles []MongoRole) []string {
+	keys := make([]string, len(roles))
+	for i, r := range roles {
+		keys[i] = r.String()
+	}
+	return slices.Compact(slices.Sorted(slices.Values(keys)))
+}
+
+func parseRoles(keys []string) []MongoRole {
+	roles := make([]MongoRole, len(keys))
+	for i, k := range keys {
+		role, db, _ := strings.Cut(k, "@")
+		roles[i] = MongoRole{Role: role, DB: db}
+	}
+	return roles
+}
+
+func joinRoles(roles []MongoRole) string {
+	s := make([]string, len(roles))
+	for i, r := range roles {
+		s[i] = r.String()
+	}
+	return strings.Join(s, " ")
+}
+
+func readMongo(ctx context.Context, client *mongo.Client, spec *MongoSpec) (mongoState, error) {
+	state := mongoState{}
+	for _, u := range spec.Users {
+		var res struct {
+			Users []struct {
+				Roles []MongoRole `bson:"roles"`
+			} `bson:"users"`
+		}
+		cmd := bson.D{{Key: "usersInfo", Value: bson.D{{Key: "user", Value: u.Name}, {Key: "db", Value: u.DB}}}}
+		if err := client.Database(u.DB).RunCommand(ctx, cmd).Decode(&res); err != nil {
+			return nil, fmt.Errorf("usersInfo %s.%s: %w", u.DB, u.Name, err)
+		}
+		if len(res.Users) > 0 {
+			state[u.DB+"."+u.Name] = res.Users[0].Roles
+		}
+	}
+	return state, nil
+}
+
+// applyMongo runs the commands one by one, MongoDB has no transactions for user management
+// so a failure leaves the changes before it applied. Running apply again finishes the job.
+func applyMongo(ctx context.Context, client *mongo.Client, changes []Change) error {
+	for _, c := range changes {
+		if err := client.Database(c.db).RunCommand(ctx, c.cmd).Err(); err != nil {
+			return fmt.Errorf("%s %s: %w", c.Target, c.Display, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+package provision
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+type pgAttrs struct {
+	Login, Superuser, CreateDB, CreateRole bool
+}
+
+func (a pgAttrs) String() string {
+	flag := func(on bool, name string) string {
+		if on {
+			return name
+		}
+		return "NO" + name
+	}
+	return strings.Join([]string{
+		flag(a.Login, "LOGIN"), flag(a.Superuser, "SUPERUSER"),
+		flag(a.CreateDB, "CREATEDB"), flag(a.CreateRole, "CREATEROLE"),
+	}, " ")
+}
+
+// pgObject is a database, a schema or a table (in schema)
+type pgObject struct {
+	Kind   string // DATABASE, SCHEMA or TABLE
+	Schema string
+	Name   string
+}
+
+func (o pgObject) String() string {
+	if o.Kind == "TABLE" {
+		return "TABLE " + pq.QuoteIdentifier(o.Schema) + "." + pq.QuoteIdentifier(o.Name)
+	}
+	return o.Kind + " " + pq.QuoteIdentifier(o.Name)
+}
+
+// pgState is what the database has for the roles of the file
+type pgState struct {
+	roles  map[string]pgAttrs
+	grants map[string]map[pgObject][]string // role -> object -> privileges, sorted
+	tables map[string][]string              // schema -> tables and views
+}
+
+// diffPostgres is the changes turning state into spec. Roles missing from the file are left alone.
+func diffPostgres(spec *PostgresSpec, state pgState, password func(env string) (string, error)) ([]Change, error) {
+	var changes []Change
+	add := func(action Action, role, display, stmt string) {
+		changes = append(changes, Change{Engine: "postgres", Action: action, Target: "role " + role, Display: display, stmt: stmt})
+	}
+
+	for _, r := range spec.Roles {
+		role := pq.QuoteIdentifier(r.Name)
+		have, exists := state.roles[r.Name]
+		current := state.grants[r.Name]
+
+		if r.Absent {
+			if !exists {
+				continue
+			}
+			for _, obj := range sortedObjects(current) {
+				stmt := fmt.Sprintf("REVOKE ALL ON %s FROM %s", obj, role)
+				add(Remove, r.Name, stmt, stmt)
+			}
+			stmt := "DROP ROLE " + role
+			add(Remove, r.Name, stmt, stmt)
+			continue
+		}
+
+		want := pgAttrs{Login: r.Login, Superuser: r.Superuser, CreateDB: r.CreateDB, CreateRole: r.CreateRole}
+		switch {
+		case !exists:
+			pw, err := password(r.PasswordEnv)
+			if err != nil {
+				return nil, fmt.Errorf("role %s: %w", r.Name, err)
+			}
+			stmt := fmt.Sprintf("CREATE ROLE %s WITH %s", role, want)
+			display := stmt
+			if pw != "" {
+				stmt += " PASSWORD " + pq.QuoteLiteral(pw)
+				display += " PASSWORD '******'"
+			}
+			add(Create, r.Name, display, stmt)
+		case have != want:
+			stmt := fmt.Sprintf("ALTER ROLE %s WITH %s", role, want)
+			add(Update, r.Name, stmt, stmt)
+		}
+
+		desired := map[pgObject][]string{}
+		for name, privs := range r.Databases {
+			desired[pgObject{Kind: "DATABASE", Name: name}] = privs
+		}
+		for name, privs := range r.Schemas {
+			desired[pgObject{Kind: "SCHEMA", Name: name}] = privs
+		}
+		for schema, privs := range r.Tables {
+			for _, table := range state.tables[schema] {
+				desired[pgObject{Kind: "TABLE", Schema: schema, Name: table}] = privs
+			}
+		}
+
+		objects := sortedObjects(desired)
+		for _, obj := range sortedObjects(current) {
+			if _, ok := desired[obj]; !ok {
+				objects = append(objects, obj)
+			}
+		}
+		for _, obj := range objects {
+			grant, revoke := diffSets(desired[obj], current[obj])
+			if len(grant) > 0 {
+				stmt := fmt.Sprintf("GRANT %s ON %s TO %s", strings.Join(grant, ", "), obj, role)
+				add(Update, r.Name, stmt, stmt)
+			}
+			if len(revoke) > 0 {
+				stmt := fmt.Sprintf("REVOKE %s ON %s FROM %s", strings.Join(revoke, ", "), obj, role)
+				add(Update, r.Name, stmt, stmt)
+			}
+		}
+	}
+	return changes, nil
+}
+
+func sortedObjects(m map[pgObject][]string) []pgObject {
+	objs := make([]pgObject, 0, len(m))
+	for o := range m {
+		objs = append(objs, o)
+	}
+	slices.SortFunc(objs, func(a, b pgObject) int {
+		return strings.Compare(a.Kind+"\x00"+a.Schema+"\x00"+a.Name, b.Kind+"\x00"+b.Schema+"\x00"+b.Name)
+	})
+	return objs
+}
+
+// The grants of the roles on databases, schemas and tables. Owners hold their privileges
+// implicitly, they aren't counted as grants. aclexplode is empty for an object that still
+// has its default privileges.
+const (
+	pgRolesQuery = `SELECT rolname, rolcanlogin, rolsuper, rolcreatedb, rolcreaterole
+		FROM pg_roles WHERE rolname = ANY($1)`
+
+	pgDatabaseGrantsQuery = `SELECT r.rolname, '' AS schema, d.datname, a.privilege_type
+		FROM pg_database d CROSS JOIN LATERAL aclexplode(d.datacl) a
+		JOIN pg_roles r ON r.oid = a.grantee
+		WHERE r.rolname = ANY($1) AND a.grantee <> d.datdba`
+
+	pgSchemaGrantsQuery = `SELECT r.rolname, '' AS schema, n.nspname, a.privilege_type
+		FROM pg_namespace n CROSS JOIN LATERAL aclexplode(n.nspacl) a
+		JOIN pg_roles r ON r.oid = a.grantee
+		WHERE r.rolname = ANY($1) AND a.grantee <> n.nspowner`
+
+	pgTableGrantsQuery = `SELECT r.rolname, n.nspname, c.relname, a.privilege_type
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		CROSS JOIN LATERAL aclexplode(c.relacl) a
+		JOIN pg_roles r ON r.oid = a.grantee
+		WHERE c.relkind IN ('r', 'p', 'v', 'm', 'f') AND r.rolname = ANY($1) AND a.grantee <> c.relowner`
+
+	pgTablesQuery = `SELECT n.nspname, c.relname
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p', 'v', 'm', 'f') AND n.nspname = ANY($1)
+		ORDER BY 1, 2`
+)
+
+func readPostgres(ctx context.Context, db *sql.DB, spec *PostgresSpec) (pgState, error) {
+	state := pgState{roles: map[string]pgAttrs{}, grants: map[string]map[pgObject][]string{}, tables: map[string][]string{}}
+
+	var names, schemas []string
+	for _, r := range spec.Roles {
+		names = append(names, r.Name)
+		for schema := range r.Tables {
+			schemas = append(schemas, schema)
+		}
+	}
+
+	rows, err := db.QueryContext(ctx, pgRolesQuery, pq.Array(names))
+	if err != nil {
+		return state, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var a pgAttrs
+		if err := rows.Scan(&name, &a.Login, &a.Superuser, &a.CreateDB, &a.CreateRole); err != nil {
+			return state, err
+		}
+		state.roles[name] = a
+	}
+	if err := rows.Err(); err != nil {
+		return state, err
+	}
+
+	grants := []struct {
+		kind    string
+		query   string
+		allowed []string
+	}{
+		{"DATABASE", pgDatabaseGrantsQuery, databasePrivileges},
+		{"SCHEMA", pgSchemaGrantsQuery, schemaPrivileges},
+		{"TABLE", pgTableGrantsQuery, tablePrivileges},
+	}
+	for _, g := range grants {
+		if err := readGrants(ctx, db, g.query, g.kind, g.allowed, names, state.grants); err != nil {
+			return state, err
+		}
+	}
+
+	rows, err = db.QueryContext(ctx, pgTablesQuery, pq.Array(schemas))
+	if err != nil {
+		return state, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var schema, table string
+		if err := rows.Scan(&schema, &table); err != nil {
+			return state, err
+		}
+		state.tables[schema] = append(state.tables[schema], table)
+	}
+	return state, rows.Err()
+}
+
+// readGrants adds the privileges of a query to grants. Privileges this tool doesn't manage,
+// like MAINTAIN of Postgres 17, are left out so they are never revoked.
+func readGrants(ctx context.Context, db *sql.DB, query, kind string, allowed, names []string, grants map[string]map[pgObject][]string) error {
+	rows, err := db.QueryContext(ctx, query, pq.Array(names))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var role, priv string
+		obj := pgObject{Kind: kind}
+		if err := rows.Scan(&role, &obj.Schema, &obj.Name, &priv); err != nil {
+			return err
+		}
+		if !slices.Contains(allowed, priv) {
+			continue
+		}
+		if grants[role] == nil {
+			grants[role] = map[pgObject][]string{}
+		}
+		grants[role][obj] = append(grants[role][obj], priv)
+	}
+	for _, objs := range grants {
+		for obj := range objs {
+			slices.Sort(objs[obj])
+		}
+	}
+	return rows.Err()
+}
+
+// applyPostgres runs the changes in one transaction, roles and grants are transactional
+// in Postgres so a failing statement leaves everything as it was
+func applyPostgres(ctx context.Context, db *sql.DB, changes []Change) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, c := range changes {
+		if _, err := tx.ExecContext(ctx, c.stmt); err != nil {
+			return fmt.Errorf("%s: %w", c.Display, err)
+		}
+	}
+	return tx.Commit()
+}
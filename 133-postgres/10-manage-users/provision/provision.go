@@ -0,0 +1,82 @@
+package provision
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var ErrNotConnected = errors.New("the roles file has a section for a database that isn't connected")
+
+// Provisioner holds the connections, either may be nil when the file has no section for it
+type Provisioner struct {
+	Postgres *sql.DB
+	Mongo    *mongo.Client
+}
+
+// Plan reads the current roles and users and returns the changes to match spec, Postgres first.
+// Running it twice gives the same plan, applying it makes the next plan empty.
+func (p Provisioner) Plan(ctx context.Context, spec *Spec) ([]Change, error) {
+	var changes []Change
+
+	if spec.Postgres != nil {
+		if p.Postgres == nil {
+			return nil, ErrNotConnected
+		}
+		state, err := readPostgres(ctx, p.Postgres, spec.Postgres)
+		if err != nil {
+			return nil, err
+		}
+		pg, err := diffPostgres(spec.Postgres, state, password)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, pg...)
+	}
+
+	if spec.Mongo != nil {
+		if p.Mongo == nil {
+			return nil, ErrNotConnected
+		}
+		state, err := readMongo(ctx, p.Mongo, spec.Mongo)
+		if err != nil {
+			return nil, err
+		}
+		mg, err := diffMongo(spec.Mongo, state, password)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, mg...)
+	}
+	return changes, nil
+}
+
+// Apply runs a plan
+func (p Provisioner) Apply(ctx context.Context, changes []Change) error {
+	var pg, mg []Change
+	for _, c := range changes {
+		if c.Engine == "postgres" {
+			pg = append(pg, c)
+		} else {
+			mg = append(mg, c)
+		}
+	}
+
+	if len(pg) > 0 {
+		if p.Postgres == nil {
+			return ErrNotConnected
+		}
+		if err := applyPostgres(ctx, p.Postgres, pg); err != nil {
+			return err
+		}
+	}
+	if len(mg) > 0 {
+		if p.Mongo == nil {
+			return ErrNotConnected
+		}
+		return applyMongo(ctx, p.Mongo, mg)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package provision
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeSpec(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "roles.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func noPassword(env string) (string, error) {
+	if env == "" {
+		return "", nil
+	}
+	return "secret", nil
+}
+
+func TestLoad(t *testing.T) {
+	spec, err := Load(writeSpec(t, `
+postgres:
+  roles:
+    - name: james
+      login: true
+      databases: {company: [all]}
+      schemas: {public: [usage]}
+      tables: {public: [select, Insert, select]}
+mongo:
+  users:
+    - name: appUser
+      db: myDatabase
+      roles: [{role: readWrite, db: myDatabase}]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := spec.Postgres.Roles[0]
+	if got := r.Databases["company"]; !slices.Equal(got, databasePrivileges) {
+		t.Errorf("ALL expanded to %v", got)
+	}
+	if got := r.Tables["public"]; !slices.Equal(got, []string{"INSERT", "SELECT"}) {
+		t.Errorf("table privileges = %v", got)
+	}
+	if u := spec.Mongo.Users[0]; u.Roles[0] != (MongoRole{Role: "readWrite", DB: "myDatabase"}) {
+		t.Errorf("mongo user = %+v", u)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	_, err := Load(writeSpec(t, `
+postgres:
+  roles:
+    - name: james
+      databases: {company: [SELECT]}
+    - name: james
+    - name: "bad name"
+mongo:
+  users:
+    - name: appUser
+`))
+	if !errors.Is(err, ErrInvalidSpec) {
+		t.Fatalf("err = %v, want ErrInvalidSpec", err)
+	}
+	for _, want := range []string{"unknown privilege SELECT", "listed twice", "invalid name", "needs a valid name and a db"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error doesn't report %q:\n%v", want, err)
+		}
+	}
+
+	if _, err := Load(writeSpec(t, "postgres:\n  roles:\n    - name: james\n      logn: true\n")); !errors.Is(err, ErrInvalidSpec) {
+		t.Errorf("unknown key: err = %v, want ErrInvalidSpec", err)
+	}
+}
+
+func displays(changes []Change) []string {
+	var out []string
+	for _, c := range changes {
+		out = append(out, string(c.Action)+" "+c.Display)
+	}
+	return out
+}
+
+func TestDiffPostgres(t *testing.T) {
+	spec := &PostgresSpec{Roles: []PGRole{
+		{Name: "app", Login: true, PasswordEnv: "APP_PASSWORD",
+			Databases: map[string][]string{"company": {"CONNECT"}},
+			Tables:    map[string][]string{"public": {"SELECT"}}},
+		{Name: "james", Login: true,
+			Databases: map[string][]string{"company": {"CONNECT", "CREATE"}}},
+		{Name: "old", Absent: true},
+		{Name: "gone", Absent: true},
+	}}
+	state := pgState{
+		roles: map[string]pgAttrs{
+			"james": {Login: true, Superuser: true},
+			"old":   {},
+		},
+		grants: map[string]map[pgObject][]string{
+			"james": {
+				{Kind: "DATABASE", Name: "company"}: {"CONNECT", "TEMPORARY"},
+				{Kind: "SCHEMA", Name: "public"}:    {"USAGE"},
+			},
+			"old": {{Kind: "DATABASE", Name: "company"}: {"CONNECT"}},
+		},
+		tables: map[string][]string{"public": {"customers", "movies"}},
+	}
+
+	changes, err := diffPostgres(spec, state, noPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`+ CREATE ROLE "app" WITH LOGIN NOSUPERUSER NOCREATEDB NOCREATEROLE PASSWORD '******'`,
+		`~ GRANT CONNECT ON DATABASE "company" TO "app"`,
+		`~ GRANT SELECT ON TABLE "public"."customers" TO "app"`,
+		`~ GRANT SELECT ON TABLE "public"."movies" TO "app"`,
+		`~ ALTER ROLE "james" WITH LOGIN NOSUPERUSER NOCREATEDB NOCREATEROLE`,
+		`~ GRANT CREATE ON DATABASE "company" TO "james"`,
+		`~ REVOKE TEMPORARY ON DATABASE "company" FROM "james"`,
+		`~ REVOKE USAGE ON SCHEMA "public" FROM "james"`,
+		`- REVOKE ALL ON DATABASE "company" FROM "old"`,
+		`- DROP ROLE "old"`,
+	}
+	if got := displays(changes); !slices.Equal(got, want) {
+		t.Errorf("plan:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if stmt := changes[0].stmt; !strings.HasSuffix(stmt, "PASSWORD 'secret'") {
+		t.Errorf("create statement = %s", stmt)
+	}
+
+	// once applied there is nothing left to do
+	state.roles["app"] = pgAttrs{Login: true}
+	state.roles["james"] = pgAttrs{Login: true}
+	delete(state.roles, "old")
+	state.grants = map[string]map[pgObject][]string{
+		"app": {
+			{Kind: "DATABASE", Name: "company"}:                  {"CONNECT"},
+			{Kind: "TABLE", Schema: "public", Name: "customers"}: {"SELECT"},
+			{Kind: "TABLE", Schema: "public", Name: "movies"}:    {"SELECT"},
+		},
+		"james": {{Kind: "DATABASE", Name: "company"}: {"CONNECT", "CREATE"}},
+	}
+	if changes, err := diffPostgres(spec, state, noPassword); err != nil || len(changes) != 0 {
+		t.Errorf("second plan = %v, %v, want no changes", displays(changes), err)
+	}
+}
+
+func TestDiffMongo(t *testing.T) {
+	spec := &MongoSpec{Users: []MongoUser{
+		{Name: "appUser", DB: "myDatabase", PasswordEnv: "APP_PASSWORD",
+			Roles: []MongoRole{{Role: "readWrite", DB: "myDatabase"}}},
+		{Name: "reporter", DB: "admin",
+			Roles: []MongoRole{{Role: "read", DB: "myDatabase"}, {Role: "read", DB: "logs"}}},
+		{Name: "readOnlyUser", DB: "myDatabase", Absent: true},
+	}}
+	state := mongoState{
+		"admin.reporter":          {{Role: "read", DB: "myDatabase"}, {Role: "dbAdmin", DB: "myDatabase"}},
+		"myDatabase.readOnlyUser": {{Role: "read", DB: "myDatabase"}},
+	}
+
+	changes, err := diffMongo(spec, state, noPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"+ createUser appUser roles [readWrite@myDatabase]",
+		"~ grantRolesToUser reporter [read@logs]",
+		"~ revokeRolesFromUser reporter [dbAdmin@myDatabase]",
+		"- dropUser readOnlyUser",
+	}
+	if got := displays(changes); !slices.Equal(got, want) {
+		t.Errorf("plan:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if c := changes[0]; c.db != "myDatabase" || c.cmd[1].Value != "secret" {
+		t.Errorf("createUser runs in %s with %v", c.db, c.cmd)
+	}
+
+	// a new user without a password can't be created
+	spec.Users[0].PasswordEnv = ""
+	if _, err := diffMongo(spec, state, noPassword); err == nil {
+		t.Error("created a user without a password")
+	}
+}
+
+func TestPrint(t *testing.T) {
+	var b strings.Builder
+	if err := Print(&b, nil); err != nil || !strings.HasPrefix(b.String(), "no changes") {
+		t.Errorf("empty plan printed %q, %v", b.String(), err)
+	}
+}
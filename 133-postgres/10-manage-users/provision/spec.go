@@ -0,0 +1,191 @@
+// Package provision makes the Postgres roles and MongoDB users of a declarative file exist
+// with exactly the privileges it lists. Plan computes the changes, Apply runs them.
+package provision
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is the roles file. Passwords aren't written in it, only the name of the environment
+// variable holding each of them.
+type Spec struct {
+	Postgres *PostgresSpec `yaml:"postgres"`
+	Mongo    *MongoSpec    `yaml:"mongo"`
+}
+
+type PostgresSpec struct {
+	Roles []PGRole `yaml:"roles"`
+}
+
+// PGRole is a role with its privileges. Privileges the role has and the file doesn't list are
+// revoked. Schema and table privileges are read in the database the tool connects to.
+type PGRole struct {
+	Name        string `yaml:"name"`
+	Absent      bool   `yaml:"absent"` // the role is revoked everything and dropped
+	Login       bool   `yaml:"login"`
+	Superuser   bool   `yaml:"superuser"`
+	CreateDB    bool   `yaml:"createdb"`
+	CreateRole  bool   `yaml:"createrole"`
+	PasswordEnv string `yaml:"password_env"` // only used when the role is created
+
+	Databases map[string][]string `yaml:"databases"` // database -> CONNECT, CREATE, TEMPORARY or ALL
+	Schemas   map[string][]string `yaml:"schemas"`   // schema -> USAGE, CREATE or ALL
+	Tables    map[string][]string `yaml:"tables"`    // schema -> privileges on every table of the schema
+}
+
+type MongoSpec struct {
+	Users []MongoUser `yaml:"users"`
+}
+
+// MongoUser is a user of its authentication database, roles it has and the file doesn't list are revoked
+type MongoUser struct {
+	Name        string      `yaml:"name"`
+	DB          string      `yaml:"db"`
+	Absent      bool        `yaml:"absent"`
+	PasswordEnv string      `yaml:"password_env"` // only used when the user is created
+	Roles       []MongoRole `yaml:"roles"`
+}
+
+type MongoRole struct {
+	Role string `yaml:"role" bson:"role"`
+	DB   string `yaml:"db" bson:"db"`
+}
+
+var ErrInvalidSpec = errors.New("invalid roles file")
+
+// Privileges per object kind, ALL is expanded to them
+var (
+	databasePrivileges = []string{"CONNECT", "CREATE", "TEMPORARY"}
+	schemaPrivileges   = []string{"CREATE", "USAGE"}
+	tablePrivileges    = []string{"DELETE", "INSERT", "REFERENCES", "SELECT", "TRIGGER", "TRUNCATE", "UPDATE"}
+)
+
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// Load reads and validates a roles file
+func Load(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := &Spec{}
+	dec := yaml.NewDecoder(strings.NewReader(string(data)))
+	dec.KnownFields(true) // a misspelt key would otherwise drop a privilege silently
+	if err := dec.Decode(spec); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSpec, err)
+	}
+	if err := spec.normalize(); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// normalize checks the names and privileges, upper cases the privileges and expands ALL.
+// Every problem is reported at once.
+func (s *Spec) normalize() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if s.Postgres != nil {
+		seen := map[string]bool{}
+		for i := range s.Postgres.Roles {
+			r := &s.Postgres.Roles[i]
+			if !namePattern.MatchString(r.Name) {
+				fail("postgres role %q: invalid name", r.Name)
+			}
+			if seen[r.Name] {
+				fail("postgres role %q: listed twice", r.Name)
+			}
+			seen[r.Name] = true
+
+			for _, m := range []struct {
+				kind    string
+				grants  map[string][]string
+				allowed []string
+			}{
+				{"database", r.Databases, databasePrivileges},
+				{"schema", r.Schemas, schemaPrivileges},
+				{"tables of schema", r.Tables, tablePrivileges},
+			} {
+				for obj, privs := range m.grants {
+					norm, err := normalizePrivileges(privs, m.allowed)
+					if err != nil {
+						fail("postgres role %q, %s %q: %v", r.Name, m.kind, obj, err)
+					}
+					m.grants[obj] = norm
+				}
+			}
+			if r.Absent && (len(r.Databases) > 0 || len(r.Schemas) > 0 || len(r.Tables) > 0) {
+				fail("postgres role %q: absent but with privileges", r.Name)
+			}
+		}
+	}
+
+	if s.Mongo != nil {
+		seen := map[string]bool{}
+		for _, u := range s.Mongo.Users {
+			if !namePattern.MatchString(u.Name) || u.DB == "" {
+				fail("mongo user %q: needs a valid name and a db", u.Name)
+			}
+			if seen[u.DB+"."+u.Name] {
+				fail("mongo user %s.%s: listed twice", u.DB, u.Name)
+			}
+			seen[u.DB+"."+u.Name] = true
+			for _, role := range u.Roles {
+				if role.Role == "" || role.DB == "" {
+					fail("mongo user %s.%s: every role needs a role and a db", u.DB, u.Name)
+				}
+			}
+			if u.Absent && len(u.Roles) > 0 {
+				fail("mongo user %s.%s: absent but with roles", u.DB, u.Name)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w:\n%w", ErrInvalidSpec, errors.Join(errs...))
+	}
+	return nil
+}
+
+func normalizePrivileges(privs, allowed []string) ([]string, error) {
+	out := []string{}
+	for _, p := range privs {
+		p = strings.ToUpper(strings.TrimSpace(p))
+		if p == "TEMP" {
+			p = "TEMPORARY"
+		}
+		switch {
+		case p == "ALL" || p == "ALL PRIVILEGES":
+			out = append(out, allowed...)
+		case slices.Contains(allowed, p):
+			out = append(out, p)
+		default:
+			return nil, fmt.Errorf("unknown privilege %s, want one of %s or ALL", p, strings.Join(allowed, ", "))
+		}
+	}
+	slices.Sort(out)
+	return slices.Compact(out), nil
+}
+
+// password reads the password of a new role or user
+func password(env string) (string, error) {
+	if env == "" {
+		return "", nil
+	}
+	v, ok := os.LookupEnv(env)
+	if !ok || v == "" {
+		return "", fmt.Errorf("password variable %s is not set", env)
+	}
+	return v, nil
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"go/go-examples/133-postgres/17-typed-queries/dbq"
 	"log"
 	"net/http"
 
@@ -66,26 +68,12 @@ func booksIndex(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rows, err := db.Query("SELECT * FROM books")
+	// * Columns are matched to the fields by name, the order doesn't matter (see 17-typed-queries)
+	books, err := dbq.QueryAll[Book](r.Context(), db, "SELECT * FROM books")
 	if err != nil {
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-
-	books := make([]Book, 0)
-	for rows.Next() {
-		book := Book{}
-		err := rows.Scan(&book.Isbn, &book.Title, &book.Author, &book.Price) // * Order matters
-		if err != nil {
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		}
-		books = append(books, book)
-	}
-
-	if err := rows.Err(); err != nil {
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-	}
 
 	for _, book := range books {
 		fmt.Fprintf(w, "%s, %s, %s, $%.2f\r\n", book.Isbn, book.Title, book.Author, book.Price)
@@ -105,12 +93,9 @@ func booksShow(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	row := db.QueryRow("SELECT * FROM books WHERE isbn = $1", isbn)
-
-	book := Book{}
-	err := row.Scan(&book.Isbn, &book.Title, &book.Author, &book.Price)
+	book, err := dbq.QueryOne[Book](r.Context(), db, "SELECT * FROM books WHERE isbn = :isbn", dbq.P{"isbn": isbn})
 	switch {
-	case err == sql.ErrNoRows:
+	case errors.Is(err, sql.ErrNoRows):
 		// http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
 		http.NotFound(w, r)
 		return
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	_ "github.com/lib/pq"
+	"go/go-examples/133-postgres/17-typed-queries/dbq"
 	"html/template"
 	"net/http"
 	"strconv"
@@ -58,27 +59,11 @@ func booksIndex(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rows, err := db.Query("SELECT * FROM books")
+	bks, err := dbq.QueryAll[Book](r.Context(), db, "SELECT * FROM books")
 	if err != nil {
 		http.Error(w, http.StatusText(500), 500)
 		return
 	}
-	defer rows.Close()
-
-	bks := make([]Book, 0)
-	for rows.Next() {
-		bk := Book{}
-		err := rows.Scan(&bk.Isbn, &bk.Title, &bk.Author, &bk.Price) // order matters
-		if err != nil {
-			http.Error(w, http.StatusText(500), 500)
-			return
-		}
-		bks = append(bks, bk)
-	}
-	if err = rows.Err(); err != nil {
-		http.Error(w, http.StatusText(500), 500)
-		return
-	}
 
 	tpl.ExecuteTemplate(w, "books.gohtml", bks)
 }
@@ -95,10 +80,7 @@ func booksShow(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	row := db.QueryRow("SELECT * FROM books WHERE isbn = $1", isbn)
-
-	bk := Book{}
-	err := row.Scan(&bk.Isbn, &bk.Title, &bk.Author, &bk.Price)
+	bk, err := dbq.QueryOne[Book](r.Context(), db, "SELECT * FROM books WHERE isbn = $1", isbn)
 	switch {
 	case err == sql.ErrNoRows:
 		http.NotFound(w, r)
@@ -165,10 +147,7 @@ func booksUpdateForm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	row := db.QueryRow("SELECT * FROM books WHERE isbn = $1", isbn)
-
-	bk := Book{}
-	err := row.Scan(&bk.Isbn, &bk.Title, &bk.Author, &bk.Price)
+	bk, err := dbq.QueryOne[Book](r.Context(), db, "SELECT * FROM books WHERE isbn = $1", isbn)
 	switch {
 	case err == sql.ErrNoRows:
 		http.NotFound(w, r)
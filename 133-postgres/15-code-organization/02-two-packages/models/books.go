@@ -1,9 +1,12 @@
 package models
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"strconv"
+
+	"go/go-examples/133-postgres/17-typed-queries/dbq"
 )
 
 type Book struct {
@@ -14,25 +17,7 @@ type Book struct {
 }
 
 func AllBooks() ([]Book, error) {
-	rows, err := db.Query("SELECT * FROM books")
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	bks := make([]Book, 0)
-	for rows.Next() {
-		bk := Book{}
-		err := rows.Scan(&bk.Isbn, &bk.Title, &bk.Author, &bk.Price) // order matters
-		if err != nil {
-			return nil, err
-		}
-		bks = append(bks, bk)
-	}
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
-	return bks, nil
+	return dbq.QueryAll[Book](context.Background(), db, "SELECT * FROM books")
 }
 
 func OneBook(r *http.Request) (Book, error) {
@@ -42,14 +27,7 @@ func OneBook(r *http.Request) (Book, error) {
 		return bk, errors.New("400. Bad Request.")
 	}
 
-	row := db.QueryRow("SELECT * FROM books WHERE isbn = $1", isbn)
-
-	err := row.Scan(&bk.Isbn, &bk.Title, &bk.Author, &bk.Price)
-	if err != nil {
-		return bk, err
-	}
-
-	return bk, nil
+	return dbq.QueryOne[Book](context.Background(), db, "SELECT * FROM books WHERE isbn = $1", isbn)
 }
 
 func PutBook(r *http.Request) (Book, error) {
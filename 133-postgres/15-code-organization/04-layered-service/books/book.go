@@ -0,0 +1,45 @@
+package books
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+type Book struct {
+	Isbn   string
+	Title  string
+	Author string
+	Price  float32
+
+	// NoPrice is set when the price was left out, a Price of 0 is then not a free book
+	NoPrice bool `db:"-"`
+}
+
+var (
+	ErrNotFound  = errors.New("book not found")
+	ErrDuplicate = errors.New("a book with this isbn already exists")
+)
+
+// Store is the persistence the service depends on. PostgresStore is the real one,
+// MemoryStore stands in for it in tests.
+type Store interface {
+	All(ctx context.Context) ([]Book, error)
+	One(ctx context.Context, isbn string) (Book, error)
+	Insert(ctx context.Context, bk Book) error
+	Update(ctx context.Context, bk Book) error
+	Delete(ctx context.Context, isbn string) error
+}
+
+// ValidationError lists every invalid field of a book, keyed by field name
+type ValidationError map[string]string
+
+func (v ValidationError) Error() string {
+	msgs := make([]string, 0, len(v))
+	for _, field := range []string{"isbn", "title", "author", "price"} {
+		if msg, ok := v[field]; ok {
+			msgs = append(msgs, field+" "+msg)
+		}
+	}
+	return "invalid book: " + strings.Join(msgs, ", ")
+}
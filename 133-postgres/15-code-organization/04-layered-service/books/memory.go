@@ -0,0 +1,77 @@
+package books
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// MemoryStore keeps books in a map, it stands in for Postgres in tests
+type MemoryStore struct {
+	mu    sync.Mutex
+	books map[string]Book
+}
+
+func NewMemoryStore(bks ...Book) *MemoryStore {
+	s := &MemoryStore{books: map[string]Book{}}
+	for _, bk := range bks {
+		s.books[bk.Isbn] = bk
+	}
+	return s
+}
+
+func (s *MemoryStore) All(_ context.Context) ([]Book, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bks := make([]Book, 0, len(s.books))
+	for _, bk := range s.books {
+		bks = append(bks, bk)
+	}
+	sort.Slice(bks, func(i, j int) bool { return bks[i].Isbn < bks[j].Isbn })
+	return bks, nil
+}
+
+func (s *MemoryStore) One(_ context.Context, isbn string) (Book, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bk, ok := s.books[isbn]
+	if !ok {
+		return Book{}, ErrNotFound
+	}
+	return bk, nil
+}
+
+func (s *MemoryStore) Insert(_ context.Context, bk Book) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.books[bk.Isbn]; ok {
+		return ErrDuplicate
+	}
+	s.books[bk.Isbn] = bk
+	return nil
+}
+
+func (s *MemoryStore) Update(_ context.Context, bk Book) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.books[bk.Isbn]; !ok {
+		return ErrNotFound
+	}
+	s.books[bk.Isbn] = bk
+	return nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, isbn string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.books[isbn]; !ok {
+		return ErrNotFound
+	}
+	delete(s.books, isbn)
+	return nil
+}
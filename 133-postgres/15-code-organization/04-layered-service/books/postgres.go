@@ -0,0 +1,109 @@
+package books
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go/go-examples/133-postgres/17-typed-queries/dbq"
+
+	"github.com/lib/pq"
+)
+
+// uniqueViolation is the Postgres error code of a duplicate primary key
+const uniqueViolation = "23505"
+
+// Handles runs each query on a database handle. A *replica.Router sends reads to replicas,
+// fn may then run a second time on the primary and must start over each time.
+type Handles interface {
+	Read(ctx context.Context, fn func(db *sql.DB) error) error
+	Write(ctx context.Context, fn func(db *sql.DB) error) error
+}
+
+// single runs every query on one database
+type single struct{ db *sql.DB }
+
+func (h single) Read(ctx context.Context, fn func(db *sql.DB) error) error  { return fn(h.db) }
+func (h single) Write(ctx context.Context, fn func(db *sql.DB) error) error { return fn(h.db) }
+
+type PostgresStore struct {
+	h Handles
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{h: single{db}}
+}
+
+// NewRoutedPostgresStore reads and writes through h, see replica.Router
+func NewRoutedPostgresStore(h Handles) *PostgresStore {
+	return &PostgresStore{h: h}
+}
+
+func (s *PostgresStore) All(ctx context.Context) ([]Book, error) {
+	var bks []Book
+	err := s.h.Read(ctx, func(db *sql.DB) error {
+		var err error
+		bks, err = dbq.QueryAll[Book](ctx, db, "SELECT isbn, title, author, price FROM books")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bks, nil
+}
+
+func (s *PostgresStore) One(ctx context.Context, isbn string) (Book, error) {
+	var bk Book
+	err := s.h.Read(ctx, func(db *sql.DB) error {
+		var err error
+		bk, err = dbq.QueryOne[Book](ctx, db, "SELECT isbn, title, author, price FROM books WHERE isbn = $1", isbn)
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return bk, ErrNotFound
+	}
+	return bk, err
+}
+
+func (s *PostgresStore) Insert(ctx context.Context, bk Book) error {
+	err := s.h.Write(ctx, func(db *sql.DB) error {
+		_, err := db.ExecContext(ctx, "INSERT INTO books (isbn, title, author, price) VALUES ($1, $2, $3, $4)", bk.Isbn, bk.Title, bk.Author, bk.Price)
+		return err
+	})
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (s *PostgresStore) Update(ctx context.Context, bk Book) error {
+	return s.exec(ctx, "UPDATE books SET title = $2, author = $3, price = $4 WHERE isbn = $1", bk.Isbn, bk.Title, bk.Author, bk.Price)
+}
+
+func (s *PostgresStore) Delete(ctx context.Context, isbn string) error {
+	return s.exec(ctx, "DELETE FROM books WHERE isbn = $1", isbn)
+}
+
+// exec runs an update or delete on the primary
+func (s *PostgresStore) exec(ctx context.Context, query string, args ...any) error {
+	return s.h.Write(ctx, func(db *sql.DB) error {
+		res, err := db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		return mustAffect(res)
+	})
+}
+
+// mustAffect turns an update or delete that matched no row into ErrNotFound
+func mustAffect(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
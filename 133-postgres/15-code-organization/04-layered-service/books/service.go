@@ -0,0 +1,85 @@
+package books
+
+import (
+	"context"
+	"strings"
+)
+
+// Service holds the business rules of books. It knows nothing about HTTP,
+// so handlers, a CLI or tests can all drive it.
+type Service struct {
+	store Store
+}
+
+func NewService(store Store) *Service {
+	return &Service{store: store}
+}
+
+func (s *Service) List(ctx context.Context) ([]Book, error) {
+	return s.store.All(ctx)
+}
+
+func (s *Service) Get(ctx context.Context, isbn string) (Book, error) {
+	isbn = strings.TrimSpace(isbn)
+	if isbn == "" {
+		return Book{}, ValidationError{"isbn": "is required"}
+	}
+	return s.store.One(ctx, isbn)
+}
+
+// Create validates the book and stores it, returning it as stored
+func (s *Service) Create(ctx context.Context, bk Book) (Book, error) {
+	bk = normalize(bk)
+	if err := validate(bk); err != nil {
+		return bk, err
+	}
+	return bk, s.store.Insert(ctx, bk)
+}
+
+// Update replaces the book with the same isbn
+func (s *Service) Update(ctx context.Context, bk Book) (Book, error) {
+	bk = normalize(bk)
+	if err := validate(bk); err != nil {
+		return bk, err
+	}
+	return bk, s.store.Update(ctx, bk)
+}
+
+func (s *Service) Delete(ctx context.Context, isbn string) error {
+	isbn = strings.TrimSpace(isbn)
+	if isbn == "" {
+		return ValidationError{"isbn": "is required"}
+	}
+	return s.store.Delete(ctx, isbn)
+}
+
+func normalize(bk Book) Book {
+	bk.Isbn = strings.TrimSpace(bk.Isbn)
+	bk.Title = strings.TrimSpace(bk.Title)
+	bk.Author = strings.TrimSpace(bk.Author)
+	return bk
+}
+
+func validate(bk Book) error {
+	v := ValidationError{}
+	if bk.Isbn == "" {
+		v["isbn"] = "is required"
+	}
+	if bk.Title == "" {
+		v["title"] = "is required"
+	}
+	if bk.Author == "" {
+		v["author"] = "is required"
+	}
+	switch {
+	case bk.NoPrice:
+		v["price"] = "is required"
+	case bk.Price < 0:
+		v["price"] = "can't be negative"
+	}
+
+	if len(v) > 0 {
+		return v
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package books
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCreateValidates(t *testing.T) {
+	tests := []struct {
+		name    string
+		bk      Book
+		invalid []string
+	}{
+		{"complete", Book{Isbn: "978-1470184841", Title: "Metamorphosis", Author: "Franz Kafka", Price: 5.9}, nil},
+		{"blank fields", Book{Isbn: " ", Price: 1}, []string{"isbn", "title", "author"}},
+		{"missing price", Book{Isbn: "1", Title: "t", Author: "a", NoPrice: true}, []string{"price"}},
+		{"negative price", Book{Isbn: "1", Title: "t", Author: "a", Price: -1}, []string{"price"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewService(NewMemoryStore())
+			_, err := svc.Create(context.Background(), tt.bk)
+
+			var v ValidationError
+			if len(tt.invalid) == 0 {
+				if err != nil {
+					t.Fatalf("got error %v, want none", err)
+				}
+				return
+			}
+			if !errors.As(err, &v) {
+				t.Fatalf("got %v, want a ValidationError", err)
+			}
+			if len(v) != len(tt.invalid) {
+				t.Errorf("got %v, want errors for %v", v, tt.invalid)
+			}
+			for _, field := range tt.invalid {
+				if _, ok := v[field]; !ok {
+					t.Errorf("no error for %s in %v", field, v)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateTrimsAndStores(t *testing.T) {
+	store := NewMemoryStore()
+	svc := NewService(store)
+
+	_, err := svc.Create(context.Background(), Book{Isbn: " 1 ", Title: " Dune ", Author: "Frank Herbert", Price: 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.One(context.Background(), "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "Dune" {
+		t.Errorf("got title %q, want %q", got.Title, "Dune")
+	}
+
+	_, err = svc.Create(context.Background(), Book{Isbn: "1", Title: "Dune", Author: "Frank Herbert"})
+	if !errors.Is(err, ErrDuplicate) {
+		t.Errorf("got %v, want %v", err, ErrDuplicate)
+	}
+}
+
+func TestUpdateAndDeleteMissingBook(t *testing.T) {
+	svc := NewService(NewMemoryStore())
+
+	_, err := svc.Update(context.Background(), Book{Isbn: "1", Title: "t", Author: "a"})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("update: got %v, want %v", err, ErrNotFound)
+	}
+
+	if err := svc.Delete(context.Background(), "1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("delete: got %v, want %v", err, ErrNotFound)
+	}
+}
@@ -0,0 +1,73 @@
+// Command cli manages books from the terminal with the same service as the web app
+package main
+
+import (
+	"code-organization/04-layered-service/app"
+	"code-organization/04-layered-service/books"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+)
+
+const usage = `usage:
+  cli list
+  cli show <isbn>
+  cli add <isbn> <title> <author> <price>
+  cli delete <isbn>`
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatalln(usage)
+	}
+
+	a, err := app.Open(context.Background(), app.ConfigFromEnv())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = run(context.Background(), a.Books, os.Args[1], os.Args[2:])
+	a.Close()
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func run(ctx context.Context, svc *books.Service, cmd string, args []string) error {
+	switch {
+	case cmd == "list" && len(args) == 0:
+		bks, err := svc.List(ctx)
+		if err != nil {
+			return err
+		}
+		for _, bk := range bks {
+			printBook(bk)
+		}
+	case cmd == "show" && len(args) == 1:
+		bk, err := svc.Get(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		printBook(bk)
+	case cmd == "add" && len(args) == 4:
+		price, err := strconv.ParseFloat(args[3], 32)
+		if err != nil {
+			return fmt.Errorf("price must be a number: %q", args[3])
+		}
+		bk, err := svc.Create(ctx, books.Book{Isbn: args[0], Title: args[1], Author: args[2], Price: float32(price)})
+		if err != nil {
+			return err
+		}
+		printBook(bk)
+	case cmd == "delete" && len(args) == 1:
+		return svc.Delete(ctx, args[0])
+	default:
+		return fmt.Errorf("%s", usage)
+	}
+	return nil
+}
+
+func printBook(bk books.Book) {
+	fmt.Printf("%s - %s - %s - %.2f\n", bk.Isbn, bk.Title, bk.Author, bk.Price)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"code-organization/04-layered-service/app"
+	"context"
+	"log"
+	"net/http"
+)
+
+func main() {
+	cfg := app.ConfigFromEnv()
+
+	a, err := app.Open(context.Background(), cfg)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer a.Close()
+	log.Println("You connected to your database.")
+
+	h, err := a.Handler(cfg)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	log.Printf("Listening on %s\n", cfg.Addr)
+	if err := http.ListenAndServe(cfg.Addr, h); err != nil {
+		log.Fatalln(err)
+	}
+}
@@ -0,0 +1,207 @@
+// Package replica sends reads to Postgres replicas and writes to the primary. Replicas are
+// health checked in the background, a replica that is down is skipped until it answers again
+// and without any replica the reads go to the primary.
+package replica
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Policy int
+
+const (
+	RoundRobin   Policy = iota // replicas take turns
+	LeastLatency               // the replica with the fastest health checks
+)
+
+type Options struct {
+	Policy        Policy
+	CheckInterval time.Duration // between health checks
+	CheckTimeout  time.Duration // of one health check
+	// StickyFor is how long the reads of a session go to the primary after it wrote,
+	// longer than the replication lag so the session reads its own writes
+	StickyFor time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Policy:        RoundRobin,
+		CheckInterval: 2 * time.Second,
+		CheckTimeout:  time.Second,
+		StickyFor:     5 * time.Second,
+	}
+}
+
+// Router picks the handle of each query. Read and Write run a function on it.
+type Router struct {
+	primary  *sql.DB
+	replicas []*node
+	opts     Options
+	next     atomic.Uint64 // round robin counter
+
+	stop chan struct{}
+	done sync.WaitGroup
+	once sync.Once
+}
+
+type node struct {
+	db      *sql.DB
+	healthy atomic.Bool
+	latency atomic.Int64 // moving average of the health checks, in ns
+}
+
+// New returns a router over the handles, the replicas count as healthy until a check says
+// otherwise. Call Start to run the health checks.
+func New(primary *sql.DB, replicas []*sql.DB, opts Options) *Router {
+	r := &Router{primary: primary, opts: opts, stop: make(chan struct{})}
+	for _, db := range replicas {
+		n := &node{db: db}
+		n.healthy.Store(true)
+		r.replicas = append(r.replicas, n)
+	}
+	return r
+}
+
+// Start checks the replicas every CheckInterval until Close
+func (r *Router) Start() {
+	r.done.Add(1)
+	go func() {
+		defer r.done.Done()
+		ticker := time.NewTicker(r.opts.CheckInterval)
+		defer ticker.Stop()
+
+		for {
+			r.Check(context.Background())
+			select {
+			case <-ticker.C:
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Check pings every replica once, in parallel, and records whether it answered and how fast
+func (r *Router) Check(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, n := range r.replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, r.opts.CheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			if err := n.db.PingContext(ctx); err != nil {
+				n.healthy.Store(false)
+				return
+			}
+			n.observe(time.Since(start))
+			n.healthy.Store(true)
+		}()
+	}
+	wg.Wait()
+}
+
+func (n *node) observe(d time.Duration) {
+	old := n.latency.Load()
+	if old == 0 {
+		n.latency.Store(int64(d))
+		return
+	}
+	n.latency.Store(old + (int64(d)-old)/4) // * recent checks weigh more, one slow ping doesn't flip the choice
+}
+
+// Primary is the handle of writes, and of reads when no replica can take them
+func (r *Router) Primary() *sql.DB {
+	return r.primary
+}
+
+// Reader returns the handle a read of ctx goes to: the primary for a session that wrote
+// less than StickyFor ago or when every replica is down, a replica otherwise
+func (r *Router) Reader(ctx context.Context) *sql.DB {
+	if s := sessionOf(ctx); s != nil && s.wroteWithin(r.opts.StickyFor) {
+		return r.primary
+	}
+	if n := r.pick(); n != nil {
+		return n.db
+	}
+	return r.primary
+}
+
+func (r *Router) pick() *node {
+	healthy := make([]*node, 0, len(r.replicas))
+	for _, n := range r.replicas {
+		if n.healthy.Load() {
+			healthy = append(healthy, n)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+
+	if r.opts.Policy == LeastLatency {
+		best := healthy[0]
+		for _, n := range healthy[1:] {
+			if n.latency.Load() < best.latency.Load() {
+				best = n
+			}
+		}
+		return best
+	}
+	return healthy[r.next.Add(1)%uint64(len(healthy))]
+}
+
+// Read runs fn on the reader of ctx. When a replica fails with a connection error it is marked
+// down, until its next health check, and fn runs again on the primary.
+func (r *Router) Read(ctx context.Context, fn func(db *sql.DB) error) error {
+	db := r.Reader(ctx)
+	err := fn(db)
+	if db == r.primary || ctx.Err() != nil || !isConnError(err) {
+		return err
+	}
+
+	for _, n := range r.replicas {
+		if n.db == db {
+			n.healthy.Store(false)
+		}
+	}
+	return fn(r.primary)
+}
+
+// Write runs fn on the primary, the session of ctx then reads from the primary for StickyFor
+func (r *Router) Write(ctx context.Context, fn func(db *sql.DB) error) error {
+	if s := sessionOf(ctx); s != nil {
+		defer s.wrote()
+	}
+	return fn(r.primary)
+}
+
+// Healthy is the number of replicas taking reads
+func (r *Router) Healthy() int {
+	n := 0
+	for _, node := range r.replicas {
+		if node.healthy.Load() {
+			n++
+		}
+	}
+	return n
+}
+
+// Close stops the health checks and closes the replicas, the primary is left to its owner
+func (r *Router) Close() error {
+	var errs []error
+	r.once.Do(func() {
+		close(r.stop)
+		r.done.Wait()
+		for _, n := range r.replicas {
+			errs = append(errs, n.db.Close())
+		}
+	})
+	return errors.Join(errs...)
+}
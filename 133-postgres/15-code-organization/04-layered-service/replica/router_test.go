@@ -0,0 +1,273 @@
+package replica
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeDB is a database whose pings fail while down and take delay
+type fakeDB struct {
+	down  atomic.Bool
+	delay time.Duration
+}
+
+func (d *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{d}, nil }
+func (d *fakeDB) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ d *fakeDB }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c fakeConn) Ping(ctx context.Context) error {
+	time.Sleep(c.d.delay)
+	if c.d.down.Load() {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func newRouter(t *testing.T, opts Options, replicas ...*fakeDB) (*Router, *sql.DB, []*sql.DB) {
+	primary := sql.OpenDB(&fakeDB{})
+	t.Cleanup(func() { primary.Close() })
+
+	dbs := make([]*sql.DB, len(replicas))
+	for i, r := range replicas {
+		dbs[i] = sql.OpenDB(r)
+	}
+	r := New(primary, dbs, opts)
+	t.Cleanup(func() { r.Close() })
+	return r, primary, dbs
+}
+
+func TestRoundRobin(t *testing.T) {
+	r, primary, dbs := newRouter(t, DefaultOptions(), &fakeDB{}, &fakeDB{})
+
+	counts := map[*sql.DB]int{}
+	for range 6 {
+		counts[r.Reader(context.Background())]++
+	}
+	if counts[dbs[0]] != 3 || counts[dbs[1]] != 3 || counts[primary] != 0 {
+		t.Errorf("reads per handle = %v, want 3 on each replica", counts)
+	}
+	if r.Primary() != primary {
+		t.Error("Primary isn't the primary")
+	}
+}
+
+func TestLeastLatency(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Policy = LeastLatency
+	r, _, dbs := newRouter(t, opts, &fakeDB{delay: 20 * time.Millisecond}, &fakeDB{})
+	r.Check(context.Background())
+
+	for range 3 {
+		if db := r.Reader(context.Background()); db != dbs[1] {
+			t.Fatal("read didn't go to the fastest replica")
+		}
+	}
+}
+
+func TestFallback(t *testing.T) {
+	a, b := &fakeDB{}, &fakeDB{}
+	r, primary, dbs := newRouter(t, DefaultOptions(), a, b)
+	ctx := context.Background()
+
+	a.down.Store(true)
+	r.Check(ctx)
+	if r.Healthy() != 1 {
+		t.Fatalf("%d healthy replicas, want 1", r.Healthy())
+	}
+	for range 3 {
+		if r.Reader(ctx) != dbs[1] {
+			t.Fatal("read went to the replica that is down")
+		}
+	}
+
+	b.down.Store(true)
+	r.Check(ctx)
+	if r.Reader(ctx) != primary {
+		t.Error("read didn't fall back to the primary with every replica down")
+	}
+
+	a.down.Store(false)
+	r.Check(ctx)
+	if r.Reader(ctx) != dbs[0] {
+		t.Error("the replica that came back takes no reads")
+	}
+}
+
+func TestReadYourWrites(t *testing.T) {
+	opts := DefaultOptions()
+	opts.StickyFor = 50 * time.Millisecond
+	r, primary, _ := newRouter(t, opts, &fakeDB{})
+	ctx := WithSession(context.Background())
+
+	if r.Reader(ctx) == primary {
+		t.Fatal("a session that didn't write reads from the primary")
+	}
+
+	r.Write(ctx, func(db *sql.DB) error {
+		if db != primary {
+			t.Error("write didn't go to the primary")
+		}
+		return nil
+	})
+	if r.Reader(ctx) != primary {
+		t.Error("read after a write didn't go to the primary")
+	}
+	if r.Reader(context.Background()) == primary {
+		t.Error("the write of a session sent another session's reads to the primary")
+	}
+
+	time.Sleep(opts.StickyFor)
+	if r.Reader(ctx) == primary {
+		t.Error("the session still reads from the primary after StickyFor")
+	}
+}
+
+func TestReadYourWritesAfterRedirect(t *testing.T) {
+	opts := DefaultOptions()
+	opts.StickyFor = 200 * time.Millisecond
+	r, primary, _ := newRouter(t, opts, &fakeDB{})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /books", func(w http.ResponseWriter, req *http.Request) {
+		r.Write(req.Context(), func(*sql.DB) error { return nil })
+		http.Redirect(w, req, "/books", http.StatusSeeOther)
+	})
+	mux.HandleFunc("GET /books", func(w http.ResponseWriter, req *http.Request) {
+		if r.Reader(req.Context()) == primary {
+			io.WriteString(w, "primary")
+		} else {
+			io.WriteString(w, "replica")
+		}
+	})
+	srv := httptest.NewServer(r.Middleware(mux))
+	defer srv.Close()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	browser := &http.Client{Jar: jar}
+	read := func(c *http.Client, req *http.Request) string {
+		t.Helper()
+		res, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return string(body)
+	}
+	get := func(c *http.Client) string {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/books", nil)
+		return read(c, req)
+	}
+
+	if got := get(browser); got != "replica" {
+		t.Fatalf("read from the %s before any write", got)
+	}
+
+	// * the client follows the redirect of the form like a browser
+	post, _ := http.NewRequest(http.MethodPost, srv.URL+"/books", nil)
+	if got := read(browser, post); got != "primary" {
+		t.Errorf("the page after the redirect read from the %s", got)
+	}
+	if got := get(browser); got != "primary" {
+		t.Errorf("the next page read from the %s within StickyFor", got)
+	}
+	if got := get(&http.Client{}); got != "replica" {
+		t.Errorf("another browser read from the %s", got)
+	}
+
+	time.Sleep(opts.StickyFor)
+	if got := get(browser); got != "replica" {
+		t.Errorf("read from the %s after StickyFor", got)
+	}
+}
+
+func TestMiddlewareIgnoresFutureCookie(t *testing.T) {
+	r, primary, _ := newRouter(t, DefaultOptions(), &fakeDB{})
+	var reader *sql.DB
+	h := r.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		reader = r.Reader(req.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/books", nil)
+	req.AddCookie(&http.Cookie{Name: stickyCookie, Value: strconv.FormatInt(time.Now().Add(time.Hour).UnixNano(), 10)})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if reader == primary {
+		t.Error("a cookie from the future sent the read to the primary")
+	}
+	if len(rec.Result().Cookies()) != 0 {
+		t.Error("a request without a write set the cookie")
+	}
+}
+
+func TestReadRetriesOnPrimary(t *testing.T) {
+	r, primary, dbs := newRouter(t, DefaultOptions(), &fakeDB{})
+	ctx := context.Background()
+
+	var used []*sql.DB
+	err := r.Read(ctx, func(db *sql.DB) error {
+		used = append(used, db)
+		if db != primary {
+			return driver.ErrBadConn
+		}
+		return nil
+	})
+	if err != nil || len(used) != 2 || used[0] != dbs[0] || used[1] != primary {
+		t.Fatalf("err = %v, ran on %v, want the replica then the primary", err, used)
+	}
+	if r.Healthy() != 0 {
+		t.Error("the failing replica is still taking reads")
+	}
+
+	// a query error is the answer, it doesn't run again
+	r.Check(ctx)
+	runs := 0
+	err = r.Read(ctx, func(db *sql.DB) error { runs++; return sql.ErrNoRows })
+	if !errors.Is(err, sql.ErrNoRows) || runs != 1 || r.Healthy() != 1 {
+		t.Errorf("err = %v after %d runs with %d healthy, want ErrNoRows after 1 run", err, runs, r.Healthy())
+	}
+}
+
+func TestStart(t *testing.T) {
+	opts := DefaultOptions()
+	opts.CheckInterval = 5 * time.Millisecond
+	a := &fakeDB{}
+	r, _, _ := newRouter(t, opts, a)
+
+	r.Start()
+	a.down.Store(true)
+	deadline := time.Now().Add(time.Second)
+	for r.Healthy() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the health checks didn't mark the replica down")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
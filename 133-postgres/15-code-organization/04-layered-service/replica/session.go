@@ -0,0 +1,105 @@
+package replica
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// stickyCookie carries the last write of a browser to its next requests, e.g. the page it is
+// redirected to after a form
+const stickyCookie = "replica_wrote"
+
+// session remembers when it last wrote, to send its reads to the primary right after
+type session struct {
+	lastWrite atomic.Int64 // unix ns, 0 before the first write
+}
+
+type sessionKey struct{}
+
+// WithSession starts a session, the reads and writes made with the returned context see
+// each other. Without a session every read may go to a replica.
+func WithSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sessionKey{}, &session{})
+}
+
+// Middleware gives every request a session, so a handler that writes then reads finds what it
+// wrote. The time of a write goes back in a cookie lasting StickyFor, the next requests of the
+// browser resume the session from it and a page after a redirect reads from the primary too.
+func (r *Router) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		s := &session{}
+		if c, err := req.Cookie(stickyCookie); err == nil {
+			last, err := strconv.ParseInt(c.Value, 10, 64)
+			// * a time in the future would keep the browser on the primary for good
+			if err == nil && last <= time.Now().UnixNano() {
+				s.lastWrite.Store(last)
+			}
+		}
+
+		sw := &stickyWriter{ResponseWriter: w, s: s, restored: s.lastWrite.Load(), maxAge: r.opts.StickyFor}
+		next.ServeHTTP(sw, req.WithContext(context.WithValue(req.Context(), sessionKey{}, s)))
+		// * a handler that wrote nothing still gets its headers, sent by net/http after this
+		sw.setCookie()
+	})
+}
+
+// stickyWriter sets the cookie of a write made by the handler before the headers go out
+type stickyWriter struct {
+	http.ResponseWriter
+	s        *session
+	restored int64
+	maxAge   time.Duration
+	sent     bool
+}
+
+func (w *stickyWriter) WriteHeader(code int) {
+	w.setCookie()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *stickyWriter) Write(b []byte) (int, error) {
+	w.setCookie()
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the flusher of the connection
+func (w *stickyWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *stickyWriter) setCookie() {
+	if w.sent {
+		return
+	}
+	w.sent = true
+	last := w.s.lastWrite.Load()
+	if last == w.restored {
+		return
+	}
+	http.SetCookie(w.ResponseWriter, &http.Cookie{
+		Name:     stickyCookie,
+		Value:    strconv.FormatInt(last, 10),
+		Path:     "/",
+		MaxAge:   int(math.Ceil(w.maxAge.Seconds())),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func sessionOf(ctx context.Context) *session {
+	s, _ := ctx.Value(sessionKey{}).(*session)
+	return s
+}
+
+func (s *session) wrote() {
+	s.lastWrite.Store(time.Now().UnixNano())
+}
+
+func (s *session) wroteWithin(d time.Duration) bool {
+	last := s.lastWrite.Load()
+	return last != 0 && time.Since(time.Unix(0, last)) < d
+}
@@ -0,0 +1,189 @@
+package web
+
+import (
+	"code-organization/04-layered-service/books"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// Handlers only deal with HTTP: they parse requests, call the service and render templates.
+// Everything they need is handed to NewHandlers, there are no package level variables.
+type Handlers struct {
+	books *books.Service
+	tpl   *template.Template
+}
+
+func NewHandlers(svc *books.Service, tpl *template.Template) *Handlers {
+	return &Handlers{books: svc, tpl: tpl}
+}
+
+// Routes registers every handler on a new mux
+func (h *Handlers) Routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", h.index)
+	mux.HandleFunc("/books", h.Index)
+	mux.HandleFunc("/books/show", h.Show)
+	mux.HandleFunc("/books/create", h.Create)
+	mux.HandleFunc("/books/create/process", h.CreateProcess)
+	mux.HandleFunc("/books/update", h.Update)
+	mux.HandleFunc("/books/update/process", h.UpdateProcess)
+	mux.HandleFunc("/books/delete/process", h.DeleteProcess)
+	return mux
+}
+
+func (h *Handlers) index(w http.ResponseWriter, r *http.Request) {
+	http.Redirect(w, r, "/books", http.StatusSeeOther)
+}
+
+func (h *Handlers) Index(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+		return
+	}
+
+	bks, err := h.books.List(r.Context())
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	h.render(w, "books.gohtml", bks)
+}
+
+func (h *Handlers) Show(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+		return
+	}
+
+	bk, err := h.books.Get(r.Context(), r.FormValue("isbn"))
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	h.render(w, "show.gohtml", bk)
+}
+
+func (h *Handlers) Create(w http.ResponseWriter, r *http.Request) {
+	h.render(w, "create.gohtml", nil)
+}
+
+func (h *Handlers) CreateProcess(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+		return
+	}
+
+	bk, err := parseBook(r)
+	if err != nil {
+		http.Error(w, http.StatusText(406)+". "+err.Error(), http.StatusNotAcceptable)
+		return
+	}
+
+	bk, err = h.books.Create(r.Context(), bk)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	h.render(w, "created.gohtml", bk)
+}
+
+func (h *Handlers) Update(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+		return
+	}
+
+	bk, err := h.books.Get(r.Context(), r.FormValue("isbn"))
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	h.render(w, "update.gohtml", bk)
+}
+
+func (h *Handlers) UpdateProcess(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+		return
+	}
+
+	bk, err := parseBook(r)
+	if err != nil {
+		http.Error(w, http.StatusText(406)+". "+err.Error(), http.StatusNotAcceptable)
+		return
+	}
+
+	bk, err = h.books.Update(r.Context(), bk)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	h.render(w, "updated.gohtml", bk)
+}
+
+func (h *Handlers) DeleteProcess(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := h.books.Delete(r.Context(), r.FormValue("isbn")); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/books", http.StatusSeeOther)
+}
+
+// parseBook reads the form, only the conversion of the price can fail here.
+// Checking the fields themselves is the service's job.
+func parseBook(r *http.Request) (books.Book, error) {
+	bk := books.Book{
+		Isbn:   r.FormValue("isbn"),
+		Title:  r.FormValue("title"),
+		Author: r.FormValue("author"),
+	}
+
+	p := r.FormValue("price")
+	if p == "" {
+		bk.NoPrice = true
+		return bk, nil
+	}
+	f64, err := strconv.ParseFloat(p, 32)
+	if err != nil {
+		return bk, errors.New("price must be a number")
+	}
+	bk.Price = float32(f64)
+
+	return bk, nil
+}
+
+// fail maps service errors to status codes
+func (h *Handlers) fail(w http.ResponseWriter, r *http.Request, err error) {
+	var invalid books.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		http.Error(w, http.StatusText(400)+". "+invalid.Error(), http.StatusBadRequest)
+	case errors.Is(err, books.ErrNotFound):
+		http.NotFound(w, r)
+	case errors.Is(err, books.ErrDuplicate):
+		http.Error(w, http.StatusText(409)+". "+err.Error(), http.StatusConflict)
+	default:
+		log.Printf("%s %s: %v\n", r.Method, r.URL.Path, err)
+		http.Error(w, http.StatusText(500), http.StatusInternalServerError)
+	}
+}
+
+func (h *Handlers) render(w http.ResponseWriter, name string, data any) {
+	if err := h.tpl.ExecuteTemplate(w, name, data); err != nil {
+		log.Printf("rendering %s: %v\n", name, err)
+	}
+}
@@ -0,0 +1,65 @@
+package web
+
+import (
+	"code-organization/04-layered-service/books"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// newTestHandlers runs the real handlers and service on top of an in-memory store
+func newTestHandlers(bks ...books.Book) *Handlers {
+	tpl := template.Must(template.ParseGlob("../templates/*.gohtml"))
+	return NewHandlers(books.NewService(books.NewMemoryStore(bks...)), tpl)
+}
+
+func TestHandlersStatusCodes(t *testing.T) {
+	kafka := books.Book{Isbn: "978-1470184841", Title: "Metamorphosis", Author: "Franz Kafka", Price: 5.9}
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		form   url.Values
+		want   int
+	}{
+		{"list", "GET", "/books", nil, http.StatusOK},
+		{"show", "GET", "/books/show?isbn=978-1470184841", nil, http.StatusOK},
+		{"show missing", "GET", "/books/show?isbn=0", nil, http.StatusNotFound},
+		{"show without isbn", "GET", "/books/show", nil, http.StatusBadRequest},
+		{"create", "POST", "/books/create/process",
+			url.Values{"isbn": {"1"}, "title": {"Dune"}, "author": {"Frank Herbert"}, "price": {"9.5"}}, http.StatusOK},
+		{"create duplicate", "POST", "/books/create/process",
+			url.Values{"isbn": {"978-1470184841"}, "title": {"t"}, "author": {"a"}, "price": {"1"}}, http.StatusConflict},
+		{"create bad price", "POST", "/books/create/process",
+			url.Values{"isbn": {"1"}, "title": {"t"}, "author": {"a"}, "price": {"cheap"}}, http.StatusNotAcceptable},
+		{"create missing title", "POST", "/books/create/process",
+			url.Values{"isbn": {"1"}, "author": {"a"}, "price": {"1"}}, http.StatusBadRequest},
+		{"create missing price", "POST", "/books/create/process",
+			url.Values{"isbn": {"1"}, "title": {"t"}, "author": {"a"}, "price": {""}}, http.StatusBadRequest},
+		{"update missing", "POST", "/books/update/process",
+			url.Values{"isbn": {"0"}, "title": {"t"}, "author": {"a"}, "price": {"1"}}, http.StatusNotFound},
+		{"delete", "GET", "/books/delete/process?isbn=978-1470184841", nil, http.StatusSeeOther},
+		{"wrong method", "POST", "/books", nil, http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandlers(kafka)
+
+			r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.form.Encode()))
+			if tt.form != nil {
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			w := httptest.NewRecorder()
+			h.Routes().ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Errorf("got status %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
@@ -2,4 +2,9 @@ module code-organization
 
 go 1.23.0
 
-require github.com/lib/pq v1.10.9 // indirect
+require (
+	github.com/lib/pq v1.10.9
+	go/go-examples/133-postgres/17-typed-queries/dbq v0.0.0-00010101000000-000000000000
+)
+
+replace go/go-examples/133-postgres/17-typed-queries/dbq => ../17-typed-queries/dbq
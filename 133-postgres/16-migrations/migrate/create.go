@@ -0,0 +1,55 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var nonWord = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Create writes an empty up/down pair to dir with the next free version and returns their paths.
+// "Add books index" becomes 0005_add_books_index.up.sql and 0005_add_books_index.down.sql.
+func Create(dir, name string) (string, string, error) {
+	slug := strings.Trim(nonWord.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if slug == "" {
+		return "", "", errors.New("the migration name needs at least one letter or digit")
+	}
+
+	ms, err := Load(os.DirFS(dir))
+	if err != nil {
+		return "", "", err
+	}
+	next := int64(1)
+	if len(ms) > 0 {
+		next = ms[len(ms)-1].Version + 1
+	}
+
+	base := filepath.Join(dir, fmt.Sprintf("%04d_%s", next, slug))
+	up, down := base+".up.sql", base+".down.sql"
+
+	if err := writeNew(up, fmt.Sprintf("-- %04d %s\n", next, name)); err != nil {
+		return "", "", err
+	}
+	if err := writeNew(down, fmt.Sprintf("-- undo %04d %s\n", next, name)); err != nil {
+		os.Remove(up)
+		return "", "", err
+	}
+	return up, down, nil
+}
+
+// writeNew never overwrites an existing file
+func writeNew(name, content string) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,166 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"log"
+	"time"
+)
+
+// lockKey identifies the advisory lock of this tool, any other runner waits for it
+const lockKey int64 = 7_238_001_146
+
+const createTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    BIGINT PRIMARY KEY,
+	name       TEXT        NOT NULL,
+	checksum   TEXT        NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// New loads the migrations of fsys, usually an embed.FS compiled into the binary
+func New(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	ms, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: ms}, nil
+}
+
+// Up applies the pending migrations up to and including target, 0 applies all of them.
+// Each migration runs in its own transaction together with its schema_migrations row.
+func (m *Migrator) Up(ctx context.Context, target int64) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *sql.Conn, applied map[int64]record) error {
+		if err := verify(m.migrations, applied); err != nil {
+			return err
+		}
+
+		for _, mg := range pending(m.migrations, applied, target) {
+			log.Printf("applying %s\n", mg)
+			err := run(ctx, conn, mg.Up,
+				"INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)",
+				mg.Version, mg.Name, mg.Checksum)
+			if err != nil {
+				return fmt.Errorf("%s: %w", mg, err)
+			}
+			done = append(done, mg)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down rolls back the last steps applied migrations, newest first
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *sql.Conn, applied map[int64]record) error {
+		if err := verify(m.migrations, applied); err != nil {
+			return err
+		}
+
+		for _, mg := range rollback(m.migrations, applied, steps) {
+			log.Printf("rolling back %s\n", mg)
+			err := run(ctx, conn, mg.Down, "DELETE FROM schema_migrations WHERE version = $1", mg.Version)
+			if err != nil {
+				return fmt.Errorf("%s: %w", mg, err)
+			}
+			done = append(done, mg)
+		}
+		return nil
+	})
+	return done, err
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var sts []Status
+	err := m.locked(ctx, func(_ *sql.Conn, applied map[int64]record) error {
+		sts = status(m.migrations, applied)
+		return nil
+	})
+	return sts, err
+}
+
+// locked runs fn holding the advisory lock. The lock belongs to a database session,
+// so everything runs on one dedicated connection that is unlocked before going back to the pool.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn, applied map[int64]record) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Waits for a runner holding the lock, ctx bounds the wait
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return fmt.Errorf("acquiring the migration lock: %w", err)
+	}
+	defer func() {
+		unlockCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		if _, err := conn.ExecContext(unlockCtx, "SELECT pg_advisory_unlock($1)", lockKey); err != nil {
+			log.Printf("releasing the migration lock: %v\n", err)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, createTable); err != nil {
+		return err
+	}
+	// Read under the lock, a runner we waited for may have applied migrations meanwhile
+	applied, err := readApplied(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn, applied)
+}
+
+func readApplied(ctx context.Context, conn *sql.Conn) (map[int64]record, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]record{}
+	for rows.Next() {
+		rec := record{}
+		if err := rows.Scan(&rec.Version, &rec.Name, &rec.Checksum, &rec.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied[rec.Version] = rec
+	}
+	return applied, rows.Err()
+}
+
+// run executes a migration file and the bookkeeping statement, in one transaction
+// unless the file opts out with the no-transaction directive
+func run(ctx context.Context, conn *sql.Conn, script, record string, args ...any) error {
+	if !inTransaction(script) {
+		if _, err := conn.ExecContext(ctx, script); err != nil {
+			return err
+		}
+		_, err := conn.ExecContext(ctx, record, args...)
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Without arguments lib/pq uses the simple protocol, which accepts several statements
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,200 @@
+package migrate
+
+import (
+	"errors"
+	"go/go-examples/133-postgres/16-migrations/migrations"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func files(names ...string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for _, n := range names {
+		fsys[n] = &fstest.MapFile{Data: []byte("SELECT 1; -- " + n)}
+	}
+	return fsys
+}
+
+func applied(ms []Migration, versions ...int64) map[int64]record {
+	byVersion := index(ms)
+	out := map[int64]record{}
+	for _, v := range versions {
+		out[v] = record{Version: v, Name: byVersion[v].Name, Checksum: byVersion[v].Checksum, AppliedAt: time.Now()}
+	}
+	return out
+}
+
+func versions(ms []Migration) []int64 {
+	out := []int64{}
+	for _, m := range ms {
+		out = append(out, m.Version)
+	}
+	return out
+}
+
+func equal(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestLoad(t *testing.T) {
+	ms, err := Load(files(
+		"0002_b.up.sql", "0002_b.down.sql",
+		"0001_a.up.sql", "0001_a.down.sql",
+		"README.md",
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := versions(ms), []int64{1, 2}; !equal(got, want) {
+		t.Errorf("got versions %v, want %v", got, want)
+	}
+	if ms[0].Name != "a" || ms[0].Checksum == "" || ms[0].Checksum == ms[1].Checksum {
+		t.Errorf("got %+v, want name a with its own checksum", ms[0])
+	}
+}
+
+func TestLoadRejectsBadFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{"missing down", files("0001_a.up.sql")},
+		{"bad name", files("1-a.up.sql", "1-a.down.sql")},
+		{"two names for one version", files("0001_a.up.sql", "0001_a.down.sql", "0001_b.up.sql", "0001_b.down.sql")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(tt.fsys); !errors.Is(err, ErrBadSource) {
+				t.Errorf("got %v, want %v", err, ErrBadSource)
+			}
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	ms, err := Load(files(
+		"0001_a.up.sql", "0001_a.down.sql",
+		"0002_b.up.sql", "0002_b.down.sql",
+		"0003_c.up.sql", "0003_c.down.sql",
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := versions(pending(ms, applied(ms, 1), 0)), []int64{2, 3}; !equal(got, want) {
+		t.Errorf("pending: got %v, want %v", got, want)
+	}
+	if got, want := versions(pending(ms, applied(ms), 2)), []int64{1, 2}; !equal(got, want) {
+		t.Errorf("pending up to 2: got %v, want %v", got, want)
+	}
+	if got, want := versions(rollback(ms, applied(ms, 1, 2, 3), 2)), []int64{3, 2}; !equal(got, want) {
+		t.Errorf("rollback: got %v, want %v", got, want)
+	}
+	if got, want := versions(rollback(ms, applied(ms, 1), 5)), []int64{1}; !equal(got, want) {
+		t.Errorf("rollback more than applied: got %v, want %v", got, want)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	ms, err := Load(files("0001_a.up.sql", "0001_a.down.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verify(ms, applied(ms, 1)); err != nil {
+		t.Errorf("got %v, want no error", err)
+	}
+
+	edited := applied(ms, 1)
+	edited[1] = record{Version: 1, Name: "a", Checksum: "something else"}
+	if err := verify(ms, edited); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("got %v, want %v", err, ErrChecksumMismatch)
+	}
+
+	gone := map[int64]record{9: {Version: 9, Name: "z"}}
+	if err := verify(ms, gone); !errors.Is(err, ErrMissingFile) {
+		t.Errorf("got %v, want %v", err, ErrMissingFile)
+	}
+
+	sts := status(ms, edited)
+	if len(sts) != 1 || sts[0].State != StateModified {
+		t.Errorf("got %+v, want version 1 modified", sts)
+	}
+}
+
+func TestVerifyOutOfOrder(t *testing.T) {
+	ms, err := Load(files(
+		"0001_a.up.sql", "0001_a.down.sql",
+		"0002_b.up.sql", "0002_b.down.sql",
+		"0003_c.up.sql", "0003_c.down.sql",
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// * 0002 was merged after 0003 ran
+	if err := verify(ms, applied(ms, 1, 3)); !errors.Is(err, ErrOutOfOrder) {
+		t.Errorf("got %v, want %v", err, ErrOutOfOrder)
+	}
+	if err := verify(ms, applied(ms, 1)); err != nil {
+		t.Errorf("got %v, want newer pending migrations accepted", err)
+	}
+
+	sts := status(ms, applied(ms, 1, 3))
+	if got := []string{sts[0].State, sts[1].State, sts[2].State}; got[0] != StateApplied || got[1] != StateOutOfOrder || got[2] != StateApplied {
+		t.Errorf("got states %q", got)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"0001_a.up.sql", "0001_a.down.sql"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("SELECT 1;"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	up, down, err := Create(dir, "Add books index!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := filepath.Base(up), "0002_add_books_index.up.sql"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if got, want := filepath.Base(down), "0002_add_books_index.down.sql"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	ms, err := Load(os.DirFS(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 2 {
+		t.Errorf("got %d migrations after create, want 2", len(ms))
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	ms, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range ms {
+		if m.Version != int64(i+1) {
+			t.Errorf("got version %d at position %d, want versions without gaps", m.Version, i)
+		}
+	}
+}
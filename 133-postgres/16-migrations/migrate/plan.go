@@ -0,0 +1,148 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var (
+	ErrChecksumMismatch = errors.New("applied migration was modified")
+	ErrMissingFile      = errors.New("applied migration has no file")
+	ErrOutOfOrder       = errors.New("pending migration is older than an applied one")
+)
+
+// record is a row of schema_migrations
+type record struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// Status states
+const (
+	StateApplied    = "applied"
+	StatePending    = "pending"
+	StateModified   = "modified"     // applied, but the up file changed since
+	StateMissing    = "missing"      // applied, but the files are gone
+	StateOutOfOrder = "out of order" // pending, but a later version is applied already
+)
+
+type Status struct {
+	Version   int64
+	Name      string
+	State     string
+	AppliedAt *time.Time
+}
+
+// verify refuses to go on when the files and the database disagree about what was applied
+func verify(ms []Migration, applied map[int64]record) error {
+	byVersion := index(ms)
+
+	var errs []error
+	for _, v := range sortedVersions(applied) {
+		rec := applied[v]
+		m, ok := byVersion[v]
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("%w: %04d_%s", ErrMissingFile, v, rec.Name))
+		case m.Checksum != rec.Checksum:
+			errs = append(errs, fmt.Errorf("%w: %s, write a new migration instead of editing an applied one", ErrChecksumMismatch, m))
+		}
+	}
+
+	// A file merged with a lower version than the newest applied one would run after it,
+	// in an order no other database saw
+	last := lastApplied(applied)
+	for _, m := range ms {
+		if _, ok := applied[m.Version]; !ok && m.Version < last {
+			errs = append(errs, fmt.Errorf("%w: %s, renumber it after %04d", ErrOutOfOrder, m, last))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// pending is every migration not applied yet up to target, 0 meaning all of them
+func pending(ms []Migration, applied map[int64]record, target int64) []Migration {
+	var out []Migration
+	for _, m := range ms {
+		if target != 0 && m.Version > target {
+			break
+		}
+		if _, ok := applied[m.Version]; !ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// rollback is the last steps applied migrations, newest first
+func rollback(ms []Migration, applied map[int64]record, steps int) []Migration {
+	byVersion := index(ms)
+	versions := sortedVersions(applied)
+
+	var out []Migration
+	for i := len(versions) - 1; i >= 0 && len(out) < steps; i-- {
+		out = append(out, byVersion[versions[i]])
+	}
+	return out
+}
+
+func status(ms []Migration, applied map[int64]record) []Status {
+	last := lastApplied(applied)
+	out := make([]Status, 0, len(ms))
+	for _, m := range ms {
+		st := Status{Version: m.Version, Name: m.Name, State: StatePending}
+		if m.Version < last {
+			st.State = StateOutOfOrder
+		}
+		if rec, ok := applied[m.Version]; ok {
+			at := rec.AppliedAt
+			st.AppliedAt = &at
+			st.State = StateApplied
+			if rec.Checksum != m.Checksum {
+				st.State = StateModified
+			}
+		}
+		out = append(out, st)
+	}
+
+	byVersion := index(ms)
+	for v, rec := range applied {
+		if _, ok := byVersion[v]; !ok {
+			at := rec.AppliedAt
+			out = append(out, Status{Version: v, Name: rec.Name, State: StateMissing, AppliedAt: &at})
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out
+}
+
+func index(ms []Migration) map[int64]Migration {
+	byVersion := make(map[int64]Migration, len(ms))
+	for _, m := range ms {
+		byVersion[m.Version] = m
+	}
+	return byVersion
+}
+
+// lastApplied is the newest applied version, 0 when there is none
+func lastApplied(applied map[int64]record) int64 {
+	var last int64
+	for v := range applied {
+		last = max(last, v)
+	}
+	return last
+}
+
+func sortedVersions(applied map[int64]record) []int64 {
+	vs := make([]int64, 0, len(applied))
+	for v := range applied {
+		vs = append(vs, v)
+	}
+	sort.Slice(vs, func(i, j int) bool { return vs[i] < vs[j] })
+	return vs
+}
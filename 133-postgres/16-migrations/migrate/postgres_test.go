@@ -0,0 +1,255 @@
+//go:build postgres
+
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// testFiles are two migrations of a throwaway table
+var testFiles = fstest.MapFS{
+	"0001_a.up.sql":   {Data: []byte("CREATE TABLE migrate_a (id INT PRIMARY KEY);")},
+	"0001_a.down.sql": {Data: []byte("DROP TABLE migrate_a;")},
+	"0002_b.up.sql":   {Data: []byte("ALTER TABLE migrate_a ADD COLUMN name TEXT;")},
+	"0002_b.down.sql": {Data: []byte("ALTER TABLE migrate_a DROP COLUMN name;")},
+}
+
+// testDB connects to POSTGRES_DSN with a schema of its own as search_path, which is dropped
+// at the end of the test. The advisory lock is shared by the whole database though.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_DSN is not set")
+	}
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	schema := fmt.Sprintf("migrate_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	// * lib/pq sends unknown settings to the server as run-time parameters
+	sep := " "
+	if strings.Contains(dsn, "://") {
+		sep = "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+	}
+	db, err := sql.Open("postgres", dsn+sep+"search_path="+schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newTestMigrator(t *testing.T, db *sql.DB) *Migrator {
+	t.Helper()
+	m, err := New(db, testFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func hasColumn(t *testing.T, db *sql.DB, column string) bool {
+	t.Helper()
+	var n int
+	err := db.QueryRow(`SELECT count(*) FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'migrate_a' AND column_name = $1`, column).Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n > 0
+}
+
+func TestUpDownPostgres(t *testing.T) {
+	db := testDB(t)
+	m := newTestMigrator(t, db)
+	ctx := context.Background()
+
+	done, err := m.Up(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(done); !equal(got, []int64{1}) {
+		t.Errorf("up to 1 applied %v", got)
+	}
+	if !hasColumn(t, db, "id") || hasColumn(t, db, "name") {
+		t.Error("want migrate_a without the name column")
+	}
+
+	done, err = m.Up(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(done); !equal(got, []int64{2}) {
+		t.Errorf("up applied %v, want only the pending 2", got)
+	}
+	if !hasColumn(t, db, "name") {
+		t.Error("the name column is missing")
+	}
+
+	sts, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, st := range sts {
+		if st.State != StateApplied || st.AppliedAt == nil {
+			t.Errorf("got %+v, want applied", st)
+		}
+	}
+
+	done, err = m.Down(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(done); !equal(got, []int64{2, 1}) {
+		t.Errorf("down rolled back %v, want 2 then 1", got)
+	}
+	if hasColumn(t, db, "id") {
+		t.Error("migrate_a is still there")
+	}
+	var n int
+	if err := db.QueryRow("SELECT count(*) FROM schema_migrations").Scan(&n); err != nil || n != 0 {
+		t.Errorf("got %d rows in schema_migrations, %v", n, err)
+	}
+}
+
+// A failing migration leaves neither its changes nor its row behind
+func TestUpFailureRollsBackPostgres(t *testing.T) {
+	db := testDB(t)
+	fsys := fstest.MapFS{
+		"0001_a.up.sql":   {Data: []byte("CREATE TABLE migrate_a (id INT); SELECT * FROM no_such_table;")},
+		"0001_a.down.sql": {Data: []byte("DROP TABLE migrate_a;")},
+	}
+	m, err := New(db, fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Up(context.Background(), 0); err == nil {
+		t.Fatal("the failing migration was applied")
+	}
+	if hasColumn(t, db, "id") {
+		t.Error("migrate_a was created by the failed migration")
+	}
+	sts, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sts) != 1 || sts[0].State != StatePending {
+		t.Errorf("got %+v, want 1 pending", sts)
+	}
+}
+
+func TestLockContentionPostgres(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	// * another runner holds the lock
+	holder, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer holder.Close()
+	if _, err := holder.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		t.Fatal(err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	if _, err := newTestMigrator(t, db).Up(waitCtx, 0); err == nil {
+		t.Fatal("migrated while another runner held the lock")
+	}
+	if hasColumn(t, db, "id") {
+		t.Fatal("migrate_a was created without the lock")
+	}
+
+	if _, err := holder.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", lockKey); err != nil {
+		t.Fatal(err)
+	}
+
+	// * runners starting together apply every migration exactly once
+	const runners = 4
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		total []int64
+		errs  []error
+	)
+	for range runners {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			done, err := newTestMigrator(t, db).Up(ctx, 0)
+			mu.Lock()
+			defer mu.Unlock()
+			total = append(total, versions(done)...)
+			errs = append(errs, err)
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		t.Fatal(err)
+	}
+	if len(total) != 2 {
+		t.Errorf("%d runners applied %v, want 1 and 2 once", runners, total)
+	}
+}
+
+func TestOutOfOrderPostgres(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	later := fstest.MapFS{
+		"0002_b.up.sql":   {Data: []byte("CREATE TABLE migrate_b (id INT);")},
+		"0002_b.down.sql": {Data: []byte("DROP TABLE migrate_b;")},
+	}
+	m, err := New(db, later)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// * 0001 shows up once 0002 is applied
+	both := fstest.MapFS{
+		"0001_a.up.sql":   {Data: []byte("CREATE TABLE migrate_a (id INT);")},
+		"0001_a.down.sql": {Data: []byte("DROP TABLE migrate_a;")},
+	}
+	for name, f := range later {
+		both[name] = f
+	}
+	m, err = New(db, both)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx, 0); !errors.Is(err, ErrOutOfOrder) {
+		t.Errorf("got %v, want %v", err, ErrOutOfOrder)
+	}
+	if hasColumn(t, db, "id") {
+		t.Error("the out of order migration was applied")
+	}
+}
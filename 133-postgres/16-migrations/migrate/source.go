@@ -0,0 +1,99 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// noTransaction as the first line of an up or down file runs it outside of a transaction,
+// which statements such as CREATE INDEX CONCURRENTLY require
+const noTransaction = "-- migrate:no-transaction"
+
+// fileName matches 0001_create_books.up.sql and 0001_create_books.down.sql
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+var ErrBadSource = errors.New("invalid migration files")
+
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // sha256 of the up file, detects edits of an applied migration
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// Load reads every numbered up/down pair in the root of fsys, ordered by version.
+// A version needs both files, and one version can't be used by two names.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	var errs []error
+	for _, e := range entries {
+		if e.IsDir() || path.Ext(e.Name()) != ".sql" {
+			continue
+		}
+
+		match := fileName.FindStringSubmatch(e.Name())
+		if match == nil {
+			errs = append(errs, fmt.Errorf("%w: %s is not named like 0001_name.up.sql", ErrBadSource, e.Name()))
+			continue
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		name, direction := match[2], match[3]
+
+		b, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			errs = append(errs, fmt.Errorf("%w: version %d is used by %s and %s", ErrBadSource, version, m.Name, name))
+			continue
+		}
+
+		if direction == "up" {
+			m.Up = string(b)
+			sum := sha256.Sum256(b)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(b)
+		}
+	}
+
+	ms := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			errs = append(errs, fmt.Errorf("%w: %s needs a non-empty up and down file", ErrBadSource, m))
+			continue
+		}
+		ms = append(ms, *m)
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Version < ms[j].Version })
+
+	return ms, errors.Join(errs...)
+}
+
+func inTransaction(sql string) bool {
+	return !strings.HasPrefix(strings.TrimSpace(sql), noTransaction)
+}
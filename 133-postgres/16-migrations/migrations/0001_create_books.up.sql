@@ -0,0 +1,7 @@
+-- bookstore of 12-go-select to 15-code-organization
+CREATE TABLE books (
+	ISBN   CHAR(14)      PRIMARY KEY NOT NULL,
+	TITLE  VARCHAR(255)  NOT NULL,
+	AUTHOR VARCHAR(255)  NOT NULL,
+	PRICE  DECIMAL(5, 2) NOT NULL
+);
@@ -0,0 +1,10 @@
+-- employees of 01-create-table, MANAGER_ID is used by the self join of 05-joins
+CREATE TABLE employees (
+	ID         INT PRIMARY KEY NOT NULL,
+	NAME       TEXT            NOT NULL,
+	RANK       INT             NOT NULL,
+	ADDRESS    CHAR(50),
+	SALARY     REAL            DEFAULT 25500.00,
+	BDAY       DATE            DEFAULT '1900-01-01',
+	MANAGER_ID INT             REFERENCES employees (ID)
+);
@@ -0,0 +1,2 @@
+DROP TABLE phonenumbers;
+DROP TABLE people;
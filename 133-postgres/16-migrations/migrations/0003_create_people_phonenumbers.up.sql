@@ -0,0 +1,13 @@
+-- people and phonenumbers of 04-relational-db, joined in 05-joins
+CREATE TABLE people (
+	ID     SERIAL PRIMARY KEY NOT NULL,
+	NAME   TEXT               NOT NULL,
+	SCORE  INT                DEFAULT 10 NOT NULL,
+	SALARY REAL               NOT NULL
+);
+
+CREATE TABLE phonenumbers (
+	ID        SERIAL PRIMARY KEY NOT NULL,
+	PHONE     CHAR(50)           NOT NULL,
+	PERSON_ID INT                REFERENCES people (ID) NOT NULL
+);
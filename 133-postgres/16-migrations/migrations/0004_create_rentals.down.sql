@@ -0,0 +1,3 @@
+DROP TABLE rentals;
+DROP TABLE movies;
+DROP TABLE customers;
@@ -0,0 +1,16 @@
+-- movie rentals of 06-transaction-example
+CREATE TABLE customers (
+	ID    SERIAL PRIMARY KEY NOT NULL,
+	FIRST CHAR(50)           NOT NULL
+);
+
+CREATE TABLE movies (
+	ID    SERIAL PRIMARY KEY NOT NULL,
+	MOVIE CHAR(50)           NOT NULL
+);
+
+CREATE TABLE rentals (
+	ID   SERIAL PRIMARY KEY NOT NULL,
+	C_ID INT                REFERENCES customers (ID) NOT NULL,
+	M_ID INT                REFERENCES movies (ID) NOT NULL
+);
@@ -0,0 +1,8 @@
+DROP INDEX rentals_open_movie;
+DROP INDEX rentals_open_customer;
+
+ALTER TABLE rentals
+	DROP COLUMN RETURNED_AT,
+	DROP COLUMN RENTED_AT;
+
+ALTER TABLE movies DROP COLUMN COPIES;
@@ -0,0 +1,10 @@
+-- copies of a movie and the rental period, used by the rental service of 06-transaction-example
+ALTER TABLE movies ADD COLUMN COPIES INT NOT NULL DEFAULT 3 CHECK (COPIES >= 0);
+
+ALTER TABLE rentals
+	ADD COLUMN RENTED_AT   TIMESTAMPTZ NOT NULL DEFAULT now(),
+	ADD COLUMN RETURNED_AT TIMESTAMPTZ;
+
+-- the open rentals of a customer and of a movie are counted on every checkout
+CREATE INDEX rentals_open_customer ON rentals (C_ID) WHERE RETURNED_AT IS NULL;
+CREATE INDEX rentals_open_movie ON rentals (M_ID) WHERE RETURNED_AT IS NULL;
@@ -0,0 +1,8 @@
+// Package migrations holds the schemas of the 133-postgres examples as numbered SQL files,
+// compiled into the binary so the tool runs from anywhere
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
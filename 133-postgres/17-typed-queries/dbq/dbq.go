@@ -0,0 +1,84 @@
+// Package dbq runs queries straight into typed values. Columns are matched to struct fields
+// by name instead of by position, so a SELECT * keeps working when a column is added.
+package dbq
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Querier is implemented by *sql.DB, *sql.Tx and *sql.Conn
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// QueryAll returns every row of the query, an empty slice when there is none.
+// T is a struct, its fields are filled from the columns of the same name, or a single value
+// like string or int64 for a query selecting one column.
+// args are positional, or a single P or Bind value for a query with :name parameters.
+func QueryAll[T any](ctx context.Context, q Querier, query string, args ...any) ([]T, error) {
+	rows, err := runQuery(ctx, q, query, args)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sc, err := newScanner[T](rows)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]T, 0)
+	for rows.Next() {
+		var v T
+		if err := sc.scan(rows, &v); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
+
+// QueryOne returns the first row of the query, sql.ErrNoRows when there is none
+func QueryOne[T any](ctx context.Context, q Querier, query string, args ...any) (T, error) {
+	var v T
+	rows, err := runQuery(ctx, q, query, args)
+	if err != nil {
+		return v, err
+	}
+	defer rows.Close()
+
+	sc, err := newScanner[T](rows)
+	if err != nil {
+		return v, err
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return v, err
+		}
+		return v, sql.ErrNoRows
+	}
+	if err := sc.scan(rows, &v); err != nil {
+		return v, err
+	}
+	return v, rows.Close()
+}
+
+// Exec runs a statement returning no rows, with the same arguments as QueryAll
+func Exec(ctx context.Context, q Querier, query string, args ...any) (sql.Result, error) {
+	query, args, err := bind(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return q.ExecContext(ctx, query, args...)
+}
+
+func runQuery(ctx context.Context, q Querier, query string, args []any) (*sql.Rows, error) {
+	query, args, err := bind(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return q.QueryContext(ctx, query, args...)
+}
@@ -0,0 +1,249 @@
+package dbq
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeDB answers every query with the same result set and remembers what it was sent
+type fakeDB struct {
+	columns []string
+	rows    [][]driver.Value
+
+	query string
+	args  []any
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.record(query, args)
+	return &fakeRows{db: c.db}, nil
+}
+
+func (c fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.record(query, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (c fakeConn) record(query string, args []driver.NamedValue) {
+	c.db.query, c.db.args = query, nil
+	for _, a := range args {
+		c.db.args = append(c.db.args, a.Value)
+	}
+}
+
+type fakeRows struct {
+	db *fakeDB
+	i  int
+}
+
+func (r *fakeRows) Columns() []string { return r.db.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.i == len(r.db.rows) {
+		return io.EOF
+	}
+	copy(dest, r.db.rows[r.i])
+	r.i++
+	return nil
+}
+
+func open(t *testing.T, f *fakeDB) *sql.DB {
+	db := sql.OpenDB(f)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+type Audit struct {
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type Book struct {
+	Isbn     string
+	Title    string
+	Author   string  `db:"author_name"`
+	Price    float32 `db:"price"`
+	Subtitle *string
+	Note     string `db:"-"`
+	Audit
+}
+
+func TestQueryAll(t *testing.T) {
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	f := &fakeDB{
+		// Another order than the fields and a column the struct doesn't know
+		columns: []string{"PRICE", "isbn", "stock", "author_name", "title", "subtitle", "created_at"},
+		rows: [][]driver.Value{
+			{9.99, "978-0441172719", int64(3), "Frank Herbert", "Dune", nil, created},
+			{5.9, "978-1470184841", int64(0), "Franz Kafka", "Metamorphosis", "a novella", created},
+		},
+	}
+
+	bks, err := QueryAll[Book](context.Background(), open(t, f), "SELECT * FROM books")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subtitle := "a novella"
+	want := []Book{
+		{Isbn: "978-0441172719", Title: "Dune", Author: "Frank Herbert", Price: 9.99, Audit: Audit{created}},
+		{Isbn: "978-1470184841", Title: "Metamorphosis", Author: "Franz Kafka", Price: 5.9, Subtitle: &subtitle, Audit: Audit{created}},
+	}
+	if !reflect.DeepEqual(bks, want) {
+		t.Errorf("got %+v, want %+v", bks, want)
+	}
+}
+
+func TestQueryAllEmpty(t *testing.T) {
+	f := &fakeDB{columns: []string{"isbn"}}
+
+	bks, err := QueryAll[Book](context.Background(), open(t, f), "SELECT isbn FROM books")
+	if err != nil || bks == nil || len(bks) != 0 {
+		t.Errorf("got %v and %v, want an empty slice", bks, err)
+	}
+}
+
+func TestQueryAllSingleColumn(t *testing.T) {
+	f := &fakeDB{columns: []string{"title"}, rows: [][]driver.Value{{"Dune"}, {"Metamorphosis"}}}
+
+	titles, err := QueryAll[string](context.Background(), open(t, f), "SELECT title FROM books")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Dune", "Metamorphosis"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("got %v, want %v", titles, want)
+	}
+
+	f.columns = []string{"isbn", "title"}
+	if _, err := QueryAll[string](context.Background(), open(t, f), "SELECT isbn, title FROM books"); err == nil {
+		t.Error("got no error scanning two columns into a string")
+	}
+}
+
+func TestQueryAllNoMatchingColumn(t *testing.T) {
+	f := &fakeDB{columns: []string{"id", "movie"}}
+
+	if _, err := QueryAll[Book](context.Background(), open(t, f), "SELECT * FROM movies"); err == nil {
+		t.Error("got no error for a query filling no field")
+	}
+}
+
+func TestQueryOne(t *testing.T) {
+	f := &fakeDB{columns: []string{"isbn", "title"}, rows: [][]driver.Value{{"978-0441172719", "Dune"}}}
+
+	bk, err := QueryOne[Book](context.Background(), open(t, f), "SELECT isbn, title FROM books WHERE isbn = $1", "978-0441172719")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bk.Title != "Dune" {
+		t.Errorf("got %+v, want Dune", bk)
+	}
+
+	f.rows = nil
+	if _, err := QueryOne[Book](context.Background(), open(t, f), "SELECT isbn, title FROM books WHERE isbn = $1", "none"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("got %v, want %v", err, sql.ErrNoRows)
+	}
+}
+
+func TestPlanIsCached(t *testing.T) {
+	p1, err := planFor(reflect.TypeFor[Book]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, _ := planFor(reflect.TypeFor[Book]())
+	if p1 != p2 {
+		t.Error("got a new plan for the same type")
+	}
+}
+
+func TestPlanRejectsDuplicateColumns(t *testing.T) {
+	type dup struct {
+		Title string
+		Name  string `db:"title"`
+	}
+	if _, err := planFor(reflect.TypeFor[dup]()); err == nil {
+		t.Error("got no error for two fields of one column")
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+		names []string
+	}{
+		{
+			"SELECT * FROM books WHERE isbn = :isbn",
+			"SELECT * FROM books WHERE isbn = $1",
+			[]string{"isbn"},
+		},
+		{
+			"SELECT * FROM books WHERE price > :min AND price < :max OR price = :min",
+			"SELECT * FROM books WHERE price > $1 AND price < $2 OR price = $1",
+			[]string{"min", "max"},
+		},
+		{
+			"SELECT ':not', \"a:b\", price::text, '2024-01-01 10:00'::timestamp FROM books -- :comment\nWHERE id = :id /* :block */",
+			"SELECT ':not', \"a:b\", price::text, '2024-01-01 10:00'::timestamp FROM books -- :comment\nWHERE id = $1 /* :block */",
+			[]string{"id"},
+		},
+		{
+			"SELECT 'unterminated :x",
+			"SELECT 'unterminated :x",
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		got := rewrite(tt.query)
+		if got.query != tt.want || !reflect.DeepEqual(got.names, tt.names) {
+			t.Errorf("rewrite(%q)\ngot  %q %v\nwant %q %v", tt.query, got.query, got.names, tt.want, tt.names)
+		}
+	}
+}
+
+func TestNamedParams(t *testing.T) {
+	f := &fakeDB{}
+	db := open(t, f)
+	ctx := context.Background()
+
+	_, err := Exec(ctx, db, "UPDATE books SET price = :price WHERE isbn = :isbn", P{"isbn": "978-0441172719", "price": 8.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "UPDATE books SET price = $1 WHERE isbn = $2"; f.query != want {
+		t.Errorf("got %q, want %q", f.query, want)
+	}
+	if want := []any{8.5, "978-0441172719"}; !reflect.DeepEqual(f.args, want) {
+		t.Errorf("got %v, want %v", f.args, want)
+	}
+
+	bk := Book{Isbn: "978-0441172719", Title: "Dune", Author: "Frank Herbert", Price: 9.99}
+	_, err = Exec(ctx, db, "INSERT INTO books (isbn, title, author, price) VALUES (:isbn, :title, :author_name, :price)", Bind(&bk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []any{"978-0441172719", "Dune", "Frank Herbert", float64(float32(9.99))}; !reflect.DeepEqual(f.args, want) {
+		t.Errorf("got %v, want %v", f.args, want)
+	}
+
+	_, err = Exec(ctx, db, "DELETE FROM books WHERE isbn = :isbn", P{"id": 1})
+	if !errors.Is(err, ErrMissingParam) {
+		t.Errorf("got %v, want %v", err, ErrMissingParam)
+	}
+}
@@ -0,0 +1,3 @@
+module go/go-examples/133-postgres/17-typed-queries/dbq
+
+go 1.23.0
@@ -0,0 +1,166 @@
+package dbq
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var ErrMissingParam = errors.New("dbq: no value for parameter")
+
+// P holds the values of the :name parameters of a query
+type P map[string]any
+
+// Bind takes the values of the :name parameters from the fields of a struct, by the same
+// names QueryAll uses for columns, e.g. INSERT INTO books (isbn, title) VALUES (:isbn, :title)
+func Bind(v any) any {
+	return bound{v: v}
+}
+
+type bound struct {
+	v any
+}
+
+type params interface {
+	lookup(name string) (any, bool, error)
+}
+
+func (p P) lookup(name string) (any, bool, error) {
+	v, ok := p[name]
+	return v, ok, nil
+}
+
+func (b bound) lookup(name string) (any, bool, error) {
+	rv := reflect.ValueOf(b.v)
+	for rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, false, fmt.Errorf("dbq: Bind needs a struct, got %T", b.v)
+	}
+
+	p, err := planFor(rv.Type())
+	if err != nil {
+		return nil, false, err
+	}
+	path, ok := p.fields[strings.ToLower(name)]
+	if !ok {
+		return nil, false, nil
+	}
+	return rv.FieldByIndex(path).Interface(), true, nil
+}
+
+// named is a query rewritten to $n placeholders and the parameter name of each of them
+type named struct {
+	query string
+	names []string
+}
+
+var rewrites sync.Map // query -> named
+
+// bind turns a query with :name parameters and a single P or Bind argument into
+// a query with $n placeholders and positional arguments. Other arguments pass through.
+func bind(query string, args []any) (string, []any, error) {
+	if len(args) != 1 {
+		return query, args, nil
+	}
+	ps, ok := args[0].(params)
+	if !ok {
+		return query, args, nil
+	}
+
+	var n named
+	if cached, ok := rewrites.Load(query); ok {
+		n = cached.(named)
+	} else {
+		n = rewrite(query)
+		rewrites.Store(query, n)
+	}
+
+	out := make([]any, len(n.names))
+	for i, name := range n.names {
+		v, ok, err := ps.lookup(name)
+		if err != nil {
+			return "", nil, err
+		}
+		if !ok {
+			return "", nil, fmt.Errorf("%w :%s", ErrMissingParam, name)
+		}
+		out[i] = v
+	}
+	return n.query, out, nil
+}
+
+// rewrite replaces :name with $n, the same name reusing its number. It leaves alone what
+// only looks like a parameter: string literals, quoted identifiers, comments and :: casts.
+func rewrite(query string) named {
+	var sb strings.Builder
+	var names []string
+	numbers := map[string]int{}
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(query[i+1:], c)
+			if end < 0 {
+				end = len(query) - i - 2 // unterminated, the database will complain
+			}
+			sb.WriteString(query[i : i+end+2])
+			i += end + 2
+			continue
+
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			sb.WriteString(query[i : i+end])
+			i += end
+			continue
+
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i - 4
+			}
+			sb.WriteString(query[i : i+end+4])
+			i += end + 4
+			continue
+
+		case strings.HasPrefix(query[i:], "::"):
+			sb.WriteString("::")
+			i += 2
+			continue
+
+		case c == ':' && i+1 < len(query) && isNameStart(query[i+1]):
+			j := i + 1
+			for j < len(query) && isNamePart(query[j]) {
+				j++
+			}
+			name := query[i+1 : j]
+			if _, ok := numbers[name]; !ok {
+				names = append(names, name)
+				numbers[name] = len(names)
+			}
+			sb.WriteString("$" + strconv.Itoa(numbers[name]))
+			i = j
+			continue
+		}
+
+		sb.WriteByte(c)
+		i++
+	}
+	return named{query: sb.String(), names: names}
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isNamePart(c byte) bool {
+	return isNameStart(c) || '0' <= c && c <= '9'
+}
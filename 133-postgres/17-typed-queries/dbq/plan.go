@@ -0,0 +1,129 @@
+package dbq
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// plan maps the column names of a struct type to the index paths of its fields.
+// Building it walks the type with reflect, it is done once per type and cached.
+type plan struct {
+	fields map[string][]int
+}
+
+var plans sync.Map // reflect.Type -> *plan
+
+// planFor returns the cached plan of t. A field's column is its db tag, or its name in lower
+// case like Postgres folds unquoted identifiers. db:"-" skips a field, embedded structs are flattened.
+func planFor(t reflect.Type) (*plan, error) {
+	if p, ok := plans.Load(t); ok {
+		return p.(*plan), nil
+	}
+
+	p := &plan{fields: map[string][]int{}}
+	if err := p.add(t, nil); err != nil {
+		return nil, err
+	}
+	actual, _ := plans.LoadOrStore(t, p)
+	return actual.(*plan), nil
+}
+
+func (p *plan) add(t reflect.Type, index []int) error {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag, _, _ := strings.Cut(f.Tag.Get("db"), ",")
+		if tag == "-" || !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		path := append(append([]int{}, index...), i)
+
+		if f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct && !isValue(f.Type) {
+			if err := p.add(f.Type, path); err != nil {
+				return err
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		col := tag
+		if col == "" {
+			col = strings.ToLower(f.Name)
+		}
+		if _, dup := p.fields[col]; dup {
+			return fmt.Errorf("dbq: %s has two fields for column %q", t, col)
+		}
+		p.fields[col] = path
+	}
+	return nil
+}
+
+// isValue reports whether t is scanned as a whole rather than field by field
+func isValue(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return true
+	}
+	return t == reflect.TypeFor[time.Time]() || reflect.PointerTo(t).Implements(reflect.TypeFor[sql.Scanner]())
+}
+
+// scanner binds a plan to the columns of one result set
+type scanner[T any] struct {
+	columns [][]int // field index path per column, nil for a column without a field
+	single  bool
+}
+
+func newScanner[T any](rows *sql.Rows) (*scanner[T], error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	t := reflect.TypeFor[T]()
+	if isValue(t) {
+		if len(cols) != 1 {
+			return nil, fmt.Errorf("dbq: scanning into %s needs one column, the query returns %d", t, len(cols))
+		}
+		return &scanner[T]{single: true}, nil
+	}
+
+	p, err := planFor(t)
+	if err != nil {
+		return nil, err
+	}
+
+	sc := &scanner[T]{columns: make([][]int, len(cols))}
+	matched := 0
+	for i, col := range cols {
+		if path, ok := p.fields[strings.ToLower(col)]; ok {
+			sc.columns[i] = path
+			matched++
+		}
+	}
+	// Extra columns are skipped, but a query filling nothing is a mistake
+	if matched == 0 {
+		return nil, fmt.Errorf("dbq: no column of %v matches a field of %s", cols, t)
+	}
+	return sc, nil
+}
+
+func (sc *scanner[T]) scan(rows *sql.Rows, v *T) error {
+	if sc.single {
+		return rows.Scan(v)
+	}
+
+	rv := reflect.ValueOf(v).Elem()
+	dest := make([]any, len(sc.columns))
+	for i, path := range sc.columns {
+		if path == nil {
+			dest[i] = new(any)
+			continue
+		}
+		dest[i] = rv.FieldByIndex(path).Addr().Interface()
+	}
+	return rows.Scan(dest...)
+}
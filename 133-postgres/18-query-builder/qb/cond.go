@@ -0,0 +1,172 @@
+package qb
+
+import "strings"
+
+// Cond is a boolean expression of a WHERE clause
+type Cond interface {
+	build(b *builder)
+}
+
+type compare struct {
+	col, op string
+	v       any
+}
+
+func (c compare) build(b *builder) {
+	b.write(c.col, " ", c.op, " ")
+	b.arg(c.v)
+}
+
+func Eq(col string, v any) Cond    { return compare{col, "=", v} }
+func NotEq(col string, v any) Cond { return compare{col, "<>", v} }
+func Lt(col string, v any) Cond    { return compare{col, "<", v} }
+func Lte(col string, v any) Cond   { return compare{col, "<=", v} }
+func Gt(col string, v any) Cond    { return compare{col, ">", v} }
+func Gte(col string, v any) Cond   { return compare{col, ">=", v} }
+func Like(col string, v any) Cond  { return compare{col, "LIKE", v} }
+func ILike(col string, v any) Cond { return compare{col, "ILIKE", v} }
+
+type in struct {
+	col string
+	vs  []any
+}
+
+// In is false for an empty list, like no row matching, rather than invalid SQL
+func In[T any](col string, vs ...T) Cond {
+	c := in{col: col}
+	for _, v := range vs {
+		c.vs = append(c.vs, v)
+	}
+	return c
+}
+
+func (c in) build(b *builder) {
+	if len(c.vs) == 0 {
+		b.write("FALSE")
+		return
+	}
+	b.write(c.col, " IN (")
+	for i, v := range c.vs {
+		if i > 0 {
+			b.write(", ")
+		}
+		b.arg(v)
+	}
+	b.write(")")
+}
+
+type between struct {
+	col    string
+	lo, hi any
+}
+
+func Between(col string, lo, hi any) Cond { return between{col, lo, hi} }
+
+func (c between) build(b *builder) {
+	b.write(c.col, " BETWEEN ")
+	b.arg(c.lo)
+	b.write(" AND ")
+	b.arg(c.hi)
+}
+
+type null struct {
+	col string
+	not bool
+}
+
+func IsNull(col string) Cond    { return null{col, false} }
+func IsNotNull(col string) Cond { return null{col, true} }
+
+func (c null) build(b *builder) {
+	if c.not {
+		b.write(c.col, " IS NOT NULL")
+	} else {
+		b.write(c.col, " IS NULL")
+	}
+}
+
+type colEq struct {
+	left, right string
+}
+
+// ColEq compares two columns, e.g. the ON condition of a join
+func ColEq(left, right string) Cond { return colEq{left, right} }
+
+func (c colEq) build(b *builder) {
+	b.write(c.left, " = ", c.right)
+}
+
+type group struct {
+	op    string
+	conds []Cond
+}
+
+// And is true without conditions, Or false, so an empty filter matches everything or nothing
+func And(conds ...Cond) Cond { return group{"AND", conds} }
+func Or(conds ...Cond) Cond  { return group{"OR", conds} }
+
+func (g group) build(b *builder) {
+	switch len(g.conds) {
+	case 0:
+		b.write(map[string]string{"AND": "TRUE", "OR": "FALSE"}[g.op])
+		return
+	case 1:
+		g.conds[0].build(b)
+		return
+	}
+
+	for i, c := range g.conds {
+		if i > 0 {
+			b.write(" ", g.op, " ")
+		}
+		// A nested group keeps its own precedence, so does an Expr that may hold an OR
+		switch c.(type) {
+		case group, expr:
+			b.write("(")
+			c.build(b)
+			b.write(")")
+		default:
+			c.build(b)
+		}
+	}
+}
+
+type not struct {
+	c Cond
+}
+
+func Not(c Cond) Cond { return not{c} }
+
+func (n not) build(b *builder) {
+	b.write("NOT (")
+	n.c.build(b)
+	b.write(")")
+}
+
+type expr struct {
+	sql  string
+	args []any
+}
+
+// Expr is SQL written by hand with ? for each argument, numbered with the rest of the statement.
+// Next to other conditions it is put in parentheses.
+func Expr(sql string, args ...any) Cond { return expr{sql, args} }
+
+func (e expr) build(b *builder) {
+	rest, i := e.sql, 0
+	for {
+		before, after, found := strings.Cut(rest, "?")
+		b.write(before)
+		if !found {
+			break
+		}
+		if i < len(e.args) {
+			b.arg(e.args[i])
+		}
+		i++
+		rest = after
+	}
+	if i != len(e.args) {
+		b.fail("%q has %d placeholders for %d arguments", e.sql, i, len(e.args))
+	}
+}
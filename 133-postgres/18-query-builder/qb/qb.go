@@ -0,0 +1,69 @@
+// Package qb builds Postgres statements. Values always become $n placeholders with their
+// arguments returned next to the SQL, only table and column names are written as given,
+// so they must come from the code and never from user input.
+package qb
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalid = errors.New("qb: invalid statement")
+
+// builder accumulates the SQL text and numbers the placeholders in order of appearance
+type builder struct {
+	sb   strings.Builder
+	args []any
+	err  error
+}
+
+// fail keeps the first mistake found while writing, Build returns it
+func (b *builder) fail(format string, args ...any) {
+	if b.err == nil {
+		b.err = fmt.Errorf("%w: "+format, append([]any{ErrInvalid}, args...)...)
+	}
+}
+
+func (b *builder) result() (string, []any, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	return b.sb.String(), b.args, nil
+}
+
+func (b *builder) write(s ...string) {
+	for _, p := range s {
+		b.sb.WriteString(p)
+	}
+}
+
+func (b *builder) arg(v any) {
+	b.args = append(b.args, v)
+	b.write("$", strconv.Itoa(len(b.args)))
+}
+
+func (b *builder) list(items []string) {
+	b.write(strings.Join(items, ", "))
+}
+
+func (b *builder) where(conds []Cond) {
+	if len(conds) > 0 {
+		b.write(" WHERE ")
+		And(conds...).build(b)
+	}
+}
+
+func (b *builder) returning(cols []string) {
+	if len(cols) > 0 {
+		b.write(" RETURNING ")
+		b.list(cols)
+	}
+}
+
+// with appends to a copy, a statement derived from another never changes it
+func with[T any](s []T, v ...T) []T {
+	return append(slices.Clip(s), v...)
+}
@@ -0,0 +1,137 @@
+package qb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type built interface {
+	Build() (string, []any, error)
+}
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name string
+		q    built
+		sql  string
+		args []any
+	}{
+		{
+			"select all",
+			Select().From("books"),
+			"SELECT * FROM books",
+			nil,
+		},
+		{
+			"and with or group",
+			Select("isbn", "title").From("books").
+				Where(Gte("price", 5), Or(ILike("title", "%go%"), Eq("author", "Rob Pike"))).
+				OrderBy("price DESC", "title").Limit(10).Offset(20),
+			"SELECT isbn, title FROM books WHERE price >= $1 AND (title ILIKE $2 OR author = $3) ORDER BY price DESC, title LIMIT $4 OFFSET $5",
+			[]any{5, "%go%", "Rob Pike", 10, 20},
+		},
+		{
+			"where calls are anded",
+			Select("id").From("rentals").Where(IsNull("returned_at")).Where(In("c_id", 1, 2, 3)),
+			"SELECT id FROM rentals WHERE returned_at IS NULL AND c_id IN ($1, $2, $3)",
+			[]any{1, 2, 3},
+		},
+		{
+			"empty in, not, between, expr",
+			Select("id").From("movies").Where(In[int]("id"), Not(Between("copies", 1, 3)), Expr("lower(movie) = ?", "jaws")),
+			"SELECT id FROM movies WHERE FALSE AND NOT (copies BETWEEN $1 AND $2) AND (lower(movie) = $3)",
+			[]any{1, 3, "jaws"},
+		},
+		{
+			"expr with or keeps its precedence",
+			Select().From("books").Where(Eq("author", "a"), Expr("price < ? OR price > ?", 1, 9)),
+			"SELECT * FROM books WHERE author = $1 AND (price < $2 OR price > $3)",
+			[]any{"a", 1, 9},
+		},
+		{
+			"expr alone",
+			Select().From("books").Where(Expr("price < ? OR price > ?", 1, 9)),
+			"SELECT * FROM books WHERE price < $1 OR price > $2",
+			[]any{1, 9},
+		},
+		{
+			"group by having",
+			Select("c_id", "count(*)").From("rentals").GroupBy("c_id").Having(Expr("count(*) > ?", 1)),
+			"SELECT c_id, count(*) FROM rentals GROUP BY c_id HAVING count(*) > $1",
+			[]any{1},
+		},
+		{
+			"insert rows",
+			Insert("books").Columns("isbn", "title").Values("1", "Dune").Values("2", "Jaws").
+				OnConflict("(isbn) DO NOTHING").Returning("isbn"),
+			"INSERT INTO books (isbn, title) VALUES ($1, $2), ($3, $4) ON CONFLICT (isbn) DO NOTHING RETURNING isbn",
+			[]any{"1", "Dune", "2", "Jaws"},
+		},
+		{
+			"update",
+			Update("books").Set("price", 9.5).Set("title", "Dune").Where(Eq("isbn", "1")).Returning("isbn", "price"),
+			"UPDATE books SET price = $1, title = $2 WHERE isbn = $3 RETURNING isbn, price",
+			[]any{9.5, "Dune", "1"},
+		},
+		{
+			"delete every row on purpose",
+			Delete("rentals").Where(And()),
+			"DELETE FROM rentals WHERE TRUE",
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := tt.q.Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sql != tt.sql {
+				t.Errorf("got\n%s\nwant\n%s", sql, tt.sql)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("got args %v, want %v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestBuildRejectsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		q    built
+	}{
+		{"select without table", Select("id")},
+		{"insert without values", Insert("books").Columns("isbn")},
+		{"insert with a short row", Insert("books").Columns("isbn", "title").Values("1")},
+		{"update without set", Update("books").Where(Eq("isbn", "1"))},
+		{"update without where", Update("books").Set("price", 1)},
+		{"delete without where", Delete("books")},
+		{"expr missing an argument", Select().From("books").Where(Expr("price > ? AND price < ?", 1))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := tt.q.Build(); !errors.Is(err, ErrInvalid) {
+				t.Errorf("got %v, want %v", err, ErrInvalid)
+			}
+		})
+	}
+}
+
+func TestDerivedQueriesAreIndependent(t *testing.T) {
+	base := Select("isbn").From("books").Where(Gt("price", 1))
+	cheap := base.Where(Lt("price", 10))
+	dear := base.Where(Gte("price", 10))
+
+	sql, _, _ := cheap.Build()
+	if want := "SELECT isbn FROM books WHERE price > $1 AND price < $2"; sql != want {
+		t.Errorf("got %s, want %s", sql, want)
+	}
+	sql, _, _ = dear.Build()
+	if want := "SELECT isbn FROM books WHERE price > $1 AND price >= $2"; sql != want {
+		t.Errorf("got %s, want %s", sql, want)
+	}
+}
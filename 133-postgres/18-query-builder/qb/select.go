@@ -0,0 +1,152 @@
+package qb
+
+type join struct {
+	kind, table string
+	on          Cond
+}
+
+// SelectQuery is a SELECT statement. Its methods return a modified copy, so a base query
+// can be shared and refined, e.g. with the filters of a request.
+type SelectQuery struct {
+	distinct bool
+	cols     []string
+	from     string
+	joins    []join
+	where    []Cond
+	groupBy  []string
+	having   []Cond
+	orderBy  []string
+	limit    int
+	offset   int
+}
+
+// Select starts a query of the given columns, none selects *
+func Select(cols ...string) SelectQuery {
+	return SelectQuery{cols: cols}
+}
+
+func (q SelectQuery) Distinct() SelectQuery {
+	q.distinct = true
+	return q
+}
+
+// From takes a table with an optional alias, e.g. "people p"
+func (q SelectQuery) From(table string) SelectQuery {
+	q.from = table
+	return q
+}
+
+func (q SelectQuery) Join(table string, on Cond) SelectQuery {
+	return q.addJoin("INNER JOIN", table, on)
+}
+
+func (q SelectQuery) LeftJoin(table string, on Cond) SelectQuery {
+	return q.addJoin("LEFT JOIN", table, on)
+}
+
+func (q SelectQuery) RightJoin(table string, on Cond) SelectQuery {
+	return q.addJoin("RIGHT JOIN", table, on)
+}
+
+func (q SelectQuery) FullJoin(table string, on Cond) SelectQuery {
+	return q.addJoin("FULL OUTER JOIN", table, on)
+}
+
+func (q SelectQuery) CrossJoin(table string) SelectQuery {
+	return q.addJoin("CROSS JOIN", table, nil)
+}
+
+func (q SelectQuery) NaturalJoin(table string) SelectQuery {
+	return q.addJoin("NATURAL JOIN", table, nil)
+}
+
+func (q SelectQuery) addJoin(kind, table string, on Cond) SelectQuery {
+	q.joins = with(q.joins, join{kind, table, on})
+	return q
+}
+
+// Where adds conditions, every call is ANDed with the previous ones
+func (q SelectQuery) Where(conds ...Cond) SelectQuery {
+	q.where = with(q.where, conds...)
+	return q
+}
+
+func (q SelectQuery) GroupBy(cols ...string) SelectQuery {
+	q.groupBy = with(q.groupBy, cols...)
+	return q
+}
+
+func (q SelectQuery) Having(conds ...Cond) SelectQuery {
+	q.having = with(q.having, conds...)
+	return q
+}
+
+// OrderBy takes columns with an optional direction, e.g. "NAME DESC"
+func (q SelectQuery) OrderBy(cols ...string) SelectQuery {
+	q.orderBy = with(q.orderBy, cols...)
+	return q
+}
+
+// Limit of 0 means no limit
+func (q SelectQuery) Limit(n int) SelectQuery {
+	q.limit = n
+	return q
+}
+
+func (q SelectQuery) Offset(n int) SelectQuery {
+	q.offset = n
+	return q
+}
+
+func (q SelectQuery) Build() (string, []any, error) {
+	b := &builder{}
+	if q.from == "" {
+		b.fail("select without a table")
+	}
+	if q.limit < 0 || q.offset < 0 {
+		b.fail("negative limit %d or offset %d", q.limit, q.offset)
+	}
+
+	b.write("SELECT ")
+	if q.distinct {
+		b.write("DISTINCT ")
+	}
+	if len(q.cols) == 0 {
+		b.write("*")
+	} else {
+		b.list(q.cols)
+	}
+	b.write(" FROM ", q.from)
+
+	for _, j := range q.joins {
+		b.write(" ", j.kind, " ", j.table)
+		if j.on != nil {
+			b.write(" ON ")
+			j.on.build(b)
+		}
+	}
+
+	b.where(q.where)
+	if len(q.groupBy) > 0 {
+		b.write(" GROUP BY ")
+		b.list(q.groupBy)
+	}
+	if len(q.having) > 0 {
+		b.write(" HAVING ")
+		And(q.having...).build(b)
+	}
+	if len(q.orderBy) > 0 {
+		b.write(" ORDER BY ")
+		b.list(q.orderBy)
+	}
+	// Placeholders too, a prepared statement can then be reused across pages
+	if q.limit > 0 {
+		b.write(" LIMIT ")
+		b.arg(q.limit)
+	}
+	if q.offset > 0 {
+		b.write(" OFFSET ")
+		b.arg(q.offset)
+	}
+	return b.result()
+}
@@ -0,0 +1,158 @@
+package qb
+
+// InsertQuery is an INSERT of one or more rows
+type InsertQuery struct {
+	table      string
+	cols       []string
+	rows       [][]any
+	conflict   string
+	returnCols []string
+}
+
+func Insert(table string) InsertQuery {
+	return InsertQuery{table: table}
+}
+
+func (q InsertQuery) Columns(cols ...string) InsertQuery {
+	q.cols = with(q.cols, cols...)
+	return q
+}
+
+// Values adds a row, one value per column
+func (q InsertQuery) Values(vs ...any) InsertQuery {
+	q.rows = with(q.rows, vs)
+	return q
+}
+
+// OnConflict is the action written after ON CONFLICT, e.g. "(isbn) DO NOTHING"
+func (q InsertQuery) OnConflict(action string) InsertQuery {
+	q.conflict = action
+	return q
+}
+
+func (q InsertQuery) Returning(cols ...string) InsertQuery {
+	q.returnCols = with(q.returnCols, cols...)
+	return q
+}
+
+func (q InsertQuery) Build() (string, []any, error) {
+	b := &builder{}
+	if len(q.cols) == 0 || len(q.rows) == 0 {
+		b.fail("insert into %s needs columns and values", q.table)
+	}
+
+	b.write("INSERT INTO ", q.table, " (")
+	b.list(q.cols)
+	b.write(") VALUES ")
+	for i, row := range q.rows {
+		if len(row) != len(q.cols) {
+			b.fail("row %d has %d values for %d columns", i+1, len(row), len(q.cols))
+		}
+		if i > 0 {
+			b.write(", ")
+		}
+		b.write("(")
+		for j, v := range row {
+			if j > 0 {
+				b.write(", ")
+			}
+			b.arg(v)
+		}
+		b.write(")")
+	}
+
+	if q.conflict != "" {
+		b.write(" ON CONFLICT ", q.conflict)
+	}
+	b.returning(q.returnCols)
+	return b.result()
+}
+
+type assignment struct {
+	col string
+	v   any
+}
+
+// UpdateQuery is an UPDATE of the rows matching its conditions
+type UpdateQuery struct {
+	table      string
+	sets       []assignment
+	where      []Cond
+	returnCols []string
+}
+
+func Update(table string) UpdateQuery {
+	return UpdateQuery{table: table}
+}
+
+func (q UpdateQuery) Set(col string, v any) UpdateQuery {
+	q.sets = with(q.sets, assignment{col, v})
+	return q
+}
+
+func (q UpdateQuery) Where(conds ...Cond) UpdateQuery {
+	q.where = with(q.where, conds...)
+	return q
+}
+
+func (q UpdateQuery) Returning(cols ...string) UpdateQuery {
+	q.returnCols = with(q.returnCols, cols...)
+	return q
+}
+
+// Build refuses an update without conditions, use Where(And()) to really update every row
+func (q UpdateQuery) Build() (string, []any, error) {
+	b := &builder{}
+	if len(q.sets) == 0 {
+		b.fail("update of %s sets nothing", q.table)
+	}
+	if q.where == nil {
+		b.fail("update of %s has no where clause", q.table)
+	}
+
+	b.write("UPDATE ", q.table, " SET ")
+	for i, s := range q.sets {
+		if i > 0 {
+			b.write(", ")
+		}
+		b.write(s.col, " = ")
+		b.arg(s.v)
+	}
+	b.where(q.where)
+	b.returning(q.returnCols)
+	return b.result()
+}
+
+// DeleteQuery is a DELETE of the rows matching its conditions
+type DeleteQuery struct {
+	table      string
+	where      []Cond
+	returnCols []string
+}
+
+func Delete(table string) DeleteQuery {
+	return DeleteQuery{table: table}
+}
+
+func (q DeleteQuery) Where(conds ...Cond) DeleteQuery {
+	q.where = with(q.where, conds...)
+	return q
+}
+
+func (q DeleteQuery) Returning(cols ...string) DeleteQuery {
+	q.returnCols = with(q.returnCols, cols...)
+	return q
+}
+
+// Build refuses a delete without conditions, use Where(And()) to really delete every row
+func (q DeleteQuery) Build() (string, []any, error) {
+	b := &builder{}
+	if q.where == nil {
+		b.fail("delete from %s has no where clause", q.table)
+	}
+
+	b.write("DELETE FROM ", q.table)
+	b.where(q.where)
+	b.returning(q.returnCols)
+	return b.result()
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"go/go-examples/133-postgres/17-typed-queries/dbq"
+	"go/go-examples/133-postgres/19-instrumented-db/sqldb"
+	"log"
+	"net/http"
+
+	_ "github.com/lib/pq"
+)
+
+type Book struct {
+	Isbn   string  `db:"isbn"`
+	Title  string  `db:"title"`
+	Author string  `db:"author"`
+	Price  float32 `db:"price"`
+}
+
+func main() {
+	cfg, err := sqldb.ConfigFromEnv()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	db, err := sqldb.Open(context.Background(), cfg)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer db.Close()
+
+	http.HandleFunc("/books", func(w http.ResponseWriter, r *http.Request) {
+		// * sqldb.DB is a dbq.Querier, the query is prepared once and measured on every call
+		books, err := dbq.QueryAll[Book](r.Context(), db, "SELECT * FROM books")
+		if err != nil {
+			log.Println(err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		for _, book := range books {
+			fmt.Fprintf(w, "%s, %s, %s, $%.2f\r\n", book.Isbn, book.Title, book.Author, book.Price)
+		}
+	})
+	http.Handle("/metrics", db.MetricsHandler())
+
+	if err := http.ListenAndServe(":8080", nil); err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,134 @@
+package sqldb
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// buckets are the upper bounds of the latency histograms, in seconds
+var buckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative, the last one is +Inf
+	sum    float64
+	errors uint64
+}
+
+func (h *histogram) observe(d time.Duration, failed bool) {
+	s := d.Seconds()
+	i, _ := slices.BinarySearch(buckets, s)
+	h.counts[i]++
+	h.sum += s
+	if failed {
+		h.errors++
+	}
+}
+
+// metrics holds a latency histogram per query text. Queries are written in the code with
+// placeholders, so the number of distinct texts stays small.
+type metrics struct {
+	mu      sync.Mutex
+	queries map[string]*histogram
+}
+
+func newMetrics() *metrics {
+	return &metrics{queries: map[string]*histogram{}}
+}
+
+func (m *metrics) observe(query string, d time.Duration, failed bool) {
+	query = normalize(query)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, ok := m.queries[query]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(buckets)+1)}
+		m.queries[query] = h
+	}
+	h.observe(d, failed)
+}
+
+// MetricsHandler serves the pool stats of sql.DBStats, the statement cache and the query
+// latencies in the Prometheus text format, ready to be scraped
+func (db *DB) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		db.WriteMetrics(w)
+	})
+}
+
+func (db *DB) WriteMetrics(w io.Writer) {
+	st := db.Stats()
+	gauge(w, "sql_max_open_connections", "Maximum number of open connections.", st.MaxOpenConnections)
+	gauge(w, "sql_open_connections", "Established connections, in use and idle.", st.OpenConnections)
+	gauge(w, "sql_in_use_connections", "Connections currently in use.", st.InUse)
+	gauge(w, "sql_idle_connections", "Idle connections.", st.Idle)
+	counter(w, "sql_wait_count_total", "Connections waited for.", st.WaitCount)
+	counter(w, "sql_wait_duration_seconds_total", "Time blocked waiting for a connection.", st.WaitDuration.Seconds())
+	counter(w, "sql_max_idle_closed_total", "Connections closed due to SetMaxIdleConns.", st.MaxIdleClosed)
+	counter(w, "sql_max_idle_time_closed_total", "Connections closed due to SetConnMaxIdleTime.", st.MaxIdleTimeClosed)
+	counter(w, "sql_max_lifetime_closed_total", "Connections closed due to SetConnMaxLifetime.", st.MaxLifetimeClosed)
+
+	size, hits, misses := db.stmts.stats()
+	gauge(w, "sql_stmt_cache_size", "Prepared statements in the cache.", size)
+	counter(w, "sql_stmt_cache_hits_total", "Queries run with a cached statement.", hits)
+	counter(w, "sql_stmt_cache_misses_total", "Queries that prepared a statement.", misses)
+
+	db.metrics.mu.Lock()
+	defer db.metrics.mu.Unlock()
+
+	queries := make([]string, 0, len(db.metrics.queries))
+	for q := range db.metrics.queries {
+		queries = append(queries, q)
+	}
+	slices.Sort(queries)
+
+	fmt.Fprintln(w, "# HELP sql_query_duration_seconds Latency of the queries, until the first row for a query returning rows.")
+	fmt.Fprintln(w, "# TYPE sql_query_duration_seconds histogram")
+	for _, q := range queries {
+		h, label := db.metrics.queries[q], escape(q)
+		var total uint64
+		for i, n := range h.counts {
+			total += n
+			le := "+Inf"
+			if i < len(buckets) {
+				le = fmt.Sprint(buckets[i])
+			}
+			fmt.Fprintf(w, "sql_query_duration_seconds_bucket{query=\"%s\",le=\"%s\"} %d\n", label, le, total)
+		}
+		fmt.Fprintf(w, "sql_query_duration_seconds_sum{query=\"%s\"} %g\n", label, h.sum)
+		fmt.Fprintf(w, "sql_query_duration_seconds_count{query=\"%s\"} %d\n", label, total)
+	}
+
+	fmt.Fprintln(w, "# HELP sql_query_errors_total Queries that returned an error.")
+	fmt.Fprintln(w, "# TYPE sql_query_errors_total counter")
+	for _, q := range queries {
+		fmt.Fprintf(w, "sql_query_errors_total{query=\"%s\"} %d\n", escape(q), db.metrics.queries[q].errors)
+	}
+}
+
+func gauge(w io.Writer, name, help string, v any) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %v\n", name, help, name, name, v)
+}
+
+func counter(w io.Writer, name, help string, v any) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %v\n", name, help, name, name, v)
+}
+
+// normalize folds the runs of white space, a query spanning lines becomes one line
+// and the same query indented differently is counted once
+func normalize(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// escape writes a label value, queries may hold quotes
+func escape(query string) string {
+	return escaper.Replace(query)
+}
@@ -0,0 +1,133 @@
+// Package sqldb wraps a sql.DB with a configured pool, a cache of prepared statements,
+// latency histograms per query, a slow query log and a metrics endpoint.
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+type DB struct {
+	db      *sql.DB
+	stmts   *stmtCache
+	metrics *metrics
+	slow    time.Duration
+	logger  *log.Logger
+}
+
+// Open opens the database of cfg and checks it answers
+func Open(ctx context.Context, cfg Config) (*DB, error) {
+	sqlDB, err := sql.Open(cfg.Driver, cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+	db := New(sqlDB, cfg)
+
+	if err := sqlDB.PingContext(ctx); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// New wraps an opened sql.DB and applies the pool settings of cfg, Driver and DSN are unused
+func New(sqlDB *sql.DB, cfg Config) *DB {
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	return &DB{
+		db:      sqlDB,
+		stmts:   newStmtCache(sqlDB, max(cfg.StmtCacheSize, 1)),
+		metrics: newMetrics(),
+		slow:    cfg.SlowQuery,
+		logger:  log.Default(),
+	}
+}
+
+// SetLogger replaces the logger of slow queries, log.Default() by default
+func (db *DB) SetLogger(l *log.Logger) {
+	db.logger = l
+}
+
+// QueryContext runs a query with the cached statement of its text
+func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	start := time.Now()
+	stmt, release, err := db.stmts.get(ctx, query)
+	if err != nil {
+		db.observe(query, start, err)
+		return nil, err
+	}
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	release()
+	db.observe(query, start, err)
+	return rows, err
+}
+
+// QueryRowContext is QueryContext for at most one row, errors are deferred to Scan like sql.DB
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	start := time.Now()
+	stmt, release, err := db.stmts.get(ctx, query)
+	if err != nil {
+		db.observe(query, start, err)
+		// A statement that fails to prepare fails the same way on sql.DB, which reports it on Scan
+		return db.db.QueryRowContext(ctx, query, args...)
+	}
+
+	row := stmt.QueryRowContext(ctx, args...)
+	release()
+	db.observe(query, start, row.Err())
+	return row
+}
+
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	start := time.Now()
+	stmt, release, err := db.stmts.get(ctx, query)
+	if err != nil {
+		db.observe(query, start, err)
+		return nil, err
+	}
+
+	res, err := stmt.ExecContext(ctx, args...)
+	release()
+	db.observe(query, start, err)
+	return res, err
+}
+
+// BeginTx starts a transaction on the pool, its statements aren't cached nor measured
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return db.db.BeginTx(ctx, opts)
+}
+
+func (db *DB) PingContext(ctx context.Context) error {
+	return db.db.PingContext(ctx)
+}
+
+func (db *DB) Stats() sql.DBStats {
+	return db.db.Stats()
+}
+
+// Unwrap returns the sql.DB, for libraries that need one
+func (db *DB) Unwrap() *sql.DB {
+	return db.db
+}
+
+// Close closes the cached statements, then the pool
+func (db *DB) Close() error {
+	db.stmts.close()
+	return db.db.Close()
+}
+
+func (db *DB) observe(query string, start time.Time, err error) {
+	elapsed := time.Since(start)
+	db.metrics.observe(query, elapsed, err != nil && err != sql.ErrNoRows)
+
+	// The arguments aren't logged, they may hold personal data or secrets
+	if db.slow > 0 && elapsed >= db.slow {
+		db.logger.Printf("slow query (%s): %s\n", elapsed.Round(time.Millisecond), normalize(query))
+	}
+}
@@ -0,0 +1,270 @@
+package sqldb
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeDriver prepares any query. A query containing "slow" takes 20ms, one containing
+// "fail" fails when run, and every query returns the single row 1.
+type fakeDriver struct {
+	prepares atomic.Int64
+	closes   atomic.Int64
+}
+
+func (d *fakeDriver) Connect(context.Context) (driver.Conn, error) { return fakeConn{d}, nil }
+func (d *fakeDriver) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	c.d.prepares.Add(1)
+	return fakeStmt{c.d, query}, nil
+}
+func (c fakeConn) Close() error              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s fakeStmt) Close() error  { s.d.closes.Add(1); return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) run() error {
+	if strings.Contains(s.query, "slow") {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if strings.Contains(s.query, "fail") {
+		return errors.New("boom")
+	}
+	return nil
+}
+
+func (s fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), s.run()
+}
+
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	if err := s.run(); err != nil {
+		return nil, err
+	}
+	return &fakeRows{}, nil
+}
+
+type fakeRows struct{ done bool }
+
+func (r *fakeRows) Columns() []string { return []string{"n"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+func open(t *testing.T, cfg Config) (*DB, *fakeDriver) {
+	d := &fakeDriver{}
+	db := New(sql.OpenDB(d), cfg)
+	t.Cleanup(func() { db.Close() })
+	return db, d
+}
+
+func TestPoolConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxOpenConns = 3
+	db, _ := open(t, cfg)
+
+	if got := db.Stats().MaxOpenConnections; got != 3 {
+		t.Errorf("got %d max open connections, want 3", got)
+	}
+}
+
+func TestStatementsAreCached(t *testing.T) {
+	db, d := open(t, DefaultConfig())
+	ctx := context.Background()
+
+	for range 3 {
+		var n int
+		if err := db.QueryRowContext(ctx, "SELECT n FROM t WHERE id = $1", 1).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.ExecContext(ctx, "UPDATE t SET n = $1", 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := d.prepares.Load(); got != 2 {
+		t.Errorf("got %d prepares, want one per query", got)
+	}
+	if size, hits, misses := db.stmts.stats(); size != 2 || hits != 4 || misses != 2 {
+		t.Errorf("got size %d, %d hits and %d misses, want 2, 4 and 2", size, hits, misses)
+	}
+}
+
+func TestStatementCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.StmtCacheSize = 2
+	db, d := open(t, cfg)
+	ctx := context.Background()
+
+	for _, q := range []string{"SELECT 1", "SELECT 2", "SELECT 1", "SELECT 3", "SELECT 1", "SELECT 2"} {
+		if _, err := db.ExecContext(ctx, q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// SELECT 2 was evicted by SELECT 3 and prepared again, SELECT 1 stayed
+	if got := d.prepares.Load(); got != 4 {
+		t.Errorf("got %d prepares, want 4", got)
+	}
+	if got := d.closes.Load(); got != 2 {
+		t.Errorf("got %d statements closed, want 2", got)
+	}
+}
+
+func TestEvictedStatementStaysOpenWhileBorrowed(t *testing.T) {
+	d := &fakeDriver{}
+	sqlDB := sql.OpenDB(d)
+	defer sqlDB.Close()
+	c := newStmtCache(sqlDB, 1)
+	ctx := context.Background()
+
+	stmt, release, err := c.get(ctx, "SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// * SELECT 2 evicts SELECT 1 before its query ran
+	_, releaseOther, err := c.get(ctx, "SELECT 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	releaseOther()
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		t.Fatalf("the evicted statement was closed under its borrower: %v", err)
+	}
+	if got := d.closes.Load(); got != 0 {
+		t.Errorf("got %d statements closed before the release, want 0", got)
+	}
+	release()
+	if got := d.closes.Load(); got != 1 {
+		t.Errorf("got %d statements closed after the release, want 1", got)
+	}
+}
+
+// More distinct queries than cache slots keep evicting statements other goroutines just got
+func TestStatementCacheConcurrentEviction(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.StmtCacheSize = 2
+	db, d := open(t, cfg)
+	ctx := context.Background()
+
+	var (
+		wg     sync.WaitGroup
+		failed atomic.Int64
+		first  atomic.Value
+	)
+	for g := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				query := fmt.Sprintf("SELECT %d", (g+i)%8)
+				var err error
+				if i%2 == 0 {
+					_, err = db.ExecContext(ctx, query)
+				} else {
+					var n int
+					err = db.QueryRowContext(ctx, query).Scan(&n)
+				}
+				if err != nil {
+					failed.Add(1)
+					first.CompareAndSwap(nil, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := failed.Load(); n > 0 {
+		t.Fatalf("%d queries failed, the first with %v", n, first.Load())
+	}
+	if size, _, _ := db.stmts.stats(); size > cfg.StmtCacheSize {
+		t.Errorf("got %d cached statements, want at most %d", size, cfg.StmtCacheSize)
+	}
+
+	// * every evicted statement was closed once its borrowers were done, the cached ones remain
+	db.stmts.mu.Lock()
+	cached := int64(db.stmts.lru.Len())
+	db.stmts.mu.Unlock()
+	if prepares, closes := d.prepares.Load(), d.closes.Load(); prepares-closes != cached {
+		t.Errorf("got %d prepares and %d closes, want %d statements left open", prepares, closes, cached)
+	}
+}
+
+func TestSlowQueryLog(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.SlowQuery = 10 * time.Millisecond
+	db, _ := open(t, cfg)
+
+	var buf bytes.Buffer
+	db.SetLogger(log.New(&buf, "", 0))
+	ctx := context.Background()
+
+	db.ExecContext(ctx, "SELECT fast")
+	db.ExecContext(ctx, "SELECT slow\n\tFROM t WHERE secret = $1", "hunter2")
+
+	out := buf.String()
+	if strings.Contains(out, "fast") || !strings.Contains(out, "SELECT slow FROM t WHERE secret = $1") {
+		t.Errorf("got log %q, want only the slow query on one line", out)
+	}
+	if strings.Contains(out, "hunter2") {
+		t.Error("the arguments were logged")
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	db, _ := open(t, DefaultConfig())
+	ctx := context.Background()
+
+	db.ExecContext(ctx, "SELECT slow")
+	db.ExecContext(ctx, "SELECT  slow")
+	db.ExecContext(ctx, `SELECT "fail"`)
+
+	rec := httptest.NewRecorder()
+	db.MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	out := rec.Body.String()
+
+	for _, want := range []string{
+		"sql_max_open_connections 10\n",
+		"sql_stmt_cache_size 3\n",
+		"sql_stmt_cache_misses_total 3\n",
+		// 20ms is above the 10ms bucket and within the 100ms one, both runs count as one query
+		"sql_query_duration_seconds_bucket{query=\"SELECT slow\",le=\"0.01\"} 0\n",
+		"sql_query_duration_seconds_bucket{query=\"SELECT slow\",le=\"0.1\"} 2\n",
+		"sql_query_duration_seconds_bucket{query=\"SELECT slow\",le=\"+Inf\"} 2\n",
+		"sql_query_duration_seconds_count{query=\"SELECT slow\"} 2\n",
+		"sql_query_errors_total{query=\"SELECT slow\"} 0\n",
+		"sql_query_errors_total{query=\"SELECT \\\"fail\\\"\"} 1\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics lack %q\n%s", want, out)
+		}
+	}
+}
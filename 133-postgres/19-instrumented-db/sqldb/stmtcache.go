@@ -0,0 +1,113 @@
+package sqldb
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// stmtCache keeps prepared statements by query text. A statement is borrowed from get until
+// its query has started, an evicted one is closed once the last borrower gives it back.
+// Rows still open after that keep it alive on their own, database/sql only releases a closed
+// statement once they are closed.
+type stmtCache struct {
+	db   *sql.DB
+	size int
+
+	mu     sync.Mutex
+	byText map[string]*list.Element
+	lru    *list.List // front is the most recently used *entry
+	hits   uint64
+	misses uint64
+}
+
+type entry struct {
+	query   string
+	stmt    *sql.Stmt
+	refs    int  // borrowers that haven't released the statement yet, guarded by stmtCache.mu
+	evicted bool // out of the cache, closed when refs drops to 0
+}
+
+func newStmtCache(db *sql.DB, size int) *stmtCache {
+	return &stmtCache{db: db, size: size, byText: map[string]*list.Element{}, lru: list.New()}
+}
+
+// get borrows the statement of query, release must be called once the query has started
+func (c *stmtCache) get(ctx context.Context, query string) (stmt *sql.Stmt, release func(), err error) {
+	c.mu.Lock()
+	if el, ok := c.byText[query]; ok {
+		c.lru.MoveToFront(el)
+		c.hits++
+		e := c.borrow(el)
+		c.mu.Unlock()
+		return e.stmt, func() { c.release(e) }, nil
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	// Prepared without the lock, a slow prepare doesn't hold up the queries already cached
+	prepared, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.byText[query]
+	if ok {
+		// Prepared by another goroutine meanwhile
+		prepared.Close()
+		c.lru.MoveToFront(el)
+	} else {
+		el = c.lru.PushFront(&entry{query: query, stmt: prepared})
+		c.byText[query] = el
+	}
+	e := c.borrow(el)
+
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		old := oldest.Value.(*entry)
+		delete(c.byText, old.query)
+		old.evicted = true
+		if old.refs == 0 {
+			old.stmt.Close()
+		}
+	}
+	return e.stmt, func() { c.release(e) }, nil
+}
+
+// borrow counts a user of the entry, c.mu is held
+func (c *stmtCache) borrow(el *list.Element) *entry {
+	e := el.Value.(*entry)
+	e.refs++
+	return e
+}
+
+func (c *stmtCache) release(e *entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e.refs--
+	if e.evicted && e.refs == 0 {
+		e.stmt.Close()
+	}
+}
+
+func (c *stmtCache) stats() (size int, hits, misses uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len(), c.hits, c.misses
+}
+
+func (c *stmtCache) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, el := range c.byText {
+		e := el.Value.(*entry)
+		e.evicted = true
+		e.stmt.Close()
+	}
+	c.byText = map[string]*list.Element{}
+	c.lru.Init()
+}
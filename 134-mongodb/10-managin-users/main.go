@@ -255,6 +255,12 @@ package main
 	combine these roles or create custom roles to meet specific needs.
 */
 
+// NOTE: Provisioning
+/*
+	The users above are declared in 133-postgres/10-manage-users/roles.example.yaml, the provision
+	command there creates them, grants and revokes their roles and drops the absent ones.
+*/
+
 func main() {
 
 }
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"crud-example/internal/constant"
+	"crud-example/internal/model"
+	"crud-example/internal/tpl"
+	apperr "crud-example/pkg/util/app_err"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// entityPage feeds entities.gohtml, which lists authors or publishers
+type entityPage struct {
+	Title string
+	Path  string // singular route prefix, e.g. "author" for /author/create
+	Items any
+}
+
+type AuthorController struct {
+}
+
+func NewAuthorController() *AuthorController {
+	return &AuthorController{}
+}
+
+func (ac *AuthorController) GetAuthors(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	aus, err := model.FetchAllAuthors(r.Context())
+
+	if err != nil {
+		apperr.HandleHttpMongoErr(w, r, err)
+		return
+	}
+
+	tpl.Tpl.ExecuteTemplate(w, "entities.gohtml", entityPage{Title: "Authors", Path: "author", Items: aus})
+}
+
+func (ac *AuthorController) CreateAuthorProcess(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	name := r.FormValue("name")
+
+	if name == "" {
+		apperr.HandleBadRequest(w, constant.ErrMissingName)
+		return
+	}
+
+	if _, err := model.EnsureAuthor(r.Context(), name); err != nil {
+		apperr.HandleHttpMongoErr(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/authors", http.StatusSeeOther)
+}
+
+func (ac *AuthorController) DeleteAuthorProcess(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id, err := primitive.ObjectIDFromHex(p.ByName(constant.SlugID))
+
+	if err != nil {
+		apperr.HandleBadRequest(w, constant.ErrInvalidID)
+		return
+	}
+
+	if err := model.DeleteAuthor(r.Context(), id); err != nil {
+		apperr.HandleHttpMongoErr(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/authors", http.StatusSeeOther)
+}
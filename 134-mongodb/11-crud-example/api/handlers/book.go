@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"context"
 	"crud-example/internal/constant"
 	"crud-example/internal/model"
 	"crud-example/internal/tpl"
 	apperr "crud-example/pkg/util/app_err"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -14,12 +16,78 @@ import (
 type BookController struct {
 }
 
+// bookConflict is rendered when an update was based on an outdated version
+type bookConflict struct {
+	Current   model.BookDetails
+	Submitted model.BookDetails
+}
+
+// bookForm feeds the author and publisher pickers of create.gohtml and update.gohtml
+type bookForm struct {
+	model.Book
+	Authors    []model.Author
+	Publishers []model.Publisher
+}
+
+func newBookForm(ctx context.Context, bk model.Book) (bookForm, error) {
+	form := bookForm{Book: bk}
+
+	aus, err := model.FetchAllAuthors(ctx)
+	if err != nil {
+		return form, err
+	}
+	form.Authors = aus
+
+	pubs, err := model.FetchAllPublishers(ctx)
+	if err != nil {
+		return form, err
+	}
+	form.Publishers = pubs
+
+	return form, nil
+}
+
+// parseBookForm reads the fields shared by the create and update forms.
+// It writes the error response itself and reports whether the handler may go on.
+func parseBookForm(w http.ResponseWriter, r *http.Request) (model.Book, bool) {
+	bk := model.Book{
+		Isbn:  r.FormValue("isbn"),
+		Title: r.FormValue("title"),
+	}
+	au := r.FormValue("authorId")
+	pr := r.FormValue("price")
+
+	if bk.Isbn == "" || bk.Title == "" || au == "" || pr == "" {
+		apperr.HandleBadRequest(w, constant.ErrMissingSomeFields)
+		return bk, false
+	}
+
+	if price, err := strconv.ParseFloat(pr, 64); err != nil {
+		apperr.HandleNotAcceptable(w, constant.ErrInvalidPriceField)
+		return bk, false
+	} else {
+		bk.Price = price
+	}
+
+	var err error
+	if bk.AuthorID, err = model.ParseObjectID(au); err != nil {
+		apperr.HandleBadRequest(w, constant.ErrInvalidID)
+		return bk, false
+	}
+	if bk.PublisherID, err = model.ParseObjectID(r.FormValue("publisherId")); err != nil {
+		apperr.HandleBadRequest(w, constant.ErrInvalidID)
+		return bk, false
+	}
+
+	return bk, true
+}
+
 func NewBookController() *BookController {
 	return &BookController{}
 }
 
 func (uc *BookController) GetBooks(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	bks, err := model.FetchAllBooks()
+	bks, err := model.FetchAllBooks(r.Context())
 
 	if err != nil {
 		apperr.HandleHttpMongoErr(w, r, err)
@@ -37,47 +105,48 @@ func (uc *BookController) GetBookDetails(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	bk, err := model.FetchBook(isbn)
+	bk, err := model.FetchBookDetails(r.Context(), isbn)
 	if err != nil {
 		apperr.HandleHttpMongoErr(w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", bookETag(bk.Book))
 	tpl.Tpl.ExecuteTemplate(w, "details.gohtml", bk)
 }
 
-func (uc *BookController) GetCreateBook(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
-	tpl.Tpl.ExecuteTemplate(w, "create.gohtml", nil)
+func (uc *BookController) GetCreateBook(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	form, err := newBookForm(r.Context(), model.Book{})
+	if err != nil {
+		apperr.HandleHttpMongoErr(w, r, err)
+		return
+	}
+
+	tpl.Tpl.ExecuteTemplate(w, "create.gohtml", form)
 }
 
 func (uc *BookController) CreateBookProcess(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-
-	bk := model.Book{
-		Isbn:   r.FormValue("isbn"),
-		Title:  r.FormValue("title"),
-		Author: r.FormValue("author"),
-	}
-	p := r.FormValue("price")
-
-	if bk.Isbn == "" || bk.Title == "" || bk.Author == "" || p == "" {
-		apperr.HandleBadRequest(w, constant.ErrMissingSomeFields)
+	bk, ok := parseBookForm(w, r)
+	if !ok {
 		return
 	}
 
-	if price, err := strconv.ParseFloat(p, 64); err != nil {
-		apperr.HandleNotAcceptable(w, constant.ErrInvalidPriceField)
-		return
-	} else {
-		bk.Price = price
+	if st := r.FormValue("stock"); st != "" {
+		stock, err := strconv.Atoi(st)
+		if err != nil || stock < 0 {
+			apperr.HandleNotAcceptable(w, constant.ErrInvalidStock)
+			return
+		}
+		bk.Stock = stock
 	}
 
-	_, err := model.InsertBook(bk)
+	details, err := model.InsertBook(r.Context(), bk)
 	if err != nil {
-		apperr.HandleInternalServerError(w, apperr.ErrNoMessage)
+		apperr.HandleHttpMongoErr(w, r, err)
 		return
 	}
 
-	tpl.Tpl.ExecuteTemplate(w, "created.gohtml", bk)
+	tpl.Tpl.ExecuteTemplate(w, "created.gohtml", details)
 }
 
 func (uc *BookController) GetUpdateBook(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
@@ -88,13 +157,20 @@ func (uc *BookController) GetUpdateBook(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	bk, err := model.FetchBook(isbn)
+	bk, err := model.FetchBook(r.Context(), isbn)
+	if err != nil {
+		apperr.HandleHttpMongoErr(w, r, err)
+		return
+	}
+
+	form, err := newBookForm(r.Context(), bk)
 	if err != nil {
 		apperr.HandleHttpMongoErr(w, r, err)
 		return
 	}
 
-	tpl.Tpl.ExecuteTemplate(w, "update.gohtml", bk)
+	w.Header().Set("ETag", bookETag(bk))
+	tpl.Tpl.ExecuteTemplate(w, "update.gohtml", form)
 }
 
 func (uc *BookController) PutUpdatedBook(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
@@ -105,32 +181,53 @@ func (uc *BookController) PutUpdatedBook(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	bk := model.Book{
-		Isbn:   r.FormValue("isbn"),
-		Title:  r.FormValue("title"),
-		Author: r.FormValue("author"),
+	bk, ok := parseBookForm(w, r)
+	if !ok {
+		return
 	}
-	pr := r.FormValue("price")
 
-	if bk.Isbn == "" || bk.Title == "" || bk.Author == "" || pr == "" {
-		apperr.HandleBadRequest(w, constant.ErrMissingSomeFields)
+	version, fromHeader, err := expectedVersion(r)
+	switch {
+	case errors.Is(err, errMissingVersion):
+		apperr.HandlePreconditionRequired(w, err.Error())
+		return
+	case err != nil:
+		apperr.HandleBadRequest(w, err.Error())
 		return
 	}
+	bk.Version = version
 
-	if price, err := strconv.ParseFloat(pr, 64); err != nil {
-		apperr.HandleNotAcceptable(w, constant.ErrInvalidPriceField)
+	submitted, err := model.ResolveBookRefs(r.Context(), bk)
+	if err != nil {
+		apperr.HandleHttpMongoErr(w, r, err)
 		return
-	} else {
-		bk.Price = price
 	}
 
-	updatedBk, err := model.UpdateBook(isbn, bk)
+	updatedBk, err := model.UpdateBook(r.Context(), isbn, bk)
+	if errors.Is(err, apperr.ErrVersionConflict) {
+		// A stale If-Match is a failed precondition, a stale form is an edit conflict
+		status := http.StatusConflict
+		if fromHeader {
+			status = http.StatusPreconditionFailed
+		}
+		current, err := model.FetchBookDetails(r.Context(), isbn)
+		if err != nil {
+			current = model.BookDetails{Book: updatedBk}
+		}
+		w.Header().Set("ETag", bookETag(updatedBk))
+		w.WriteHeader(status)
+		tpl.Tpl.ExecuteTemplate(w, "conflict.gohtml", bookConflict{Current: current, Submitted: submitted})
+		return
+	}
 	if err != nil {
 		apperr.HandleHttpMongoErr(w, r, err)
 		return
 	}
 
-	tpl.Tpl.ExecuteTemplate(w, "updated.gohtml", updatedBk)
+	// The refs were just resolved for the submitted book, only the stored fields changed
+	submitted.Book = updatedBk
+	w.Header().Set("ETag", bookETag(updatedBk))
+	tpl.Tpl.ExecuteTemplate(w, "updated.gohtml", submitted)
 }
 
 func (uc *BookController) DeleteBookProcess(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
@@ -141,10 +238,76 @@ func (uc *BookController) DeleteBookProcess(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	if err := model.DeleteBook(isbn); err != nil {
+	if err := model.DeleteBook(r.Context(), isbn); err != nil {
 		apperr.HandleHttpMongoErr(w, r, err)
 		return
 	}
 
 	http.Redirect(w, r, "/books", http.StatusSeeOther)
 }
+
+func (uc *BookController) GetTrash(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	bks, err := model.FetchDeletedBooks(r.Context())
+
+	if err != nil {
+		apperr.HandleHttpMongoErr(w, r, err)
+		return
+	}
+
+	tpl.Tpl.ExecuteTemplate(w, "trash.gohtml", bks)
+}
+
+func (uc *BookController) RestoreBookProcess(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	isbn := p.ByName(constant.SlugISBN)
+
+	if isbn == "" {
+		apperr.HandleBadRequest(w, constant.ErrMissingISBN)
+		return
+	}
+
+	if err := model.RestoreBook(r.Context(), isbn); err != nil {
+		apperr.HandleHttpMongoErr(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/books/trash", http.StatusSeeOther)
+}
+
+func (uc *BookController) PurgeBookProcess(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	isbn := p.ByName(constant.SlugISBN)
+
+	if isbn == "" {
+		apperr.HandleBadRequest(w, constant.ErrMissingISBN)
+		return
+	}
+
+	if err := model.PurgeBook(r.Context(), isbn); err != nil {
+		apperr.HandleHttpMongoErr(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/books/trash", http.StatusSeeOther)
+}
+
+// AdjustStockProcess adds (or with a negative delta removes) copies of a book
+func (uc *BookController) AdjustStockProcess(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	isbn := p.ByName(constant.SlugISBN)
+
+	if isbn == "" {
+		apperr.HandleBadRequest(w, constant.ErrMissingISBN)
+		return
+	}
+
+	delta, err := strconv.Atoi(r.FormValue("delta"))
+	if err != nil {
+		apperr.HandleNotAcceptable(w, constant.ErrInvalidStock)
+		return
+	}
+
+	if _, err := model.AdjustStock(r.Context(), isbn, delta); err != nil {
+		apperr.HandleHttpMongoErr(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/book/details/"+isbn, http.StatusSeeOther)
+}
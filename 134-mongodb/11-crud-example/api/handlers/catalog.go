@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"crud-example/internal/catalog"
+	"crud-example/internal/constant"
+	"crud-example/internal/tpl"
+	apperr "crud-example/pkg/util/app_err"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxImportSize caps an import request. The rows are parsed as they arrive, nothing is
+// buffered, the cap only bounds the work one request can cause.
+var maxImportSize int64 = 64 << 20
+
+// maxFormField caps the small form fields sent before the file
+const maxFormField = 1 << 10
+
+var errMissingImportFile = errors.New(constant.ErrMissingImportFile)
+
+type CatalogController struct {
+}
+
+func NewCatalogController() *CatalogController {
+	return &CatalogController{}
+}
+
+func (cc *CatalogController) GetImport(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	tpl.Tpl.ExecuteTemplate(w, "import.gohtml", nil)
+}
+
+// ImportProcess accepts either the import.gohtml upload form (rendered as html)
+// or a raw csv/ndjson request body (answered with the json report), e.g.
+// curl --data-binary @books.csv -H "Content-Type: text/csv" ":8080/books/import?dryRun=true"
+// format and dryRun come from the query string or from form fields sent before the file.
+func (cc *CatalogController) ImportProcess(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	var (
+		body     io.Reader = r.Body
+		hint               = r.Header.Get("Content-Type")
+		fromForm           = strings.HasPrefix(hint, "multipart/form-data")
+		// * r.FormValue would parse the whole body of a form post before the import starts
+		fields = r.URL.Query()
+	)
+
+	if fromForm {
+		file, formFields, err := importFile(r)
+		if err != nil {
+			if !importTooLarge(w, err) {
+				apperr.HandleBadRequest(w, err.Error())
+			}
+			return
+		}
+		defer file.Close()
+		body, hint = file, file.FileName()
+
+		for name, values := range formFields {
+			fields[name] = values
+		}
+	}
+
+	format, err := importFormat(fields.Get("format"), hint)
+	if err != nil {
+		apperr.HandleBadRequest(w, err.Error())
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(fields.Get("dryRun"))
+	rep, err := catalog.Import(r.Context(), body, catalog.ImportOptions{Format: format, DryRun: dryRun})
+	if err != nil {
+		log.Printf("Catalog import failed after %d rows: %v\n", rep.Rows, err)
+		if !importTooLarge(w, err) {
+			apperr.HandleInternalServerError(w, err.Error())
+		}
+		return
+	}
+
+	if fromForm {
+		tpl.Tpl.ExecuteTemplate(w, "imported.gohtml", rep)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rep)
+}
+
+// importTooLarge answers 413 when err comes from the maxImportSize cap
+func importTooLarge(w http.ResponseWriter, err error) bool {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		return false
+	}
+
+	msg := fmt.Sprintf("%s imports are limited to %d bytes", http.StatusText(http.StatusRequestEntityTooLarge), tooLarge.Limit)
+	http.Error(w, msg, http.StatusRequestEntityTooLarge)
+	return true
+}
+
+// importFile reads the multipart form up to its file part and returns the file unread,
+// along with the fields sent before it. Fields after the file are never read.
+func importFile(r *http.Request) (*multipart.Part, url.Values, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fields := url.Values{}
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, nil, errMissingImportFile
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if part.FormName() == "file" {
+			return part, fields, nil
+		}
+
+		value, err := io.ReadAll(io.LimitReader(part, maxFormField))
+		part.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+		fields.Add(part.FormName(), string(value))
+	}
+}
+
+func (cc *CatalogController) GetExport(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	f := r.FormValue("format")
+	if f == "" {
+		f = string(catalog.FormatCSV)
+	}
+
+	format, err := catalog.ParseFormat(f)
+	if err != nil {
+		apperr.HandleBadRequest(w, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="books.%s"`, format))
+
+	// Headers are gone once the first row is written, so a failure can only be logged
+	if n, err := catalog.Export(r.Context(), w, format); err != nil {
+		log.Printf("Catalog export failed after %d books: %v\n", n, err)
+	}
+}
+
+// importFormat prefers the explicit format and falls back to a file name or content type hint
+func importFormat(explicit, hint string) (catalog.Format, error) {
+	if explicit != "" {
+		return catalog.ParseFormat(explicit)
+	}
+
+	switch {
+	case strings.Contains(hint, "csv"):
+		return catalog.FormatCSV, nil
+	case strings.Contains(hint, "json"):
+		return catalog.FormatNDJSON, nil
+	}
+	return catalog.FormatFromFilename(hint)
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bytes"
+	"crud-example/internal/tpl"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+const importCSV = "isbn,title,author,price\n111,Dune,Frank Herbert,12.5\n222,,Austen,9\n"
+
+// importForm builds the multipart body of import.gohtml, the fields come before the file
+func importForm(t *testing.T, fields map[string]string, filename, content string) (io.Reader, string) {
+	t.Helper()
+	var b bytes.Buffer
+	mw := multipart.NewWriter(&b)
+	for name, value := range fields {
+		mw.WriteField(name, value)
+	}
+	if filename != "" {
+		fw, err := mw.CreateFormFile("file", filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(fw, content)
+	}
+	mw.Close()
+	return &b, mw.FormDataContentType()
+}
+
+func withImportedTemplate(t *testing.T) {
+	t.Helper()
+	old := tpl.Tpl
+	tpl.Tpl = template.Must(template.New("imported.gohtml").Parse("{{.DryRun}} {{.Rows}} {{.Valid}} {{.Invalid}}"))
+	t.Cleanup(func() { tpl.Tpl = old })
+}
+
+func TestImportFormStreamsFile(t *testing.T) {
+	withImportedTemplate(t)
+
+	body, contentType := importForm(t, map[string]string{"dryRun": "true"}, "books.csv", importCSV)
+	r := httptest.NewRequest(http.MethodPost, "/books/import", body)
+	r.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	NewCatalogController().ImportProcess(w, r, nil)
+
+	if w.Code != http.StatusOK || w.Body.String() != "true 2 1 1" {
+		t.Errorf("got %d %q, want a dry run of 2 rows, 1 valid", w.Code, w.Body.String())
+	}
+}
+
+func TestImportFormWithoutFile(t *testing.T) {
+	body, contentType := importForm(t, map[string]string{"dryRun": "true"}, "", "")
+	r := httptest.NewRequest(http.MethodPost, "/books/import", body)
+	r.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	NewCatalogController().ImportProcess(w, r, nil)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("got %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestImportRawBody(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/books/import?dryRun=true", strings.NewReader(importCSV))
+	r.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	NewCatalogController().ImportProcess(w, r, nil)
+
+	var rep struct {
+		DryRun      bool
+		Rows, Valid int
+		Invalid     int
+		Errors      []struct{ Line int }
+	}
+	if err := json.NewDecoder(w.Body).Decode(&rep); err != nil {
+		t.Fatalf("status %d: %v", w.Code, err)
+	}
+	if !rep.DryRun || rep.Rows != 2 || rep.Valid != 1 || rep.Invalid != 1 || len(rep.Errors) != 1 || rep.Errors[0].Line != 3 {
+		t.Errorf("got %+v", rep)
+	}
+}
+
+func TestImportTooLarge(t *testing.T) {
+	old := maxImportSize
+	maxImportSize = 64
+	t.Cleanup(func() { maxImportSize = old })
+
+	for _, multi := range []bool{false, true} {
+		var (
+			body        io.Reader = strings.NewReader(importCSV + strings.Repeat("333,Emma,Austen,9\n", 10))
+			contentType           = "text/csv"
+		)
+		if multi {
+			body, contentType = importForm(t, map[string]string{"dryRun": "true"}, "books.csv", importCSV+strings.Repeat("333,Emma,Austen,9\n", 10))
+		}
+		r := httptest.NewRequest(http.MethodPost, "/books/import?dryRun=true", body)
+		r.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		NewCatalogController().ImportProcess(w, r, nil)
+
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("multipart %v: got %d %q, want %d", multi, w.Code, w.Body.String(), http.StatusRequestEntityTooLarge)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"crud-example/config"
+	"crud-example/internal/db/dbtest"
+	ctxhelper "crud-example/pkg/util/context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// withBudgets swaps the data layer budgets for the duration of a test
+func withBudgets(t *testing.T, b ctxhelper.Budgets) {
+	t.Helper()
+	ctxhelper.Configure(b)
+	t.Cleanup(func() { ctxhelper.Configure(config.Default().Mongo.Budgets()) })
+}
+
+func TestClientDisconnectCancelsQuery(t *testing.T) {
+	srv := dbtest.Connect(t, "find")
+	withBudgets(t, ctxhelper.Budgets{Read: time.Minute, Write: time.Minute, Long: time.Minute})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := httptest.NewRequest(http.MethodGet, "/publishers", nil).WithContext(ctx)
+
+	done := make(chan struct{})
+	go func() {
+		NewPublisherController().GetPublishers(httptest.NewRecorder(), r, nil)
+		close(done)
+	}()
+
+	select {
+	case <-srv.Hung():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the query never reached the server")
+	}
+
+	// What net/http does when the client closes the connection
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handler still waiting on the query after the request was cancelled")
+	}
+}
+
+func TestReadBudgetAnswersGatewayTimeout(t *testing.T) {
+	dbtest.Connect(t, "find")
+	withBudgets(t, ctxhelper.Budgets{Read: 100 * time.Millisecond, Write: time.Minute, Long: time.Minute})
+
+	w := httptest.NewRecorder()
+	NewPublisherController().GetPublishers(w, httptest.NewRequest(http.MethodGet, "/publishers", nil), nil)
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusGatewayTimeout)
+	}
+}
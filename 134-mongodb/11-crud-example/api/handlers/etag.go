@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"crud-example/internal/constant"
+	"crud-example/internal/model"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	errMissingVersion = errors.New(constant.ErrMissingVersion)
+	errInvalidVersion = errors.New(constant.ErrInvalidVersion)
+)
+
+// bookETag builds a strong ETag from the book version, e.g. "3"
+func bookETag(bk model.Book) string {
+	return strconv.Quote(strconv.FormatInt(bk.Version, 10))
+}
+
+// expectedVersion returns the version the client based its update on.
+// The If-Match header wins over the hidden version form field; fromHeader reports which one was used.
+func expectedVersion(r *http.Request) (version int64, fromHeader bool, err error) {
+	raw := strings.TrimSpace(r.Header.Get("If-Match"))
+	if raw != "" && raw != "*" {
+		fromHeader = true
+		raw = strings.Trim(strings.TrimPrefix(raw, "W/"), `"`)
+	} else {
+		raw = r.FormValue("version")
+	}
+
+	if raw == "" {
+		return 0, fromHeader, errMissingVersion
+	}
+
+	version, err = strconv.ParseInt(raw, 10, 64)
+	if err != nil || version < 0 {
+		return 0, fromHeader, errInvalidVersion
+	}
+
+	return version, fromHeader, nil
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"crud-example/internal/model"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestBookETag(t *testing.T) {
+	if got := bookETag(model.Book{Version: 3}); got != `"3"` {
+		t.Errorf("got %s, want %q", got, `"3"`)
+	}
+}
+
+func TestExpectedVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		ifMatch    string
+		form       string
+		want       int64
+		fromHeader bool
+		err        error
+	}{
+		{name: "form field", form: "4", want: 4},
+		{name: "if-match", ifMatch: `"5"`, want: 5, fromHeader: true},
+		{name: "if-match wins over the form", ifMatch: `"5"`, form: "4", want: 5, fromHeader: true},
+		{name: "weak etag", ifMatch: `W/"6"`, want: 6, fromHeader: true},
+		{name: "wildcard falls back to the form", ifMatch: "*", form: "4", want: 4},
+		{name: "missing", err: errMissingVersion},
+		{name: "wildcard alone", ifMatch: "*", err: errMissingVersion},
+		{name: "malformed header", ifMatch: `"abc"`, form: "4", fromHeader: true, err: errInvalidVersion},
+		{name: "malformed form", form: "4.5", err: errInvalidVersion},
+		{name: "negative", form: "-1", err: errInvalidVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			if tt.form != "" {
+				form.Set("version", tt.form)
+			}
+			r := httptest.NewRequest(http.MethodPost, "/books/x/update", strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.ifMatch != "" {
+				r.Header.Set("If-Match", tt.ifMatch)
+			}
+
+			got, fromHeader, err := expectedVersion(r)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if got != tt.want || fromHeader != tt.fromHeader {
+				t.Errorf("got (%d, %v), want (%d, %v)", got, fromHeader, tt.want, tt.fromHeader)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"crud-example/internal/live"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// sseHeartbeat keeps proxies from closing an idle event stream
+const sseHeartbeat = 25 * time.Second
+
+type EventsController struct {
+	broker *live.Broker
+}
+
+func NewEventsController(broker *live.Broker) *EventsController {
+	return &EventsController{broker: broker}
+}
+
+// GetBookEvents streams book changes as Server-Sent Events until the client goes away
+func (ec *EventsController) GetBookEvents(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	events, unsubscribe := ec.broker.Subscribe()
+	defer unsubscribe()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			data, _ := json.Marshal(ev)
+			fmt.Fprintf(w, "event: book\ndata: %s\n\n", data)
+		}
+		flusher.Flush()
+	}
+}
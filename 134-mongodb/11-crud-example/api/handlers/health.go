@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// readyTimeout keeps a hanging dependency from hanging the probe
+const readyTimeout = 2 * time.Second
+
+type HealthController struct {
+	ready func(ctx context.Context) error
+}
+
+// NewHealthController takes the readiness check, e.g. a MongoDB ping
+func NewHealthController(ready func(ctx context.Context) error) *HealthController {
+	return &HealthController{ready: ready}
+}
+
+// GetHealthz answers as long as the process serves requests
+func (hc *HealthController) GetHealthz(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ok\n"))
+}
+
+// GetReadyz tells load balancers whether to send traffic, it fails while MongoDB is
+// unreachable and once shutdown has begun
+func (hc *HealthController) GetReadyz(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err := hc.ready(ctx); err != nil {
+		http.Error(w, "not ready: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("ready\n"))
+}
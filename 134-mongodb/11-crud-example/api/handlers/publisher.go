@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"crud-example/internal/constant"
+	"crud-example/internal/model"
+	"crud-example/internal/tpl"
+	apperr "crud-example/pkg/util/app_err"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PublisherController struct {
+}
+
+func NewPublisherController() *PublisherController {
+	return &PublisherController{}
+}
+
+func (pc *PublisherController) GetPublishers(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	pubs, err := model.FetchAllPublishers(r.Context())
+
+	if err != nil {
+		apperr.HandleHttpMongoErr(w, r, err)
+		return
+	}
+
+	tpl.Tpl.ExecuteTemplate(w, "entities.gohtml", entityPage{Title: "Publishers", Path: "publisher", Items: pubs})
+}
+
+func (pc *PublisherController) CreatePublisherProcess(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	name := r.FormValue("name")
+
+	if name == "" {
+		apperr.HandleBadRequest(w, constant.ErrMissingName)
+		return
+	}
+
+	if _, err := model.EnsurePublisher(r.Context(), name); err != nil {
+		apperr.HandleHttpMongoErr(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/publishers", http.StatusSeeOther)
+}
+
+func (pc *PublisherController) DeletePublisherProcess(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id, err := primitive.ObjectIDFromHex(p.ByName(constant.SlugID))
+
+	if err != nil {
+		apperr.HandleBadRequest(w, constant.ErrInvalidID)
+		return
+	}
+
+	if err := model.DeletePublisher(r.Context(), id); err != nil {
+		apperr.HandleHttpMongoErr(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/publishers", http.StatusSeeOther)
+}
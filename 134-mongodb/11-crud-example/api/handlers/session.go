@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+)
+
+const sessionCookie = "bookstore_session"
+
+// randRead fills the new session ids, tests replace it
+var randRead = rand.Read
+
+// sessionID returns the id of the visitor's session, starting a new one when there is none yet.
+// It fails rather than hand out an id that isn't random, which could be someone else's.
+func sessionID(w http.ResponseWriter, r *http.Request) (string, error) {
+	if c, err := r.Cookie(sessionCookie); err == nil && c.Value != "" {
+		return c.Value, nil
+	}
+
+	b := make([]byte, 16)
+	if _, err := randRead(b); err != nil {
+		return "", fmt.Errorf("new session id: %w", err)
+	}
+	id := hex.EncodeToString(b)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    id,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return id, nil
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSessionID(t *testing.T) {
+	w := httptest.NewRecorder()
+	id, err := sessionID(w, httptest.NewRequest(http.MethodGet, "/cart", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(id) != 32 {
+		t.Errorf("got id %q, want 16 random bytes in hex", id)
+	}
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != sessionCookie || cookies[0].Value != id {
+		t.Fatalf("got cookies %v, want %s=%s", cookies, sessionCookie, id)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/cart", nil)
+	r.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	if again, err := sessionID(w, r); err != nil || again != id {
+		t.Errorf("got %q, %v, want the session of the cookie %q", again, err, id)
+	}
+	if len(w.Result().Cookies()) != 0 {
+		t.Error("a new cookie was set for an existing session")
+	}
+}
+
+func TestSessionIDRandFails(t *testing.T) {
+	errRand := errors.New("no entropy")
+	randRead = func([]byte) (int, error) { return 0, errRand }
+	t.Cleanup(func() { randRead = rand.Read })
+
+	// * the cart handler never reaches the store, a nil one would panic
+	sc := NewShopController(nil)
+	w := httptest.NewRecorder()
+	sc.GetCart(w, httptest.NewRequest(http.MethodGet, "/cart", nil), nil)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	if len(w.Result().Cookies()) != 0 {
+		t.Error("a session cookie was set")
+	}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"crud-example/internal/constant"
+	"crud-example/internal/shop"
+	"crud-example/internal/tpl"
+	apperr "crud-example/pkg/util/app_err"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ShopController struct {
+	store shop.Store
+}
+
+func NewShopController(store shop.Store) *ShopController {
+	return &ShopController{store: store}
+}
+
+func (sc *ShopController) GetCart(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	sid, err := sessionID(w, r)
+	if err != nil {
+		apperr.HandleHttpMongoErr(w, r, err)
+		return
+	}
+
+	cart, err := sc.store.Cart(r.Context(), sid)
+	if err != nil {
+		apperr.HandleHttpMongoErr(w, r, err)
+		return
+	}
+
+	tpl.Tpl.ExecuteTemplate(w, "cart.gohtml", cart)
+}
+
+func (sc *ShopController) AddToCartProcess(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	isbn := p.ByName(constant.SlugISBN)
+
+	if isbn == "" {
+		apperr.HandleBadRequest(w, constant.ErrMissingISBN)
+		return
+	}
+
+	qty := 1
+	if q := r.FormValue("qty"); q != "" {
+		n, err := strconv.Atoi(q)
+		if err != nil || n == 0 {
+			apperr.HandleBadRequest(w, constant.ErrInvalidQty)
+			return
+		}
+		qty = n
+	}
+
+	sid, err := sessionID(w, r)
+	if err != nil {
+		apperr.HandleHttpMongoErr(w, r, err)
+		return
+	}
+
+	if err := sc.store.AddToCart(r.Context(), sid, isbn, qty); err != nil {
+		apperr.HandleHttpMongoErr(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/cart", http.StatusSeeOther)
+}
+
+func (sc *ShopController) RemoveFromCartProcess(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	isbn := p.ByName(constant.SlugISBN)
+
+	if isbn == "" {
+		apperr.HandleBadRequest(w, constant.ErrMissingISBN)
+		return
+	}
+
+	sid, err := sessionID(w, r)
+	if err != nil {
+		apperr.HandleHttpMongoErr(w, r, err)
+		return
+	}
+
+	if err := sc.store.RemoveFromCart(r.Context(), sid, isbn); err != nil {
+		apperr.HandleHttpMongoErr(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/cart", http.StatusSeeOther)
+}
+
+func (sc *ShopController) CheckoutProcess(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	sid, err := sessionID(w, r)
+	if err != nil {
+		apperr.HandleHttpMongoErr(w, r, err)
+		return
+	}
+
+	order, err := sc.store.PlaceOrder(r.Context(), sid)
+	if err != nil {
+		apperr.HandleHttpMongoErr(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/order/"+order.ID.Hex(), http.StatusSeeOther)
+}
+
+func (sc *ShopController) GetOrder(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id, err := primitive.ObjectIDFromHex(p.ByName(constant.SlugID))
+
+	if err != nil {
+		apperr.HandleBadRequest(w, constant.ErrInvalidID)
+		return
+	}
+
+	order, err := sc.store.Order(r.Context(), id)
+	if err != nil {
+		apperr.HandleHttpMongoErr(w, r, err)
+		return
+	}
+
+	// Orders are only visible to the session that placed them
+	sid, err := sessionID(w, r)
+	if err == nil && order.SessionID != sid {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		apperr.HandleHttpMongoErr(w, r, err)
+		return
+	}
+
+	tpl.Tpl.ExecuteTemplate(w, "order.gohtml", order)
+}
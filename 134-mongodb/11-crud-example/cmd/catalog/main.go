@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"crud-example/config"
+	"crud-example/internal/catalog"
+	"crud-example/internal/db"
+	"crud-example/internal/migrate"
+	"crud-example/internal/model"
+	ctxhelper "crud-example/pkg/util/context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+const usage = `usage:
+  catalog import [-format csv|ndjson] [-dry-run] [-batch n] <file|->
+  catalog export [-format csv|ndjson] [-o file]
+  catalog link-authors
+  catalog migrate up [-to version]
+  catalog migrate down [-steps n]
+  catalog migrate status
+every subcommand also takes the configuration flags, see catalog <subcommand> -h`
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatalln(usage)
+	}
+
+	// Ctrl-C cancels the running database operation
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var err error
+	switch os.Args[1] {
+	case "import":
+		err = runImport(ctx, os.Args[2:])
+	case "export":
+		err = runExport(ctx, os.Args[2:])
+	case "link-authors":
+		err = runLinkAuthors(ctx, os.Args[2:])
+	case "migrate":
+		err = runMigrate(ctx, os.Args[2:])
+	default:
+		log.Fatalln(usage)
+	}
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func runImport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	format := fs.String("format", "", "csv or ndjson, detected from the file extension when empty")
+	dryRun := fs.Bool("dry-run", false, "validate the file without writing anything")
+	batch := fs.Int("batch", catalog.DefaultBatchSize, "books per bulk write")
+	conf, err := config.Load(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("import needs exactly one file\n%s", usage)
+	}
+	name := fs.Arg(0)
+
+	f, err := importFormat(*format, name)
+	if err != nil {
+		return err
+	}
+
+	var in io.Reader = os.Stdin
+	if name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+
+	if err := connect(ctx, conf); err != nil {
+		return err
+	}
+	defer disconnect()
+
+	rep, err := catalog.Import(ctx, in, catalog.ImportOptions{Format: f, DryRun: *dryRun, BatchSize: *batch})
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(rep)
+
+	return err
+}
+
+func runExport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", string(catalog.FormatCSV), "csv or ndjson")
+	out := fs.String("o", "-", "output file, - for stdout")
+	conf, err := config.Load(fs, args)
+	if err != nil {
+		return err
+	}
+
+	f, err := catalog.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "-" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if err := connect(ctx, conf); err != nil {
+		return err
+	}
+	defer disconnect()
+
+	n, err := catalog.Export(ctx, w, f)
+	log.Printf("Exported %d books\n", n)
+	return err
+}
+
+// runLinkAuthors turns the free-text author of older books into author references
+func runLinkAuthors(ctx context.Context, args []string) error {
+	conf, err := config.Load(flag.NewFlagSet("link-authors", flag.ExitOnError), args)
+	if err != nil {
+		return err
+	}
+
+	if err := connect(ctx, conf); err != nil {
+		return err
+	}
+	defer disconnect()
+
+	n, err := model.LinkLegacyAuthors(ctx)
+	log.Printf("Linked %d books to their authors\n", n)
+	return err
+}
+
+// runMigrate applies, rolls back or lists the schema migrations of internal/migrate
+func runMigrate(ctx context.Context, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("migrate needs up, down or status\n%s", usage)
+	}
+	action := args[0]
+
+	fs := flag.NewFlagSet("migrate "+action, flag.ExitOnError)
+	to := fs.Int("to", 0, "up: apply migrations up to this version, 0 applies all")
+	steps := fs.Int("steps", 1, "down: number of migrations to roll back")
+	conf, err := config.Load(fs, args[1:])
+	if err != nil {
+		return err
+	}
+
+	if err := connect(ctx, conf); err != nil {
+		return err
+	}
+	defer disconnect()
+
+	runner, err := migrate.New(db.Database(), migrate.Bookstore)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := ctxhelper.ForLong(ctx)
+	defer cancel()
+
+	switch action {
+	case "up":
+		done, err := runner.Up(ctx, *to)
+		log.Printf("Applied %d migration(s)\n", len(done))
+		return err
+	case "down":
+		done, err := runner.Down(ctx, *steps)
+		log.Printf("Rolled back %d migration(s)\n", len(done))
+		return err
+	case "status":
+		return printMigrateStatus(ctx, runner)
+	default:
+		return fmt.Errorf("unknown migrate action %q\n%s", action, usage)
+	}
+}
+
+func printMigrateStatus(ctx context.Context, runner *migrate.Runner) error {
+	sts, err := runner.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED")
+	for _, st := range sts {
+		applied := "pending"
+		if st.AppliedAt != nil {
+			applied = st.AppliedAt.Local().Format(time.DateTime)
+		}
+		if st.Unknown {
+			applied += " (unknown to this build)"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", st.Version, st.Name, applied)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	drift, err := migrate.Drift(ctx, db.Database(), model.CollectionIndexes())
+	if err != nil {
+		return err
+	}
+	for _, d := range drift {
+		if len(d.Missing) > 0 {
+			fmt.Printf("%s: missing indexes %s\n", d.Collection, strings.Join(d.Missing, ", "))
+		}
+		if len(d.Extra) > 0 {
+			fmt.Printf("%s: undeclared indexes %s\n", d.Collection, strings.Join(d.Extra, ", "))
+		}
+	}
+	return nil
+}
+
+func importFormat(explicit, name string) (catalog.Format, error) {
+	if explicit != "" {
+		return catalog.ParseFormat(explicit)
+	}
+	if name == "-" {
+		return "", fmt.Errorf("-format is required when reading from stdin")
+	}
+	return catalog.FormatFromFilename(name)
+}
+
+func connect(ctx context.Context, conf config.Config) error {
+	ctxhelper.Configure(conf.Mongo.Budgets())
+	return db.Connect(ctx, conf.Mongo)
+}
+
+func disconnect() {
+	if err := db.Disconnect(context.Background()); err != nil {
+		log.Println(err)
+	}
+}
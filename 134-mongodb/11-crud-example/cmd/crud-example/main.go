@@ -1,12 +1,29 @@
 package main
 
 import (
+	"crud-example/config"
 	"crud-example/internal/app"
+	"flag"
+	"fmt"
 	"log"
+	"os"
 )
 
 func main() {
-	err := app.Run()
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	printConfig := fs.Bool("print-config", false, "print the effective configuration, secrets redacted, and exit")
+
+	conf, err := config.Load(fs, os.Args[1:])
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v\n", err)
+	}
+
+	if *printConfig {
+		fmt.Print(conf)
+		return
+	}
+
+	err = app.Run(conf)
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -1,3 +0,0 @@
-package config
-
-const WebPort = "8080"
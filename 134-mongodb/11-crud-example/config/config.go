@@ -0,0 +1,167 @@
+package config
+
+import (
+	ctxhelper "crud-example/pkg/util/context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Config is the whole application configuration. It is built by Load from, in increasing
+// precedence: Default(), an optional file (yaml, toml or .env), the environment and flags.
+type Config struct {
+	Web   Web
+	Mongo Mongo
+	Trash Trash
+	Live  Live
+}
+
+type Web struct {
+	Port              int
+	ReadHeaderTimeout time.Duration
+	ShutdownTimeout   time.Duration // how long in-flight requests may drain on shutdown
+}
+
+type Mongo struct {
+	URI      string // when set, Host, User and Password are ignored
+	Host     string
+	User     string
+	Password string
+	Database string
+
+	MaxPoolSize            uint64
+	MinPoolSize            uint64
+	MaxConnecting          uint64
+	MaxConnIdleTime        time.Duration
+	ConnectTimeout         time.Duration
+	ServerSelectionTimeout time.Duration
+	// Startup retries while MongoDB isn't reachable yet
+	ConnectAttempts   int
+	ConnectBackoff    time.Duration
+	ConnectBackoffMax time.Duration
+	// Deadline budgets of a read, a write and an operation walking a whole collection
+	QueryTimeout     time.Duration
+	WriteTimeout     time.Duration
+	LongQueryTimeout time.Duration
+}
+
+type Trash struct {
+	Retention     time.Duration // soft deleted books are purged after this long
+	PurgeInterval time.Duration
+}
+
+type Live struct {
+	PollInterval time.Duration // used when change streams aren't available
+}
+
+func Default() Config {
+	return Config{
+		Web: Web{
+			Port:              8080,
+			ReadHeaderTimeout: 5 * time.Second,
+			ShutdownTimeout:   15 * time.Second,
+		},
+		Mongo: Mongo{
+			Host:                   "localhost:27017",
+			Database:               "bookstore",
+			MaxPoolSize:            7,
+			MinPoolSize:            2,
+			MaxConnecting:          5,
+			MaxConnIdleTime:        30 * time.Second,
+			ConnectTimeout:         10 * time.Second,
+			ServerSelectionTimeout: 5 * time.Second,
+			ConnectAttempts:        10,
+			ConnectBackoff:         500 * time.Millisecond,
+			ConnectBackoffMax:      10 * time.Second,
+			QueryTimeout:           5 * time.Second,
+			WriteTimeout:           5 * time.Second,
+			LongQueryTimeout:       5 * time.Minute,
+		},
+		Trash: Trash{
+			Retention:     30 * 24 * time.Hour,
+			PurgeInterval: time.Hour,
+		},
+		Live: Live{
+			PollInterval: 5 * time.Second,
+		},
+	}
+}
+
+// Validate reports every problem at once rather than stopping at the first one
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Web.Port > 0 && c.Web.Port < 65536, "web.port must be between 1 and 65535, got %d", c.Web.Port)
+
+	if c.Mongo.URI != "" {
+		u, err := url.Parse(c.Mongo.URI)
+		check(err == nil && (u.Scheme == "mongodb" || u.Scheme == "mongodb+srv"),
+			"mongo.uri must be a mongodb:// or mongodb+srv:// url")
+	} else {
+		check(c.Mongo.Host != "", "mongo.host or mongo.uri must be set")
+		check(c.Mongo.Password == "" || c.Mongo.User != "", "mongo.password is set without mongo.user")
+	}
+	check(c.Mongo.Database != "", "mongo.database must be set")
+	check(c.Mongo.ConnectAttempts > 0, "mongo.connectAttempts must be at least 1, got %d", c.Mongo.ConnectAttempts)
+	check(c.Mongo.MaxPoolSize == 0 || c.Mongo.MinPoolSize <= c.Mongo.MaxPoolSize,
+		"mongo.minPoolSize (%d) must not exceed mongo.maxPoolSize (%d)", c.Mongo.MinPoolSize, c.Mongo.MaxPoolSize)
+
+	for _, s := range settings {
+		if d, ok := s.ptr(&c).(*time.Duration); ok {
+			check(*d > 0, "%s must be a positive duration, got %s", s.key, *d)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// URIString returns mongo.uri or builds one from host, credentials and database.
+// url.URL takes care of escaping special characters in the credentials.
+func (m Mongo) URIString() string {
+	if m.URI != "" {
+		return m.URI
+	}
+
+	u := url.URL{Scheme: "mongodb", Host: m.Host, Path: "/" + m.Database}
+	if m.User != "" {
+		u.User = url.UserPassword(m.User, m.Password)
+	}
+	return u.String()
+}
+
+// Budgets are the deadlines applied by the data layer on top of the request context
+func (m Mongo) Budgets() ctxhelper.Budgets {
+	return ctxhelper.Budgets{Read: m.QueryTimeout, Write: m.WriteTimeout, Long: m.LongQueryTimeout}
+}
+
+const redacted = "******"
+
+// String prints every setting with secrets redacted, so a Config is safe to log
+func (c Config) String() string {
+	var sb strings.Builder
+	for _, s := range settings {
+		fmt.Fprintf(&sb, "%s = %s\n", s.key, s.display(&c))
+	}
+	return sb.String()
+}
+
+// GoString keeps %#v from bypassing the redaction
+func (c Config) GoString() string {
+	return c.String()
+}
+
+// redactURI hides the password of a connection string
+func redactURI(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return redacted
+	}
+	return u.Redacted()
+}
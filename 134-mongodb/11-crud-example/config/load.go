@@ -0,0 +1,159 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileEnv points at the config file when -config isn't given.
+// Without either of them a .env file in the working directory is used if there is one.
+const ConfigFileEnv = "CONFIG_FILE"
+
+// Load registers a flag per setting plus -config on fs, parses args and builds the Config
+// from defaults, the config file, the environment and flags, each one overriding the previous.
+// Every bad value and validation problem is returned together in one joined error.
+func Load(fs *flag.FlagSet, args []string) (Config, error) {
+	cfg := Default()
+
+	type flagValue struct {
+		s   setting
+		raw string
+	}
+	var flagValues []flagValue
+
+	configFile := fs.String("config", "", "yaml, toml or .env config file (env "+ConfigFileEnv+")")
+	for _, s := range settings {
+		s := s
+		fs.Func(s.flag(), fmt.Sprintf("%s (env %s)", s.usage, s.env), func(raw string) error {
+			flagValues = append(flagValues, flagValue{s: s, raw: raw})
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	var errs []error
+
+	path := *configFile
+	if path == "" {
+		path = os.Getenv(ConfigFileEnv)
+	}
+	if path == "" {
+		if _, err := os.Stat(".env"); err == nil {
+			path = ".env"
+		}
+	}
+	if path != "" {
+		errs = append(errs, loadFile(&cfg, path)...)
+	}
+
+	for _, s := range settings {
+		if raw, ok := os.LookupEnv(s.env); ok {
+			if err := s.set(&cfg, raw); err != nil {
+				errs = append(errs, fmt.Errorf("%s from env %s: %w", s.key, s.env, err))
+			}
+		}
+	}
+
+	for _, fv := range flagValues {
+		if err := fv.s.set(&cfg, fv.raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s from flag -%s: %w", fv.s.key, fv.s.flag(), err))
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return cfg, errors.Join(errs...)
+}
+
+// loadFile applies a yaml or toml file (nested keys such as mongo.maxPoolSize)
+// or a .env file (the same variable names as the environment)
+func loadFile(cfg *Config, path string) []error {
+	ext := strings.ToLower(filepath.Ext(path))
+	if filepath.Base(path) == ".env" {
+		ext = ".env"
+	}
+
+	if ext == ".env" {
+		vars, err := godotenv.Read(path)
+		if err != nil {
+			return []error{fmt.Errorf("reading %s: %w", path, err)}
+		}
+
+		var errs []error
+		for _, s := range settings {
+			if raw, ok := vars[s.env]; ok {
+				if err := s.set(cfg, raw); err != nil {
+					errs = append(errs, fmt.Errorf("%s from %s: %w", s.key, path, err))
+				}
+			}
+		}
+		return errs
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return []error{fmt.Errorf("reading %s: %w", path, err)}
+	}
+
+	doc := map[string]any{}
+	switch ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &doc)
+	case ".toml":
+		err = toml.Unmarshal(b, &doc)
+	default:
+		return []error{fmt.Errorf("%s: unsupported config file, use .yaml, .toml or .env", path)}
+	}
+	if err != nil {
+		return []error{fmt.Errorf("parsing %s: %w", path, err)}
+	}
+
+	values := map[string]string{}
+	flatten("", doc, values)
+
+	var errs []error
+	for _, s := range settings {
+		key := strings.ToLower(s.key)
+		raw, ok := values[key]
+		if !ok {
+			continue
+		}
+		delete(values, key)
+		if err := s.set(cfg, raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s from %s: %w", s.key, path, err))
+		}
+	}
+	for key := range values {
+		errs = append(errs, fmt.Errorf("%s: unknown setting %q", path, key))
+	}
+
+	return errs
+}
+
+// flatten turns {mongo: {maxPoolSize: 7}} into {"mongo.maxpoolsize": "7"}, keys are matched case-insensitively
+func flatten(prefix string, doc map[string]any, out map[string]string) {
+	for k, v := range doc {
+		key := strings.ToLower(k)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		if nested, ok := v.(map[string]any); ok {
+			flatten(key, nested, out)
+			continue
+		}
+		out[key] = fmt.Sprint(v)
+	}
+}
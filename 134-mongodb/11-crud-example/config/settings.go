@@ -0,0 +1,142 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// setting ties a Config field to its file key, environment variable and flag.
+// The flag name is derived from the key, e.g. mongo.maxPoolSize is -mongo-max-pool-size.
+type setting struct {
+	key    string
+	env    string
+	usage  string
+	secret bool
+	ptr    func(*Config) any
+}
+
+// The DB_* names are kept from the original .env file
+var settings = []setting{
+	{key: "web.port", env: "WEB_PORT", usage: "http port",
+		ptr: func(c *Config) any { return &c.Web.Port }},
+	{key: "web.readHeaderTimeout", env: "WEB_READ_HEADER_TIMEOUT", usage: "time allowed to read request headers",
+		ptr: func(c *Config) any { return &c.Web.ReadHeaderTimeout }},
+	{key: "web.shutdownTimeout", env: "WEB_SHUTDOWN_TIMEOUT", usage: "time given to in-flight requests on shutdown",
+		ptr: func(c *Config) any { return &c.Web.ShutdownTimeout }},
+
+	{key: "mongo.uri", env: "MONGO_URI", usage: "full connection string, overrides host and credentials", secret: true,
+		ptr: func(c *Config) any { return &c.Mongo.URI }},
+	{key: "mongo.host", env: "DB_HOST", usage: "mongodb host:port",
+		ptr: func(c *Config) any { return &c.Mongo.Host }},
+	{key: "mongo.user", env: "DB_USER", usage: "mongodb user",
+		ptr: func(c *Config) any { return &c.Mongo.User }},
+	{key: "mongo.password", env: "DB_PASSWORD", usage: "mongodb password", secret: true,
+		ptr: func(c *Config) any { return &c.Mongo.Password }},
+	{key: "mongo.database", env: "DB_NAME", usage: "database name",
+		ptr: func(c *Config) any { return &c.Mongo.Database }},
+	{key: "mongo.maxPoolSize", env: "MONGO_MAX_POOL_SIZE", usage: "max connections in the pool, 0 is unlimited",
+		ptr: func(c *Config) any { return &c.Mongo.MaxPoolSize }},
+	{key: "mongo.minPoolSize", env: "MONGO_MIN_POOL_SIZE", usage: "connections kept open",
+		ptr: func(c *Config) any { return &c.Mongo.MinPoolSize }},
+	{key: "mongo.maxConnecting", env: "MONGO_MAX_CONNECTING", usage: "connections established concurrently",
+		ptr: func(c *Config) any { return &c.Mongo.MaxConnecting }},
+	{key: "mongo.maxConnIdleTime", env: "MONGO_MAX_CONN_IDLE_TIME", usage: "idle connections are closed after this long",
+		ptr: func(c *Config) any { return &c.Mongo.MaxConnIdleTime }},
+	{key: "mongo.connectTimeout", env: "MONGO_CONNECT_TIMEOUT", usage: "timeout to open a connection",
+		ptr: func(c *Config) any { return &c.Mongo.ConnectTimeout }},
+	{key: "mongo.serverSelectionTimeout", env: "MONGO_SERVER_SELECTION_TIMEOUT", usage: "timeout to find a usable server",
+		ptr: func(c *Config) any { return &c.Mongo.ServerSelectionTimeout }},
+	{key: "mongo.connectAttempts", env: "MONGO_CONNECT_ATTEMPTS", usage: "connection attempts at startup",
+		ptr: func(c *Config) any { return &c.Mongo.ConnectAttempts }},
+	{key: "mongo.connectBackoff", env: "MONGO_CONNECT_BACKOFF", usage: "first delay between startup connection attempts",
+		ptr: func(c *Config) any { return &c.Mongo.ConnectBackoff }},
+	{key: "mongo.connectBackoffMax", env: "MONGO_CONNECT_BACKOFF_MAX", usage: "longest delay between startup connection attempts",
+		ptr: func(c *Config) any { return &c.Mongo.ConnectBackoffMax }},
+	{key: "mongo.queryTimeout", env: "MONGO_QUERY_TIMEOUT", usage: "deadline of a single read",
+		ptr: func(c *Config) any { return &c.Mongo.QueryTimeout }},
+	{key: "mongo.writeTimeout", env: "MONGO_WRITE_TIMEOUT", usage: "deadline of a single write or transaction",
+		ptr: func(c *Config) any { return &c.Mongo.WriteTimeout }},
+	{key: "mongo.longQueryTimeout", env: "MONGO_LONG_QUERY_TIMEOUT", usage: "deadline of full collection scans such as exports",
+		ptr: func(c *Config) any { return &c.Mongo.LongQueryTimeout }},
+
+	{key: "trash.retention", env: "TRASH_RETENTION", usage: "how long deleted books stay in the trash",
+		ptr: func(c *Config) any { return &c.Trash.Retention }},
+	{key: "trash.purgeInterval", env: "TRASH_PURGE_INTERVAL", usage: "how often the trash is purged",
+		ptr: func(c *Config) any { return &c.Trash.PurgeInterval }},
+
+	{key: "live.pollInterval", env: "LIVE_POLL_INTERVAL", usage: "book change polling interval without change streams",
+		ptr: func(c *Config) any { return &c.Live.PollInterval }},
+}
+
+func (s setting) flag() string {
+	var sb strings.Builder
+	for _, r := range s.key {
+		switch {
+		case r == '.':
+			sb.WriteRune('-')
+		case unicode.IsUpper(r):
+			sb.WriteRune('-')
+			sb.WriteRune(unicode.ToLower(r))
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func (s setting) set(c *Config, raw string) error {
+	raw = strings.TrimSpace(raw)
+
+	switch p := s.ptr(c).(type) {
+	case *string:
+		*p = raw
+	case *int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", raw)
+		}
+		*p = n
+	case *uint64:
+		n, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a positive whole number", raw)
+		}
+		*p = n
+	case *time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration such as 30s or 720h", raw)
+		}
+		*p = d
+	default:
+		return fmt.Errorf("unsupported setting type %T", p)
+	}
+
+	return nil
+}
+
+// display is the printable value, secrets are redacted
+func (s setting) display(c *Config) string {
+	var v string
+	switch p := s.ptr(c).(type) {
+	case *string:
+		v = *p
+	case *int:
+		v = strconv.Itoa(*p)
+	case *uint64:
+		v = strconv.FormatUint(*p, 10)
+	case *time.Duration:
+		v = p.String()
+	}
+
+	if !s.secret || v == "" {
+		return v
+	}
+	if s.key == "mongo.uri" {
+		return redactURI(v)
+	}
+	return redacted
+}
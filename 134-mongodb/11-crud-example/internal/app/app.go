@@ -1,30 +1,154 @@
 package app
 
 import (
+	"context"
 	"crud-example/config"
 	"crud-example/internal/db"
+	"crud-example/internal/job"
+	"crud-example/internal/lifecycle"
+	"crud-example/internal/live"
 	"crud-example/internal/tpl"
+	ctxhelper "crud-example/pkg/util/context"
+	"errors"
 	"fmt"
+	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
 )
 
-type cfg struct{}
+var errShuttingDown = errors.New("shutting down")
+
+type cfg struct {
+	broker *live.Broker
+	// stopping answers /readyz with 503 once shutdown began. The listener closes right away,
+	// so only probes on a kept-alive connection still see it while requests drain.
+	stopping atomic.Bool
+}
+
+// Run starts templates, MongoDB, the background jobs and the http server in that order,
+// then blocks until SIGINT/SIGTERM or a failure and stops them again in reverse order
+func Run(conf config.Config) error {
+	app := &cfg{broker: live.NewBroker()}
+	// Deadline budgets of the data layer
+	ctxhelper.Configure(conf.Mongo.Budgets())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	lc := lifecycle.New()
 
-func Run() error {
-	app := cfg{}
-	// Initialize env variables
-	config.LoadEnv()
 	// Initialize templates
-	tpl.LoadTemplates()
-	// Initialize mongodb
-	db.InitMongoClient()
-	// Close MongoDB connection on exit
-	defer db.DisconnectMongoClient()
+	lc.Add(lifecycle.Component{
+		Name:  "templates",
+		Start: func(context.Context) error { return tpl.LoadTemplates() },
+	})
+
+	// MongoDB may still be booting next to us, e.g. in docker compose
+	lc.Add(lifecycle.Component{
+		Name:  "mongodb",
+		Start: func(ctx context.Context) error { return db.Connect(ctx, conf.Mongo) },
+		Stop:  db.Disconnect,
+		Retry: &lifecycle.Backoff{
+			Attempts: conf.Mongo.ConnectAttempts,
+			Initial:  conf.Mongo.ConnectBackoff,
+			Max:      conf.Mongo.ConnectBackoffMax,
+		},
+	})
+
+	lc.Add(app.jobs(conf))
+	lc.Add(app.server(conf, lc))
+
+	return lc.Run(ctx, conf.Web.ShutdownTimeout)
+}
+
+// jobs runs the trash purge and the book watcher until stopped
+func (app *cfg) jobs(conf config.Config) lifecycle.Component {
+	var (
+		wg     sync.WaitGroup
+		cancel context.CancelFunc
+	)
 
+	return lifecycle.Component{
+		Name: "jobs",
+		Start: func(context.Context) error {
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+
+			wg.Add(2)
+			// Hard-delete books that stayed in the trash past the retention period
+			go func() {
+				defer wg.Done()
+				job.PurgeTrash(ctx, conf.Trash.Retention, conf.Trash.PurgeInterval)
+			}()
+			// Cache books and push their changes to open pages
+			go func() {
+				defer wg.Done()
+				live.Watch(ctx, app.broker, conf.Live.PollInterval)
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			cancel()
+
+			done := make(chan struct{})
+			go func() {
+				wg.Wait()
+				close(done)
+			}()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+// server listens before Start returns, so a taken port fails the startup instead of
+// surfacing later. Stop drains in-flight requests within the shutdown timeout.
+func (app *cfg) server(conf config.Config, lc *lifecycle.Manager) lifecycle.Component {
 	srv := &http.Server{
-		Addr:    fmt.Sprintf(":%s", config.WebPort),
-		Handler: app.routes(),
+		Addr:              fmt.Sprintf(":%d", conf.Web.Port),
+		Handler:           app.routes(),
+		ReadHeaderTimeout: conf.Web.ReadHeaderTimeout,
 	}
+	// Event streams never finish on their own, closing the broker ends them
+	srv.RegisterOnShutdown(app.broker.Close)
 
-	return srv.ListenAndServe()
+	return lifecycle.Component{
+		Name: "http server",
+		Start: func(context.Context) error {
+			ln, err := net.Listen("tcp", srv.Addr)
+			if err != nil {
+				return err
+			}
+			log.Printf("Listening on %s\n", ln.Addr())
+
+			go func() {
+				if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+					lc.Fail(fmt.Errorf("http server: %w", err))
+				}
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			// * there's no delay before the drain, new connections are refused from here on
+			app.stopping.Store(true)
+			return srv.Shutdown(ctx)
+		},
+	}
+}
+
+// ready backs /readyz
+func (app *cfg) ready(ctx context.Context) error {
+	if app.stopping.Load() {
+		return errShuttingDown
+	}
+	return db.Ping(ctx)
 }
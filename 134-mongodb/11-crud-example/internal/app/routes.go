@@ -2,6 +2,7 @@ package app
 
 import (
 	"crud-example/api/handlers"
+	"crud-example/internal/shop"
 	"net/http"
 
 	"github.com/julienschmidt/httprouter"
@@ -10,15 +11,42 @@ import (
 func (app *cfg) routes() http.Handler {
 	router := httprouter.New()
 	bc := handlers.NewBookController()
+	cc := handlers.NewCatalogController()
+	ac := handlers.NewAuthorController()
+	pc := handlers.NewPublisherController()
+	sc := handlers.NewShopController(shop.NewMongoStore())
+	ec := handlers.NewEventsController(app.broker)
+	hc := handlers.NewHealthController(app.ready)
 
 	router.Handler(http.MethodGet, "/", http.RedirectHandler("/books", http.StatusSeeOther))
+	router.GET("/healthz", hc.GetHealthz)
+	router.GET("/readyz", hc.GetReadyz)
 	router.GET("/books", bc.GetBooks)
+	router.GET("/books/events", ec.GetBookEvents)
 	router.GET("/book/details/:isbn", bc.GetBookDetails)
 	router.GET("/book/create", bc.GetCreateBook)
 	router.POST("/book/create", bc.CreateBookProcess)
 	router.GET("/book/update/:isbn", bc.GetUpdateBook)
 	router.PUT("/book/update/:isbn", bc.PutUpdatedBook)
 	router.DELETE("/book/delete/:isbn", bc.DeleteBookProcess)
+	router.GET("/books/trash", bc.GetTrash)
+	router.PUT("/book/restore/:isbn", bc.RestoreBookProcess)
+	router.DELETE("/book/purge/:isbn", bc.PurgeBookProcess)
+	router.GET("/books/import", cc.GetImport)
+	router.POST("/books/import", cc.ImportProcess)
+	router.GET("/books/export", cc.GetExport)
+	router.GET("/authors", ac.GetAuthors)
+	router.POST("/author/create", ac.CreateAuthorProcess)
+	router.DELETE("/author/delete/:id", ac.DeleteAuthorProcess)
+	router.GET("/publishers", pc.GetPublishers)
+	router.POST("/publisher/create", pc.CreatePublisherProcess)
+	router.DELETE("/publisher/delete/:id", pc.DeletePublisherProcess)
+	router.POST("/book/stock/:isbn", bc.AdjustStockProcess)
+	router.GET("/cart", sc.GetCart)
+	router.POST("/cart/add/:isbn", sc.AddToCartProcess)
+	router.POST("/cart/remove/:isbn", sc.RemoveFromCartProcess)
+	router.POST("/checkout", sc.CheckoutProcess)
+	router.GET("/order/:id", sc.GetOrder)
 
 	return router
 }
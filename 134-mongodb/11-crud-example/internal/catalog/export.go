@@ -0,0 +1,88 @@
+package catalog
+
+import (
+	"context"
+	"crud-example/internal/model"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Export streams every book that is not in the trash to w and returns how many were written
+func Export(ctx context.Context, w io.Writer, f Format) (int, error) {
+	switch f {
+	case FormatCSV:
+		return exportCSV(ctx, w)
+	case FormatNDJSON:
+		return exportNDJSON(ctx, w)
+	}
+	return 0, fmt.Errorf("%w: %q", ErrUnknownFormat, f)
+}
+
+func exportCSV(ctx context.Context, w io.Writer) (int, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	err := model.StreamBooks(ctx, func(bk model.BookDetails) error {
+		err := cw.Write([]string{
+			bk.Isbn,
+			bk.Title,
+			bk.Author.Name,
+			publisherName(bk),
+			strconv.FormatFloat(bk.Price, 'f', -1, 64),
+		})
+		if err != nil {
+			return err
+		}
+		n++
+		return nil
+	})
+
+	cw.Flush()
+	if err == nil {
+		err = cw.Error()
+	}
+	return n, err
+}
+
+func exportNDJSON(ctx context.Context, w io.Writer) (int, error) {
+	// json.Encoder terminates every value with a newline
+	enc := json.NewEncoder(w)
+
+	type record struct {
+		Isbn      string  `json:"isbn"`
+		Title     string  `json:"title"`
+		Author    string  `json:"author"`
+		Publisher string  `json:"publisher,omitempty"`
+		Price     float64 `json:"price"`
+	}
+
+	n := 0
+	err := model.StreamBooks(ctx, func(bk model.BookDetails) error {
+		rec := record{
+			Isbn:      bk.Isbn,
+			Title:     bk.Title,
+			Author:    bk.Author.Name,
+			Publisher: publisherName(bk),
+			Price:     bk.Price,
+		}
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+		n++
+		return nil
+	})
+	return n, err
+}
+
+func publisherName(bk model.BookDetails) string {
+	if bk.Publisher == nil {
+		return ""
+	}
+	return bk.Publisher.Name
+}
@@ -0,0 +1,186 @@
+package catalog
+
+import (
+	"context"
+	"crud-example/internal/model"
+	"errors"
+	"fmt"
+	"io"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DefaultBatchSize = 500
+	// MaxReportedErrors caps the error list of a report, Invalid still counts every bad row
+	MaxReportedErrors = 100
+)
+
+type ImportOptions struct {
+	Format    Format
+	DryRun    bool // validate only, nothing is written
+	BatchSize int
+}
+
+type RowError struct {
+	Line int    `json:"line"`
+	Isbn string `json:"isbn,omitempty"`
+	Err  string `json:"error"`
+}
+
+type Report struct {
+	DryRun   bool       `json:"dryRun"`
+	Rows     int        `json:"rows"`
+	Valid    int        `json:"valid"`
+	Invalid  int        `json:"invalid"`
+	Inserted int64      `json:"inserted"`
+	Updated  int64      `json:"updated"`
+	Errors   []RowError `json:"errors"`
+}
+
+func (rep *Report) addError(line int, isbn string, err error) {
+	rep.Invalid++
+	if len(rep.Errors) < MaxReportedErrors {
+		rep.Errors = append(rep.Errors, RowError{Line: line, Isbn: isbn, Err: err.Error()})
+	}
+}
+
+// Import streams rows from r, validates each of them and upserts the valid ones by ISBN
+// in batches of opts.BatchSize. Invalid rows are reported and skipped, they never abort the import.
+// The returned error is only set for unreadable input or a failed database write.
+func Import(ctx context.Context, r io.Reader, opts ImportOptions) (Report, error) {
+	rep := Report{DryRun: opts.DryRun, Errors: []RowError{}}
+
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+
+	rows, err := newRowReader(r, opts.Format)
+	if err != nil {
+		return rep, err
+	}
+
+	// The same ISBN twice in one file would race inside an unordered bulk write
+	seen := map[string]int{}
+	refs := newRefCache(opts.DryRun)
+	batch := make([]model.Book, 0, opts.BatchSize)
+
+	flush := func() error {
+		if opts.DryRun || len(batch) == 0 {
+			batch = batch[:0]
+			return nil
+		}
+		res, err := model.UpsertBooks(ctx, batch)
+		rep.Inserted += res.Inserted
+		rep.Updated += res.Updated
+		batch = batch[:0]
+		return err
+	}
+
+	for {
+		row, err := rows.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return rep, err
+		}
+		rep.Rows++
+
+		bk, err := validate(row)
+		if err != nil {
+			rep.addError(row.Line, row.Isbn, err)
+			continue
+		}
+		if first, dup := seen[bk.Isbn]; dup {
+			rep.addError(row.Line, row.Isbn, fmt.Errorf("duplicate isbn, first seen on line %d", first))
+			continue
+		}
+		if err := refs.link(ctx, &bk, row); err != nil {
+			return rep, err
+		}
+		seen[bk.Isbn] = row.Line
+		rep.Valid++
+
+		batch = append(batch, bk)
+		if len(batch) == opts.BatchSize {
+			if err := flush(); err != nil {
+				return rep, err
+			}
+		}
+	}
+
+	return rep, flush()
+}
+
+func validate(row rawRow) (model.Book, error) {
+	if row.Err != nil {
+		return model.Book{}, row.Err
+	}
+	if row.Isbn == "" || row.Title == "" || row.Author == "" || row.Price == "" {
+		return model.Book{}, errors.New("isbn, title, author and price are required")
+	}
+
+	price, err := parsePrice(row.Price)
+	if err != nil {
+		return model.Book{}, err
+	}
+
+	return model.Book{
+		Isbn:  row.Isbn,
+		Title: row.Title,
+		Price: price,
+	}, nil
+}
+
+// refCache turns author and publisher names into ids, creating the missing entities once per import
+type refCache struct {
+	dryRun     bool
+	authors    map[string]primitive.ObjectID
+	publishers map[string]primitive.ObjectID
+}
+
+func newRefCache(dryRun bool) *refCache {
+	return &refCache{
+		dryRun:     dryRun,
+		authors:    map[string]primitive.ObjectID{},
+		publishers: map[string]primitive.ObjectID{},
+	}
+}
+
+// link is a no-op on a dry run so nothing gets created
+func (rc *refCache) link(ctx context.Context, bk *model.Book, row rawRow) error {
+	if rc.dryRun {
+		return nil
+	}
+
+	id, err := resolve(ctx, rc.authors, row.Author, model.EnsureAuthor)
+	if err != nil {
+		return err
+	}
+	bk.AuthorID = id
+
+	if row.Publisher != "" {
+		id, err := resolve(ctx, rc.publishers, row.Publisher, model.EnsurePublisher)
+		if err != nil {
+			return err
+		}
+		bk.PublisherID = id
+	}
+
+	return nil
+}
+
+func resolve(ctx context.Context, cache map[string]primitive.ObjectID, name string, ensure func(context.Context, string) (primitive.ObjectID, error)) (primitive.ObjectID, error) {
+	if id, ok := cache[name]; ok {
+		return id, nil
+	}
+
+	id, err := ensure(ctx, name)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	cache[name] = id
+
+	return id, nil
+}
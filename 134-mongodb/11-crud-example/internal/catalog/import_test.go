@@ -0,0 +1,66 @@
+package catalog
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// A dry run needs no database, nothing is linked or written
+func TestImportDryRun(t *testing.T) {
+	input := "isbn,title,author,price\n" +
+		"111,Dune,Frank Herbert,12.5\n" +
+		"222,,Austen,9\n" +
+		"333,Emma,Austen,-1\n" +
+		"444,Ulysses,Joyce,cheap\n" +
+		"111,Dune again,Frank Herbert,3\n" +
+		"555,Emma,Austen,9\n"
+
+	rep, err := Import(context.Background(), strings.NewReader(input), ImportOptions{Format: FormatCSV, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !rep.DryRun || rep.Rows != 6 || rep.Valid != 2 || rep.Invalid != 4 || rep.Inserted != 0 || rep.Updated != 0 {
+		t.Errorf("got %+v", rep)
+	}
+
+	want := []RowError{
+		{Line: 3, Isbn: "222", Err: "isbn, title, author and price are required"},
+		{Line: 4, Isbn: "333", Err: "price must not be negative"},
+		{Line: 5, Isbn: "444", Err: "price must be a number"},
+		{Line: 6, Isbn: "111", Err: "duplicate isbn, first seen on line 2"},
+	}
+	if len(rep.Errors) != len(want) {
+		t.Fatalf("got errors %+v, want %+v", rep.Errors, want)
+	}
+	for i := range want {
+		if rep.Errors[i] != want[i] {
+			t.Errorf("error %d: got %+v, want %+v", i, rep.Errors[i], want[i])
+		}
+	}
+}
+
+func TestImportCapsReportedErrors(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("isbn,title,author,price\n")
+	for range MaxReportedErrors + 5 {
+		b.WriteString("1,,,\n")
+	}
+
+	rep, err := Import(context.Background(), strings.NewReader(b.String()), ImportOptions{Format: FormatNDJSON, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// * read as ndjson every line is broken json
+	if rep.Invalid != MaxReportedErrors+6 || len(rep.Errors) != MaxReportedErrors {
+		t.Errorf("got %d invalid and %d reported, want %d and %d", rep.Invalid, len(rep.Errors), MaxReportedErrors+6, MaxReportedErrors)
+	}
+}
+
+func TestImportUnreadableInput(t *testing.T) {
+	_, err := Import(context.Background(), strings.NewReader("isbn,title\n"), ImportOptions{Format: FormatCSV, DryRun: true})
+	if err == nil {
+		t.Error("got no error for a header without the required columns")
+	}
+}
@@ -0,0 +1,171 @@
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// rawRow is a decoded but not yet validated row
+type rawRow struct {
+	Line      int
+	Isbn      string
+	Title     string
+	Author    string
+	Publisher string // optional
+	Price     string
+	Err       error // set when the row itself could not be decoded
+}
+
+// rowReader returns io.EOF after the last row, any other error aborts the import
+type rowReader interface {
+	Next() (rawRow, error)
+}
+
+func newRowReader(r io.Reader, f Format) (rowReader, error) {
+	switch f {
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatNDJSON:
+		return newNDJSONReader(r), nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, f)
+}
+
+type csvReader struct {
+	r   *csv.Reader
+	col map[string]int
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("csv: missing header row")
+		}
+		return nil, fmt.Errorf("csv: reading header: %w", err)
+	}
+
+	col := make(map[string]int, len(header))
+	for i, name := range header {
+		col[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range csvHeader {
+		if _, ok := col[name]; !ok && name != "publisher" {
+			return nil, fmt.Errorf("csv: header must contain %q", name)
+		}
+	}
+
+	return &csvReader{r: cr, col: col}, nil
+}
+
+func (c *csvReader) Next() (rawRow, error) {
+	rec, err := c.r.Read()
+	if errors.Is(err, io.EOF) {
+		return rawRow{}, io.EOF
+	}
+
+	var pErr *csv.ParseError
+	if errors.As(err, &pErr) {
+		// A broken row is reported, the reader carries on with the next one
+		return rawRow{Line: pErr.Line, Err: pErr.Err}, nil
+	}
+	if err != nil {
+		return rawRow{}, err
+	}
+
+	line, _ := c.r.FieldPos(0)
+	field := func(name string) string {
+		i, ok := c.col[name]
+		if !ok || i >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[i])
+	}
+
+	return rawRow{
+		Line:      line,
+		Isbn:      field("isbn"),
+		Title:     field("title"),
+		Author:    field("author"),
+		Publisher: field("publisher"),
+		Price:     field("price"),
+	}, nil
+}
+
+type ndjsonReader struct {
+	sc   *bufio.Scanner
+	line int
+}
+
+// maxNDJSONLine bounds the memory used by a single record
+const maxNDJSONLine = 1 << 20
+
+func newNDJSONReader(r io.Reader) *ndjsonReader {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxNDJSONLine)
+	return &ndjsonReader{sc: sc}
+}
+
+func (n *ndjsonReader) Next() (rawRow, error) {
+	for n.sc.Scan() {
+		n.line++
+		b := bytes.TrimSpace(n.sc.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+
+		var rec struct {
+			Isbn      string          `json:"isbn"`
+			Title     string          `json:"title"`
+			Author    string          `json:"author"`
+			Publisher string          `json:"publisher"`
+			Price     json.RawMessage `json:"price"`
+		}
+		if err := json.Unmarshal(b, &rec); err != nil {
+			return rawRow{Line: n.line, Err: err}, nil
+		}
+
+		// Accept both 12.5 and "12.5"
+		price := strings.Trim(string(rec.Price), `"`)
+		if price == "null" {
+			price = ""
+		}
+
+		return rawRow{
+			Line:      n.line,
+			Isbn:      strings.TrimSpace(rec.Isbn),
+			Title:     strings.TrimSpace(rec.Title),
+			Author:    strings.TrimSpace(rec.Author),
+			Publisher: strings.TrimSpace(rec.Publisher),
+			Price:     strings.TrimSpace(price),
+		}, nil
+	}
+
+	if err := n.sc.Err(); err != nil {
+		return rawRow{}, err
+	}
+	return rawRow{}, io.EOF
+}
+
+// parsePrice mirrors the checks done by the create and update forms
+func parsePrice(s string) (float64, error) {
+	price, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, errors.New("price must be a number")
+	}
+	if price < 0 {
+		return 0, errors.New("price must not be negative")
+	}
+	return price, nil
+}
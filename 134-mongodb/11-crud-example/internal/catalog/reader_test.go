@@ -0,0 +1,141 @@
+package catalog
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// readAll returns every row of input, it fails the test on an error other than io.EOF
+func readAll(t *testing.T, input string, f Format) []rawRow {
+	t.Helper()
+	rr, err := newRowReader(strings.NewReader(input), f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rows []rawRow
+	for {
+		row, err := rr.Next()
+		if errors.Is(err, io.EOF) {
+			return rows
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, row)
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	input := "Price, title ,ISBN,author\n" +
+		"12.5,Dune,111,Frank Herbert\n" +
+		"9,\"The \"\"Hobbit\"\"\",222,Tolkien\n" +
+		"3,\"unterminated,333,x\n"
+
+	rows := readAll(t, input, FormatCSV)
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3: %+v", len(rows), rows)
+	}
+
+	want := rawRow{Line: 2, Isbn: "111", Title: "Dune", Author: "Frank Herbert", Price: "12.5"}
+	if rows[0] != want {
+		t.Errorf("got %+v, want %+v", rows[0], want)
+	}
+	if rows[1].Title != `The "Hobbit"` || rows[1].Line != 3 {
+		t.Errorf("got %+v", rows[1])
+	}
+	if rows[2].Err == nil || rows[2].Line != 4 {
+		t.Errorf("got %+v, want the broken quote reported on line 4", rows[2])
+	}
+}
+
+func TestCSVReaderShortRow(t *testing.T) {
+	rows := readAll(t, "isbn,title,author,publisher,price\n111,Dune\n", FormatCSV)
+	if len(rows) != 1 || rows[0].Author != "" || rows[0].Price != "" || rows[0].Err != nil {
+		t.Errorf("got %+v, want a row with the missing fields empty", rows)
+	}
+}
+
+func TestCSVReaderHeader(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{"empty", "", "missing header row"},
+		{"missing column", "isbn,title,price\n", `header must contain "author"`},
+		{"publisher optional", "isbn,title,author,price\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newRowReader(strings.NewReader(tt.input), FormatCSV)
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got %v, want an error containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestNDJSONReader(t *testing.T) {
+	input := `{"isbn":"111","title":"Dune","author":"Frank Herbert","price":12.5}` + "\n" +
+		"\n" +
+		`{"isbn":" 222 ","title":"Emma","author":"Austen","publisher":"Penguin","price":"9"}` + "\n" +
+		`{"isbn":"333","title":"Null","author":"x","price":null}` + "\n" +
+		`{"isbn":` + "\n"
+
+	rows := readAll(t, input, FormatNDJSON)
+	if len(rows) != 4 {
+		t.Fatalf("got %d rows, want 4: %+v", len(rows), rows)
+	}
+
+	tests := []rawRow{
+		{Line: 1, Isbn: "111", Title: "Dune", Author: "Frank Herbert", Price: "12.5"},
+		{Line: 3, Isbn: "222", Title: "Emma", Author: "Austen", Publisher: "Penguin", Price: "9"},
+		{Line: 4, Isbn: "333", Title: "Null", Author: "x"},
+	}
+	for i, want := range tests {
+		if rows[i] != want {
+			t.Errorf("row %d: got %+v, want %+v", i, rows[i], want)
+		}
+	}
+	if rows[3].Err == nil || rows[3].Line != 5 {
+		t.Errorf("got %+v, want the broken json reported on line 5", rows[3])
+	}
+}
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		err  string
+	}{
+		{"12.5", 12.5, ""},
+		{"0", 0, ""},
+		{"1e2", 100, ""},
+		{"-1", 0, "price must not be negative"},
+		{"12,5", 0, "price must be a number"},
+		{"", 0, "price must be a number"},
+	}
+
+	for _, tt := range tests {
+		got, err := parsePrice(tt.in)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("parsePrice(%q): got %v, want %q", tt.in, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parsePrice(%q): got (%v, %v), want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -2,7 +2,15 @@ package constant
 
 var (
 	SlugISBN             = "isbn"
+	SlugID               = "id"
 	ErrMissingISBN       = "isbn field must be provided"
 	ErrMissingSomeFields = "All fields must be complete"
 	ErrInvalidPriceField = "Enter number for price"
+	ErrMissingVersion    = "version must be sent in the If-Match header or the version field"
+	ErrInvalidVersion    = "version must be a number"
+	ErrMissingImportFile = "choose a csv or ndjson file to import"
+	ErrInvalidID         = "invalid id"
+	ErrMissingName       = "name must be provided"
+	ErrInvalidQty        = "quantity must be a non-zero number"
+	ErrInvalidStock      = "stock must be a whole number"
 )
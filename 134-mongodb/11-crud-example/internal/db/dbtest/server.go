@@ -0,0 +1,382 @@
+// Package dbtest runs a fake mongod for tests that need the driver to talk to a server
+// without a real MongoDB, e.g. to check that a cancelled context aborts a query in flight.
+package dbtest
+
+import (
+	"context"
+	"crud-example/config"
+	"crud-example/internal/db"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Wire protocol op codes
+const (
+	opReply = 1
+	opQuery = 2004
+	opMsg   = 2013
+)
+
+// Server answers the handshake and every command with ok, except the commands it was told
+// to hang on: those never get a reply, so they stay in flight until the client gives up.
+// On scripts the reply of a command, Received returns the commands that arrived.
+type Server struct {
+	ln   net.Listener
+	hang map[string]bool
+	hung chan string
+
+	mu         sync.Mutex
+	standalone bool // answers the handshake like a mongod outside of a replica set
+	conns      map[net.Conn]struct{}
+	replies    map[string]Reply
+	received   []bson.Raw
+	wg         sync.WaitGroup
+}
+
+// Reply builds the answer to a command from the command document
+type Reply func(cmd bson.Raw) bson.D
+
+func NewServer(hang ...string) (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		ln:      ln,
+		hang:    map[string]bool{},
+		hung:    make(chan string, 16),
+		conns:   map[net.Conn]struct{}{},
+		replies: map[string]Reply{},
+	}
+	for _, cmd := range hang {
+		s.hang[cmd] = true
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Connect starts a Server, a single node replica set, and points the db package at it until
+// the test ends
+func Connect(t *testing.T, hang ...string) *Server {
+	t.Helper()
+	return connect(t, false, hang)
+}
+
+// ConnectStandalone is Connect for a standalone mongod
+func ConnectStandalone(t *testing.T, hang ...string) *Server {
+	t.Helper()
+	return connect(t, true, hang)
+}
+
+func connect(t *testing.T, standalone bool, hang []string) *Server {
+	t.Helper()
+
+	s, err := NewServer(hang...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	s.standalone = standalone
+	s.mu.Unlock()
+
+	cfg := config.Default().Mongo
+	cfg.URI = s.URI()
+	if err := db.Connect(context.Background(), cfg); err != nil {
+		s.Close()
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		db.Disconnect(ctx)
+		s.Close()
+	})
+	return s
+}
+
+func (s *Server) URI() string {
+	return fmt.Sprintf("mongodb://%s/test?directConnection=true", s.ln.Addr())
+}
+
+// On answers every cmd command with reply instead of ok
+func (s *Server) On(cmd string, reply Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replies[cmd] = reply
+}
+
+// Received returns the cmd commands received so far, in order
+func (s *Server) Received(cmd string) []bson.Raw {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var docs []bson.Raw
+	for _, doc := range s.received {
+		if name, _ := commandName(doc); name == cmd {
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
+
+// Cursor is the reply of a find or aggregate returning docs in a single batch
+func Cursor(ns string, docs ...any) bson.D {
+	batch := bson.A{}
+	for _, doc := range docs {
+		batch = append(batch, doc)
+	}
+	return bson.D{
+		{Key: "cursor", Value: bson.D{
+			{Key: "id", Value: int64(0)},
+			{Key: "ns", Value: ns},
+			{Key: "firstBatch", Value: batch},
+		}},
+		{Key: "ok", Value: 1.0},
+	}
+}
+
+// Hung receives the name of every command left without a reply, once it has arrived
+func (s *Server) Hung() <-chan string {
+	return s.hung
+}
+
+func (s *Server) Close() {
+	s.ln.Close()
+
+	s.mu.Lock()
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.conns, c)
+				s.mu.Unlock()
+				c.Close()
+			}()
+			s.handle(c)
+		}()
+	}
+}
+
+func (s *Server) handle(c net.Conn) {
+	for {
+		header := make([]byte, 16)
+		if _, err := io.ReadFull(c, header); err != nil {
+			return
+		}
+		size := int(binary.LittleEndian.Uint32(header[0:]))
+		requestID := int32(binary.LittleEndian.Uint32(header[4:]))
+		opCode := int32(binary.LittleEndian.Uint32(header[12:]))
+		if size < 16 {
+			return
+		}
+
+		body := make([]byte, size-16)
+		if _, err := io.ReadFull(c, body); err != nil {
+			return
+		}
+
+		doc, err := command(opCode, body)
+		if err != nil {
+			return
+		}
+		cmd, err := commandName(doc)
+		if err != nil {
+			return
+		}
+
+		if s.hang[cmd] {
+			select {
+			case s.hung <- cmd:
+			default:
+			}
+			// The driver sends nothing more on this connection before the reply,
+			// the next read ends when the client closes it
+			continue
+		}
+
+		if _, err := c.Write(reply(opCode, requestID, s.response(cmd, doc))); err != nil {
+			return
+		}
+	}
+}
+
+// command returns the command document of a request
+func command(opCode int32, body []byte) (bson.Raw, error) {
+	var doc []byte
+	switch opCode {
+	case opQuery:
+		// flags, full collection name, number to skip and to return, then the query
+		i := 4
+		for i < len(body) && body[i] != 0 {
+			i++
+		}
+		doc = body[min(i+1+8, len(body)):]
+	case opMsg:
+		// flag bits, then the kind 0 section holding the command, document sequences follow it
+		if len(body) < 5 || body[4] != 0 {
+			return nil, errors.New("unexpected op_msg layout")
+		}
+		doc = body[5:]
+	default:
+		return nil, fmt.Errorf("unsupported op code %d", opCode)
+	}
+
+	if len(doc) < 4 {
+		return nil, errors.New("unreadable command")
+	}
+	size := int(binary.LittleEndian.Uint32(doc))
+	if size > len(doc) {
+		return nil, errors.New("unreadable command")
+	}
+	if opCode != opMsg || size == len(doc) {
+		return bson.Raw(doc[:size]), nil
+	}
+	return withSequences(bson.Raw(doc[:size]), doc[size:])
+}
+
+// withSequences adds the kind 1 sections of an op_msg to its command, the driver sends the
+// documents of insert, update and delete that way: {delete: "books"} + deletes [...]
+func withSequences(doc bson.Raw, sections []byte) (bson.Raw, error) {
+	var cmd bson.D
+	if err := bson.Unmarshal(doc, &cmd); err != nil {
+		return nil, err
+	}
+
+	for len(sections) > 0 {
+		if sections[0] != 1 || len(sections) < 5 {
+			return nil, errors.New("unexpected op_msg section")
+		}
+		size := int(binary.LittleEndian.Uint32(sections[1:]))
+		if size < 4 || 1+size > len(sections) {
+			return nil, errors.New("unreadable op_msg section")
+		}
+		seq := sections[5 : 1+size]
+		sections = sections[1+size:]
+
+		// identifier as a cstring, then the documents back to back
+		end := 0
+		for end < len(seq) && seq[end] != 0 {
+			end++
+		}
+		if end == len(seq) {
+			return nil, errors.New("unreadable op_msg section")
+		}
+		id, seq := string(seq[:end]), seq[end+1:]
+
+		docs := bson.A{}
+		for len(seq) >= 4 {
+			n := int(binary.LittleEndian.Uint32(seq))
+			if n < 5 || n > len(seq) {
+				return nil, errors.New("unreadable op_msg section")
+			}
+			docs = append(docs, bson.Raw(seq[:n]))
+			seq = seq[n:]
+		}
+		cmd = append(cmd, bson.E{Key: id, Value: docs})
+	}
+
+	return bson.Marshal(cmd)
+}
+
+// commandName is the first key of the command document
+func commandName(doc bson.Raw) (string, error) {
+	elems, err := doc.Elements()
+	if err != nil || len(elems) == 0 {
+		return "", errors.New("unreadable command")
+	}
+	return elems[0].Key(), nil
+}
+
+func (s *Server) response(cmd string, doc bson.Raw) bson.D {
+	switch cmd {
+	case "hello", "isMaster", "ismaster":
+		s.mu.Lock()
+		standalone := s.standalone
+		s.mu.Unlock()
+
+		hello := bson.D{
+			{Key: "helloOk", Value: true},
+			{Key: "isWritablePrimary", Value: true},
+			{Key: "ismaster", Value: true},
+		}
+		if !standalone {
+			hello = append(hello, bson.E{Key: "setName", Value: "rs0"}, bson.E{Key: "hosts", Value: bson.A{s.ln.Addr().String()}})
+		}
+		return append(hello, bson.D{
+			{Key: "logicalSessionTimeoutMinutes", Value: 30},
+			{Key: "maxBsonObjectSize", Value: 16 * 1024 * 1024},
+			{Key: "maxMessageSizeBytes", Value: 48000000},
+			{Key: "maxWriteBatchSize", Value: 100000},
+			{Key: "localTime", Value: time.Now()},
+			{Key: "minWireVersion", Value: 0},
+			{Key: "maxWireVersion", Value: 21},
+			{Key: "ok", Value: 1.0},
+		}...)
+	}
+
+	s.mu.Lock()
+	s.received = append(s.received, slices.Clone(doc))
+	reply := s.replies[cmd]
+	s.mu.Unlock()
+
+	if reply != nil {
+		return reply(doc)
+	}
+	return bson.D{{Key: "ok", Value: 1.0}}
+}
+
+// reply answers in the op code of the request, legacy handshakes use OP_QUERY and get an OP_REPLY
+func reply(opCode, responseTo int32, doc bson.D) []byte {
+	raw, _ := bson.Marshal(doc)
+
+	var body []byte
+	if opCode == opQuery {
+		body = make([]byte, 20) // response flags, cursor id, starting from, number returned
+		binary.LittleEndian.PutUint32(body[16:], 1)
+		body = append(body, raw...)
+		opCode = opReply
+	} else {
+		body = append([]byte{0, 0, 0, 0, 0}, raw...) // flag bits, kind 0 section
+	}
+
+	msg := make([]byte, 16, 16+len(body))
+	binary.LittleEndian.PutUint32(msg[0:], uint32(16+len(body)))
+	binary.LittleEndian.PutUint32(msg[8:], uint32(responseTo))
+	binary.LittleEndian.PutUint32(msg[12:], uint32(opCode))
+	return append(msg, body...)
+}
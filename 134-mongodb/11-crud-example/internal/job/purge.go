@@ -0,0 +1,38 @@
+package job
+
+import (
+	"context"
+	"crud-example/internal/model"
+	"log"
+	"time"
+)
+
+// now is swapped by the tests
+var now = time.Now
+
+// PurgeTrash permanently deletes books that have been in the trash longer than retention.
+// It runs once right away and then every interval until ctx is cancelled.
+func PurgeTrash(ctx context.Context, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purge(ctx, retention)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func purge(ctx context.Context, retention time.Duration) {
+	cutoff := now().UTC().Add(-retention)
+	n, err := model.PurgeDeletedBefore(ctx, cutoff)
+	if err != nil {
+		log.Printf("Trash purge failed: %v\n", err)
+	} else if n > 0 {
+		log.Printf("Purged %d book(s) deleted before %s\n", n, cutoff.Format(time.RFC3339))
+	}
+}
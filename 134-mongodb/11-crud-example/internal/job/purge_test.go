@@ -0,0 +1,46 @@
+package job
+
+import (
+	"context"
+	"crud-example/internal/db/dbtest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestPurgeUsesRetention(t *testing.T) {
+	srv := dbtest.Connect(t)
+	srv.On("delete", func(bson.Raw) bson.D { return bson.D{{Key: "n", Value: 2}, {Key: "ok", Value: 1.0}} })
+
+	clock := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { now = time.Now })
+
+	purge(context.Background(), 30*24*time.Hour)
+
+	deletes := srv.Received("delete")
+	if len(deletes) != 1 {
+		t.Fatalf("got %d deletes, want 1", len(deletes))
+	}
+	var cmd struct {
+		Deletes []struct {
+			Q struct {
+				DeletedAt struct {
+					Lt time.Time `bson:"$lt"`
+				} `bson:"deletedAt"`
+			} `bson:"q"`
+		} `bson:"deletes"`
+	}
+	if err := bson.Unmarshal(deletes[0], &cmd); err != nil {
+		t.Fatal(err)
+	}
+	if len(cmd.Deletes) != 1 {
+		t.Fatalf("got %d delete statements, want 1", len(cmd.Deletes))
+	}
+
+	want := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	if got := cmd.Deletes[0].Q.DeletedAt.Lt; !got.Equal(want) {
+		t.Errorf("purged books deleted before %s, want before %s", got, want)
+	}
+}
@@ -0,0 +1,135 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Component is one piece of the application started and stopped by the Manager.
+// Start must return once the component is up, long running work belongs in goroutines
+// that report unrecoverable failures with Manager.Fail.
+type Component struct {
+	Name  string
+	Start func(ctx context.Context) error
+	Stop  func(ctx context.Context) error // optional
+	Retry *Backoff                        // optional, retries a failing Start
+}
+
+// Backoff retries up to Attempts times, doubling the delay from Initial up to Max
+type Backoff struct {
+	Attempts int
+	Initial  time.Duration
+	Max      time.Duration
+}
+
+// Manager starts components in the order they were added and stops them in reverse order
+type Manager struct {
+	components []Component
+	started    []Component
+
+	failOnce sync.Once
+	failed   chan error
+}
+
+func New() *Manager {
+	return &Manager{failed: make(chan error, 1)}
+}
+
+func (m *Manager) Add(c Component) {
+	m.components = append(m.components, c)
+}
+
+// Fail asks Run to shut everything down, only the first failure is kept
+func (m *Manager) Fail(err error) {
+	m.failOnce.Do(func() {
+		m.failed <- err
+	})
+}
+
+// Run starts every component, waits until ctx is done (e.g. on a signal) or a component fails,
+// then stops the started components in reverse order, each stop sharing the stopTimeout budget.
+func (m *Manager) Run(ctx context.Context, stopTimeout time.Duration) error {
+	startErr := m.Start(ctx)
+
+	var runErr error
+	if startErr == nil {
+		select {
+		case <-ctx.Done():
+			log.Println("Shutting down")
+		case runErr = <-m.failed:
+			log.Printf("Shutting down after failure: %v\n", runErr)
+		}
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+
+	return errors.Join(startErr, runErr, m.Stop(stopCtx))
+}
+
+// Start brings the components up in order. When one of them can't start, the ones already
+// started stay registered for Stop, so a failed startup still tears down cleanly.
+func (m *Manager) Start(ctx context.Context) error {
+	for _, c := range m.components {
+		if err := start(ctx, c); err != nil {
+			return fmt.Errorf("starting %s: %w", c.Name, err)
+		}
+		m.started = append(m.started, c)
+		log.Printf("Started %s\n", c.Name)
+	}
+	return nil
+}
+
+// Stop stops the started components in reverse order and reports every failure
+func (m *Manager) Stop(ctx context.Context) error {
+	var errs []error
+	for i := len(m.started) - 1; i >= 0; i-- {
+		c := m.started[i]
+		if c.Stop == nil {
+			continue
+		}
+		if err := c.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stopping %s: %w", c.Name, err))
+			continue
+		}
+		log.Printf("Stopped %s\n", c.Name)
+	}
+	m.started = nil
+
+	return errors.Join(errs...)
+}
+
+func start(ctx context.Context, c Component) error {
+	if c.Retry == nil {
+		return c.Start(ctx)
+	}
+
+	delay := c.Retry.Initial
+	var err error
+	for attempt := 1; attempt <= c.Retry.Attempts; attempt++ {
+		if err = c.Start(ctx); err == nil {
+			return nil
+		}
+		if attempt == c.Retry.Attempts {
+			break
+		}
+
+		log.Printf("Starting %s failed (attempt %d/%d), retrying in %s: %v\n",
+			c.Name, attempt, c.Retry.Attempts, delay, err)
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-t.C:
+		}
+		delay = min(delay*2, c.Retry.Max)
+	}
+
+	return err
+}
@@ -0,0 +1,126 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// recorder builds components that log their start and stop calls
+type recorder struct {
+	calls []string
+}
+
+func (r *recorder) component(name string, startErr error) Component {
+	return Component{
+		Name: name,
+		Start: func(context.Context) error {
+			r.calls = append(r.calls, "start "+name)
+			return startErr
+		},
+		Stop: func(context.Context) error {
+			r.calls = append(r.calls, "stop "+name)
+			return nil
+		},
+	}
+}
+
+func TestRunStopsInReverseOrder(t *testing.T) {
+	rec := &recorder{}
+	m := New()
+	m.Add(rec.component("db", nil))
+	m.Add(rec.component("jobs", nil))
+	m.Add(rec.component("http", nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel() // as if SIGINT arrived right after startup
+
+	if err := m.Run(ctx, time.Second); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	want := []string{"start db", "start jobs", "start http", "stop http", "stop jobs", "stop db"}
+	if !reflect.DeepEqual(rec.calls, want) {
+		t.Errorf("got %v, want %v", rec.calls, want)
+	}
+}
+
+func TestFailedStartStopsWhatWasStarted(t *testing.T) {
+	rec := &recorder{}
+	boom := errors.New("boom")
+	m := New()
+	m.Add(rec.component("db", nil))
+	m.Add(rec.component("http", boom))
+	m.Add(rec.component("never", nil))
+
+	err := m.Run(context.Background(), time.Second)
+	if !errors.Is(err, boom) {
+		t.Fatalf("got %v, want %v", err, boom)
+	}
+
+	want := []string{"start db", "start http", "stop db"}
+	if !reflect.DeepEqual(rec.calls, want) {
+		t.Errorf("got %v, want %v", rec.calls, want)
+	}
+}
+
+func TestFailShutsDown(t *testing.T) {
+	rec := &recorder{}
+	m := New()
+	m.Add(rec.component("http", nil))
+
+	boom := errors.New("listener closed")
+	go m.Fail(boom)
+
+	if err := m.Run(context.Background(), time.Second); !errors.Is(err, boom) {
+		t.Fatalf("got %v, want %v", err, boom)
+	}
+	if len(rec.calls) != 2 {
+		t.Errorf("got %v, want a start and a stop", rec.calls)
+	}
+}
+
+func TestStartRetriesWithBackoff(t *testing.T) {
+	attempts := 0
+	m := New()
+	m.Add(Component{
+		Name: "db",
+		Start: func(context.Context) error {
+			attempts++
+			if attempts < 3 {
+				return errors.New("connection refused")
+			}
+			return nil
+		},
+		Retry: &Backoff{Attempts: 5, Initial: time.Millisecond, Max: 2 * time.Millisecond},
+	})
+
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("got %d attempts, want %d", attempts, 3)
+	}
+}
+
+func TestStartGivesUpAfterAttempts(t *testing.T) {
+	attempts := 0
+	m := New()
+	m.Add(Component{
+		Name: "db",
+		Start: func(context.Context) error {
+			attempts++
+			return errors.New("connection refused")
+		},
+		Retry: &Backoff{Attempts: 3, Initial: time.Millisecond, Max: time.Millisecond},
+	})
+
+	if err := m.Start(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+	if attempts != 3 {
+		t.Errorf("got %d attempts, want %d", attempts, 3)
+	}
+}
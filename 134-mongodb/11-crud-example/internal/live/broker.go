@@ -0,0 +1,71 @@
+package live
+
+import "sync"
+
+// Event tells listeners that a book changed, Isbn is empty when it is unknown
+// (e.g. a hard delete or a change picked up by polling)
+type Event struct {
+	Type string `json:"type"` // insert, update, replace, delete or refresh
+	Isbn string `json:"isbn,omitempty"`
+}
+
+// subscriberBuffer is how many events a slow subscriber may lag behind before events are dropped for it
+const subscriberBuffer = 16
+
+// Broker fans events out to every subscriber without ever blocking the publisher
+type Broker struct {
+	mu     sync.Mutex
+	subs   map[chan Event]struct{}
+	closed bool
+}
+
+func NewBroker() *Broker {
+	return &Broker{subs: map[chan Event]struct{}{}}
+}
+
+// Subscribe returns the event channel and a function to stop listening
+func (b *Broker) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.closed {
+		close(ch)
+	} else {
+		b.subs[ch] = struct{}{}
+	}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+func (b *Broker) Publish(ev Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs {
+		select {
+		case ch <- ev:
+		default:
+			// The page reloads on any event, losing a few for a slow client is harmless
+		}
+	}
+}
+
+// Close ends every subscription, which lets long lived event streams return on shutdown
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for ch := range b.subs {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
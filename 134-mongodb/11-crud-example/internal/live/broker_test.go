@@ -0,0 +1,54 @@
+package live
+
+import "testing"
+
+func TestBrokerDoesNotBlockOnSlowSubscribers(t *testing.T) {
+	b := NewBroker()
+
+	slow, stopSlow := b.Subscribe()
+	defer stopSlow()
+	fast, stopFast := b.Subscribe()
+	defer stopFast()
+
+	// Nobody reads slow, publishing past its buffer must still return
+	for i := 0; i < subscriberBuffer*2; i++ {
+		b.Publish(Event{Type: "update", Isbn: "111"})
+		<-fast
+	}
+
+	if got := len(slow); got != subscriberBuffer {
+		t.Errorf("slow subscriber holds %d events, want %d", got, subscriberBuffer)
+	}
+}
+
+func TestBrokerUnsubscribeClosesChannel(t *testing.T) {
+	b := NewBroker()
+
+	events, stop := b.Subscribe()
+	stop()
+	stop() // stopping twice is harmless
+
+	if _, ok := <-events; ok {
+		t.Error("channel still open after unsubscribe")
+	}
+
+	b.Publish(Event{Type: "delete"})
+}
+
+func TestBrokerCloseEndsSubscriptions(t *testing.T) {
+	b := NewBroker()
+
+	events, stop := b.Subscribe()
+	b.Close()
+	stop() // unsubscribing after Close is harmless
+
+	if _, ok := <-events; ok {
+		t.Error("channel still open after Close")
+	}
+
+	late, _ := b.Subscribe()
+	if _, ok := <-late; ok {
+		t.Error("subscribing after Close returned an open channel")
+	}
+	b.Publish(Event{Type: "update"})
+}
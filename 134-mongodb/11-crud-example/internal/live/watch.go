@@ -0,0 +1,166 @@
+package live
+
+import (
+	"context"
+	"crud-example/internal/db"
+	"crud-example/internal/model"
+	ctxhelper "crud-example/pkg/util/context"
+	"encoding/binary"
+	"hash/fnv"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const maxRetryDelay = 30 * time.Second
+
+// Watch keeps the book cache coherent and publishes every change of the books collection
+// until ctx is cancelled. It uses a change stream and falls back to polling every
+// pollInterval when the stream can't be opened at start (e.g. a standalone mongod),
+// in which case changes made by other instances show up within pollInterval.
+func Watch(ctx context.Context, b *Broker, pollInterval time.Duration) {
+	model.EnableBookCache()
+	defer model.DisableBookCache()
+
+	notify := func(ev Event) {
+		model.InvalidateBookCache()
+		b.Publish(ev)
+	}
+
+	var (
+		resumeToken bson.Raw
+		streamed    bool // the change stream worked at least once
+		delay       = time.Second
+	)
+
+	for ctx.Err() == nil {
+		opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+		if resumeToken != nil {
+			opts.SetResumeAfter(resumeToken)
+		}
+
+		cs, err := books().Watch(ctx, mongo.Pipeline{}, opts)
+		if err != nil {
+			if !streamed {
+				log.Printf("Change streams unavailable, polling books every %s: %v\n", pollInterval, err)
+				poll(ctx, pollInterval, notify)
+				return
+			}
+			log.Printf("Reopening books change stream failed: %v\n", err)
+			if !sleep(ctx, delay) {
+				return
+			}
+			delay = min(delay*2, maxRetryDelay)
+			continue
+		}
+
+		streamed, delay = true, time.Second
+		// Changes may have been missed while the stream was down
+		notify(Event{Type: "refresh"})
+
+		for cs.Next(ctx) {
+			var change struct {
+				OperationType string `bson:"operationType"`
+				FullDocument  struct {
+					Isbn string `bson:"isbn"`
+				} `bson:"fullDocument"`
+			}
+			if err := cs.Decode(&change); err != nil {
+				log.Printf("Decoding books change failed: %v\n", err)
+				continue
+			}
+			resumeToken = cs.ResumeToken()
+			notify(Event{Type: change.OperationType, Isbn: change.FullDocument.Isbn})
+		}
+
+		if err := cs.Err(); err != nil && ctx.Err() == nil {
+			log.Printf("Books change stream stopped: %v\n", err)
+		}
+		cs.Close(context.Background())
+	}
+}
+
+// poll compares a fingerprint of every book with the previous one, which is cheap enough
+// for a fallback and catches changes (stock included) that don't bump the version
+func poll(ctx context.Context, interval time.Duration, notify func(Event)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last uint64
+	first := true
+	for {
+		sum, err := fingerprint(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Polling books failed: %v\n", err)
+			}
+		} else if first {
+			last, first = sum, false
+		} else if sum != last {
+			last = sum
+			notify(Event{Type: "refresh"})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func fingerprint(ctx context.Context) (uint64, error) {
+	ctx, cancel := ctxhelper.ForLong(ctx)
+	defer cancel()
+
+	projection := bson.M{"_id": 1, "version": 1, "stock": 1, "deletedAt": 1}
+	findOpts := options.Find().SetProjection(projection).SetSort(bson.M{"_id": 1})
+
+	cur, err := books().Find(ctx, bson.M{}, findOpts)
+	if err != nil {
+		return 0, err
+	}
+	defer cur.Close(ctx)
+
+	h := fnv.New64a()
+	for cur.Next(ctx) {
+		var doc struct {
+			ID        primitive.ObjectID `bson:"_id"`
+			Version   int64              `bson:"version"`
+			Stock     int64              `bson:"stock"`
+			DeletedAt *time.Time         `bson:"deletedAt"`
+		}
+		if err := cur.Decode(&doc); err != nil {
+			return 0, err
+		}
+
+		h.Write(doc.ID[:])
+		binary.Write(h, binary.LittleEndian, doc.Version)
+		binary.Write(h, binary.LittleEndian, doc.Stock)
+		if doc.DeletedAt != nil {
+			binary.Write(h, binary.LittleEndian, doc.DeletedAt.UnixNano())
+		}
+	}
+
+	return h.Sum64(), cur.Err()
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+func books() *mongo.Collection {
+	return db.Database().Collection("books")
+}
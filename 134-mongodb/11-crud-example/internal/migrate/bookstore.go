@@ -0,0 +1,55 @@
+package migrate
+
+import (
+	"context"
+	"crud-example/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Bookstore is the schema history of the bookstore database. Append new migrations with
+// the next version, never edit or renumber one that may have been applied somewhere.
+var Bookstore = []Migration{
+	{
+		Version: 1,
+		Name:    "book indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db.Collection("books"), model.Book{}.Indexes())
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("books"), model.Book{}.Indexes())
+		},
+	},
+	{
+		Version: 2,
+		Name:    "author and publisher name indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			if err := createIndexes(ctx, db.Collection("authors"), model.Author{}.Indexes()); err != nil {
+				return err
+			}
+			return createIndexes(ctx, db.Collection("publishers"), model.Publisher{}.Indexes())
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			if err := dropIndexes(ctx, db.Collection("publishers"), model.Publisher{}.Indexes()); err != nil {
+				return err
+			}
+			return dropIndexes(ctx, db.Collection("authors"), model.Author{}.Indexes())
+		},
+	},
+	{
+		Version: 3,
+		Name:    "backfill book version and stock",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			cl := db.Collection("books")
+			// Books created before optimistic concurrency and inventory existed
+			if _, err := cl.UpdateMany(ctx, bson.M{"version": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"version": 1}}); err != nil {
+				return err
+			}
+			_, err := cl.UpdateMany(ctx, bson.M{"stock": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"stock": 0}})
+			return err
+		},
+		// The backfilled values are what the code assumes for missing fields anyway
+		Down: func(context.Context, *mongo.Database) error { return nil },
+	},
+}
@@ -0,0 +1,96 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// errCodeIndexNotFound is returned by dropIndex for a missing index
+const errCodeIndexNotFound = 27
+
+// IndexDrift compares a collection with its declared indexes
+type IndexDrift struct {
+	Collection string
+	Missing    []string // declared but not in the database
+	Extra      []string // in the database but not declared, e.g. created by hand in the shell
+}
+
+// createIndexes is idempotent, MongoDB ignores an index that already exists with the same definition
+func createIndexes(ctx context.Context, cl *mongo.Collection, ims []mongo.IndexModel) error {
+	if _, err := cl.Indexes().CreateMany(ctx, ims); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("%s: duplicate values prevent a unique index, clean them up first: %w", cl.Name(), err)
+		}
+		return fmt.Errorf("%s: %w", cl.Name(), err)
+	}
+	return nil
+}
+
+// dropIndexes skips indexes that are already gone, so a half finished rollback can be repeated
+func dropIndexes(ctx context.Context, cl *mongo.Collection, ims []mongo.IndexModel) error {
+	for _, name := range indexNames(ims) {
+		_, err := cl.Indexes().DropOne(ctx, name)
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && cmdErr.Code == errCodeIndexNotFound {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%s: dropping index %s: %w", cl.Name(), name, err)
+		}
+	}
+	return nil
+}
+
+// Drift reports, per collection, the declared indexes that are missing and the undeclared ones
+func Drift(ctx context.Context, db *mongo.Database, declared map[string][]mongo.IndexModel) ([]IndexDrift, error) {
+	var out []IndexDrift
+	for _, name := range sortedKeys(declared) {
+		specs, err := db.Collection(name).Indexes().ListSpecifications(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		existing := map[string]bool{}
+		for _, s := range specs {
+			existing[s.Name] = true
+		}
+		d := IndexDrift{Collection: name}
+		for _, n := range indexNames(declared[name]) {
+			if !existing[n] {
+				d.Missing = append(d.Missing, n)
+			}
+			delete(existing, n)
+		}
+		delete(existing, "_id_")
+		for n := range existing {
+			d.Extra = append(d.Extra, n)
+		}
+		sort.Strings(d.Extra)
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// indexNames relies on every declared index having a name, see model.Book.Indexes
+func indexNames(ims []mongo.IndexModel) []string {
+	names := make([]string, 0, len(ims))
+	for _, im := range ims {
+		if im.Options != nil && im.Options.Name != nil {
+			names = append(names, *im.Options.Name)
+		}
+	}
+	return names
+}
+
+func sortedKeys(m map[string][]mongo.IndexModel) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
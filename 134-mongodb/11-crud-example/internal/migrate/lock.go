@@ -0,0 +1,75 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const lockID = "lock"
+
+// lockTTL lets another runner take over the lock of a runner that crashed while holding it
+const lockTTL = 15 * time.Minute
+
+var ErrLocked = errors.New("another migration runner holds the lock")
+
+// lock is a single document in the migrations collection, taken with an upsert filtered on
+// an expired lock. When a live lock exists the filter misses, the upsert tries to insert a
+// second document with the same _id and fails with a duplicate key error.
+type lock struct {
+	cl    *mongo.Collection
+	owner string
+}
+
+func newLock(cl *mongo.Collection) *lock {
+	host, _ := os.Hostname()
+	return &lock{cl: cl, owner: fmt.Sprintf("%s:%d:%d", host, os.Getpid(), time.Now().UnixNano())}
+}
+
+func (l *lock) with(ctx context.Context, fn func() error) error {
+	if err := l.acquire(ctx); err != nil {
+		return err
+	}
+
+	err := fn()
+
+	// Release even when ctx is done, otherwise the lock stays until it expires
+	relCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	return errors.Join(err, l.release(relCtx))
+}
+
+func (l *lock) acquire(ctx context.Context) error {
+	now := time.Now().UTC()
+	filter := bson.M{"_id": lockID, "expiresAt": bson.M{"$lt": now}}
+	update := bson.M{"$set": bson.M{"owner": l.owner, "lockedAt": now, "expiresAt": now.Add(lockTTL)}}
+
+	_, err := l.cl.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return l.lockedErr(ctx)
+	}
+	return err
+}
+
+func (l *lock) release(ctx context.Context) error {
+	_, err := l.cl.DeleteOne(ctx, bson.M{"_id": lockID, "owner": l.owner})
+	return err
+}
+
+// lockedErr names the holder, which helps deciding whether to wait or clean up by hand
+func (l *lock) lockedErr(ctx context.Context) error {
+	doc := struct {
+		Owner     string    `bson:"owner"`
+		ExpiresAt time.Time `bson:"expiresAt"`
+	}{}
+	if err := l.cl.FindOne(ctx, bson.M{"_id": lockID}).Decode(&doc); err != nil {
+		return ErrLocked
+	}
+	return fmt.Errorf("%w: held by %s until %s", ErrLocked, doc.Owner, doc.ExpiresAt.Format(time.RFC3339))
+}
@@ -0,0 +1,161 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collection records the applied versions, one document per version, next to the lock document
+const Collection = "migrations"
+
+var (
+	ErrUnknownVersion = errors.New("applied migration is not known to this build")
+	ErrNoSuchVersion  = errors.New("no migration with that version")
+)
+
+// Migration is one versioned schema change. MongoDB has no transactional DDL, so Up and Down
+// should be safe to run again after a partial failure, e.g. creating an index that exists is a no-op.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, db *mongo.Database) error
+	Down    func(ctx context.Context, db *mongo.Database) error
+}
+
+// Status is a migration of this build or a version found applied in the database
+type Status struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+	Unknown   bool // applied in the database but missing from this build
+}
+
+type record struct {
+	Version   int       `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"appliedAt"`
+}
+
+type Runner struct {
+	db         *mongo.Database
+	migrations []Migration
+	lock       *lock
+}
+
+// New checks the migrations are declared in increasing version order with both directions set
+func New(db *mongo.Database, migrations []Migration) (*Runner, error) {
+	if err := validate(migrations); err != nil {
+		return nil, err
+	}
+	return &Runner{db: db, migrations: migrations, lock: newLock(db.Collection(Collection))}, nil
+}
+
+// Up applies the pending migrations up to and including target, 0 applies all of them
+func (r *Runner) Up(ctx context.Context, target int) ([]Migration, error) {
+	if target != 0 && !r.known(target) {
+		return nil, fmt.Errorf("%w: %d", ErrNoSuchVersion, target)
+	}
+
+	var done []Migration
+	err := r.lock.with(ctx, func() error {
+		applied, err := r.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range pending(r.migrations, applied, target) {
+			log.Printf("Applying migration %d %s\n", m.Version, m.Name)
+			if err := m.Up(ctx, r.db); err != nil {
+				return fmt.Errorf("migration %d %s: %w", m.Version, m.Name, err)
+			}
+
+			rec := record{Version: m.Version, Name: m.Name, AppliedAt: time.Now().UTC()}
+			if _, err := r.records().InsertOne(ctx, rec); err != nil {
+				return fmt.Errorf("recording migration %d: %w", m.Version, err)
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+
+	return done, err
+}
+
+// Down rolls back the last steps applied migrations, newest first
+func (r *Runner) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := r.lock.with(ctx, func() error {
+		applied, err := r.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		ms, err := rollback(r.migrations, applied, steps)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range ms {
+			log.Printf("Rolling back migration %d %s\n", m.Version, m.Name)
+			if err := m.Down(ctx, r.db); err != nil {
+				return fmt.Errorf("migration %d %s: %w", m.Version, m.Name, err)
+			}
+
+			if _, err := r.records().DeleteOne(ctx, bson.M{"_id": m.Version}); err != nil {
+				return fmt.Errorf("unrecording migration %d: %w", m.Version, err)
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+
+	return done, err
+}
+
+// Status lists every migration of this build plus the unknown applied ones, by version
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return status(r.migrations, applied), nil
+}
+
+// applied reads the version records, the lock document is the only one without a numeric _id
+func (r *Runner) applied(ctx context.Context) (map[int]record, error) {
+	cur, err := r.records().Find(ctx, bson.M{"_id": bson.M{"$type": "number"}}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	var recs []record
+	if err := cur.All(ctx, &recs); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]record, len(recs))
+	for _, rec := range recs {
+		applied[rec.Version] = rec
+	}
+	return applied, nil
+}
+
+func (r *Runner) known(version int) bool {
+	for _, m := range r.migrations {
+		if m.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Runner) records() *mongo.Collection {
+	return r.db.Collection(Collection)
+}
@@ -0,0 +1,92 @@
+package migrate
+
+import (
+	"fmt"
+	"sort"
+)
+
+// The functions below decide what to run, they don't touch the database
+
+func validate(ms []Migration) error {
+	for i, m := range ms {
+		if m.Version <= 0 {
+			return fmt.Errorf("migration %q: version must be positive, got %d", m.Name, m.Version)
+		}
+		if i > 0 && m.Version <= ms[i-1].Version {
+			return fmt.Errorf("migration %d %s: versions must be declared in increasing order", m.Version, m.Name)
+		}
+		if m.Up == nil || m.Down == nil {
+			return fmt.Errorf("migration %d %s: both Up and Down are required", m.Version, m.Name)
+		}
+	}
+	return nil
+}
+
+// pending is every migration not applied yet up to target, 0 meaning no limit.
+// Gaps are filled too, a migration merged after a newer one was applied still runs.
+func pending(ms []Migration, applied map[int]record, target int) []Migration {
+	var out []Migration
+	for _, m := range ms {
+		if target != 0 && m.Version > target {
+			break
+		}
+		if _, ok := applied[m.Version]; !ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// rollback is the last steps applied migrations, newest first. An applied version this build
+// doesn't know can't be rolled back, so reaching one is an error rather than skipping past it.
+func rollback(ms []Migration, applied map[int]record, steps int) ([]Migration, error) {
+	byVersion := make(map[int]Migration, len(ms))
+	for _, m := range ms {
+		byVersion[m.Version] = m
+	}
+
+	versions := make([]int, 0, len(applied))
+	for v := range applied {
+		versions = append(versions, v)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+	var out []Migration
+	for _, v := range versions {
+		if len(out) == steps {
+			break
+		}
+		m, ok := byVersion[v]
+		if !ok {
+			return nil, fmt.Errorf("%w: %d %s", ErrUnknownVersion, v, applied[v].Name)
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+func status(ms []Migration, applied map[int]record) []Status {
+	out := make([]Status, 0, len(ms))
+	for _, m := range ms {
+		st := Status{Version: m.Version, Name: m.Name}
+		if rec, ok := applied[m.Version]; ok {
+			at := rec.AppliedAt
+			st.AppliedAt = &at
+		}
+		out = append(out, st)
+	}
+
+	known := make(map[int]bool, len(ms))
+	for _, m := range ms {
+		known[m.Version] = true
+	}
+	for v, rec := range applied {
+		if !known[v] {
+			at := rec.AppliedAt
+			out = append(out, Status{Version: v, Name: rec.Name, AppliedAt: &at, Unknown: true})
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out
+}
@@ -0,0 +1,128 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func noop(context.Context, *mongo.Database) error { return nil }
+
+func migrations(versions ...int) []Migration {
+	ms := make([]Migration, 0, len(versions))
+	for _, v := range versions {
+		ms = append(ms, Migration{Version: v, Name: "m", Up: noop, Down: noop})
+	}
+	return ms
+}
+
+func appliedVersions(versions ...int) map[int]record {
+	applied := map[int]record{}
+	for _, v := range versions {
+		applied[v] = record{Version: v, Name: "m", AppliedAt: time.Unix(int64(v), 0)}
+	}
+	return applied
+}
+
+func versionsOf(ms []Migration) []int {
+	vs := []int{}
+	for _, m := range ms {
+		vs = append(vs, m.Version)
+	}
+	return vs
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		ms      []Migration
+		wantErr bool
+	}{
+		{"increasing", migrations(1, 2, 5), false},
+		{"zero version", migrations(0, 1), true},
+		{"duplicate", migrations(1, 2, 2), true},
+		{"out of order", migrations(2, 1), true},
+		{"missing down", []Migration{{Version: 1, Name: "m", Up: noop}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validate(tt.ms); (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPending(t *testing.T) {
+	ms := migrations(1, 2, 3, 4)
+
+	tests := []struct {
+		name    string
+		applied map[int]record
+		target  int
+		want    []int
+	}{
+		{"fresh database", appliedVersions(), 0, []int{1, 2, 3, 4}},
+		{"up to target", appliedVersions(1), 3, []int{2, 3}},
+		{"fills gaps", appliedVersions(1, 3), 0, []int{2, 4}},
+		{"nothing left", appliedVersions(1, 2, 3, 4), 0, []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := versionsOf(pending(ms, tt.applied, tt.target)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRollback(t *testing.T) {
+	ms := migrations(1, 2, 3)
+
+	got, err := rollback(ms, appliedVersions(1, 2, 3), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{3, 2}; !reflect.DeepEqual(versionsOf(got), want) {
+		t.Errorf("got %v, want %v", versionsOf(got), want)
+	}
+
+	got, err = rollback(ms, appliedVersions(1), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1}; !reflect.DeepEqual(versionsOf(got), want) {
+		t.Errorf("more steps than applied: got %v, want %v", versionsOf(got), want)
+	}
+
+	// Version 9 was applied by a newer build, rolling back past it must stop
+	if _, err := rollback(ms, appliedVersions(1, 9), 1); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("got %v, want %v", err, ErrUnknownVersion)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	got := status(migrations(1, 2), appliedVersions(1, 7))
+
+	if len(got) != 3 {
+		t.Fatalf("got %d entries, want 3", len(got))
+	}
+	if got[0].AppliedAt == nil || got[1].AppliedAt != nil {
+		t.Errorf("got applied %v and %v, want only version 1 applied", got[0].AppliedAt, got[1].AppliedAt)
+	}
+	if got[2].Version != 7 || !got[2].Unknown {
+		t.Errorf("got %+v, want unknown version 7 last", got[2])
+	}
+}
+
+func TestBookstoreMigrationsAreValid(t *testing.T) {
+	if err := validate(Bookstore); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,85 @@
+package model
+
+import (
+	"context"
+	"crud-example/internal/db"
+	apperr "crud-example/pkg/util/app_err"
+	ctxhelper "crud-example/pkg/util/context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const authorsCollection = "authors"
+
+type Author struct {
+	ID      primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name    string             `json:"name" bson:"name"`
+	NameKey string             `json:"-" bson:"nameKey"` // normalized name, unique per author
+}
+
+func FetchAllAuthors(ctx context.Context) ([]Author, error) {
+	aus := []Author{}
+
+	cl := getAuthorsCollection()
+
+	ctx, cancel := ctxhelper.ForRead(ctx)
+	defer cancel()
+
+	findOpts := options.Find().SetSort(bson.D{{Key: "nameKey", Value: 1}})
+	cur, err := cl.Find(ctx, bson.M{}, findOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cur.All(ctx, &aus)
+	if err != nil {
+		return nil, err
+	}
+
+	return aus, nil
+}
+
+func FetchAuthor(ctx context.Context, id primitive.ObjectID) (Author, error) {
+	au := Author{}
+
+	cl := getAuthorsCollection()
+
+	ctx, cancel := ctxhelper.ForRead(ctx)
+	defer cancel()
+
+	err := cl.FindOne(ctx, bson.M{"_id": id}).Decode(&au)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return au, apperr.ErrAuthorNotFound
+	}
+
+	return au, err
+}
+
+// EnsureAuthor returns the author with that name (ignoring case and extra spaces), creating it when missing
+func EnsureAuthor(ctx context.Context, name string) (primitive.ObjectID, error) {
+	ctx, cancel := ctxhelper.ForWrite(ctx)
+	defer cancel()
+
+	return ensureNamed(ctx, getAuthorsCollection(), name)
+}
+
+// DeleteAuthor refuses with apperr.ErrAuthorInUse while books still reference the author
+func DeleteAuthor(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := ctxhelper.ForWrite(ctx)
+	defer cancel()
+
+	err := deleteUnreferenced(ctx, getAuthorsCollection(), id, "authorId", apperr.ErrAuthorInUse)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return apperr.ErrDeleteItemFailed
+	}
+
+	return err
+}
+
+func getAuthorsCollection() *mongo.Collection {
+	return db.Database().Collection(authorsCollection)
+}
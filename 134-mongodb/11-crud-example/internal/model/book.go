@@ -1,9 +1,12 @@
 package model
 
 import (
+	"context"
 	"crud-example/internal/db"
 	apperr "crud-example/pkg/util/app_err"
 	ctxhelper "crud-example/pkg/util/context"
+	"errors"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -11,30 +14,58 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+const booksCollection = "books"
+
 type Book struct {
-	ID     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	Isbn   string             `json:"isbn" bson:"isbn"`
-	Title  string             `json:"title" bson:"title"`
-	Author string             `json:"author" bson:"author"`
-	Price  float64            `json:"price" bson:"price"`
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Isbn        string             `json:"isbn" bson:"isbn"`
+	Title       string             `json:"title" bson:"title"`
+	AuthorID    primitive.ObjectID `json:"authorId" bson:"authorId"`
+	PublisherID primitive.ObjectID `json:"publisherId,omitempty" bson:"publisherId,omitempty"`
+	Price       float64            `json:"price" bson:"price"`
+	Stock       int                `json:"stock" bson:"stock"`     // only changed by AdjustStock and orders
+	Version     int64              `json:"version" bson:"version"` // bumped on every update
+	DeletedAt   *time.Time         `json:"deletedAt,omitempty" bson:"deletedAt,omitempty"`
+}
+
+// BookDetails is a book with its author and publisher embedded by $lookup
+type BookDetails struct {
+	Book      `bson:",inline"`
+	Author    Author     `json:"author" bson:"author"`
+	Publisher *Publisher `json:"publisher,omitempty" bson:"publisher,omitempty"`
 }
 
-func FetchAllBooks() ([]Book, error) {
-	bks := []Book{}
+func FetchAllBooks(ctx context.Context) ([]BookDetails, error) {
+	bks, ok, gen := booksCache.getAll()
+	if ok {
+		return bks, nil
+	}
+
+	bks, err := fetchAllBooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	booksCache.putAll(gen, bks)
+	return bks, nil
+}
+
+func fetchAllBooks(ctx context.Context) ([]BookDetails, error) {
+	bks := []BookDetails{}
 
 	cl := getBooksCollection()
 
-	ctx, cancel := ctxhelper.CtxWithNormalTimeout()
+	ctx, cancel := ctxhelper.ForRead(ctx)
 	defer cancel()
 
-	cur, err := cl.Find(ctx, bson.M{}, nil)
+	cur, err := cl.Aggregate(ctx, detailsPipeline(notDeleted(bson.M{})))
 	if err != nil {
 		return nil, err
 	}
 
 	// Manual
 	// if cur.Next(ctx) {
-	// 	bk := Book{}
+	// 	bk := BookDetails{}
 	// 	err := cur.Decode(bk)
 	// }
 	// defer cur.Close(ctx)
@@ -47,15 +78,30 @@ func FetchAllBooks() ([]Book, error) {
 	return bks, nil
 }
 
-func FetchBook(isbn string) (Book, error) {
+func FetchBook(ctx context.Context, isbn string) (Book, error) {
+	bk, ok, gen := booksCache.get(isbn)
+	if ok {
+		return bk, nil
+	}
+
+	bk, err := fetchBook(ctx, isbn)
+	if err != nil {
+		return bk, err
+	}
+
+	booksCache.put(gen, bk)
+	return bk, nil
+}
+
+func fetchBook(ctx context.Context, isbn string) (Book, error) {
 	bk := Book{}
 
 	cl := getBooksCollection()
 
-	ctx, cancel := ctxhelper.CtxWithNormalTimeout()
+	ctx, cancel := ctxhelper.ForRead(ctx)
 	defer cancel()
 
-	res := cl.FindOne(ctx, bson.M{"isbn": isbn})
+	res := cl.FindOne(ctx, notDeleted(bson.M{"isbn": isbn}))
 
 	if err := res.Err(); err != nil {
 		return bk, err
@@ -65,59 +111,155 @@ func FetchBook(isbn string) (Book, error) {
 	return bk, err
 }
 
-func InsertBook(book Book) (primitive.ObjectID, error) {
+// InsertBook writes the book along with the check of its author and publisher, in one
+// transaction, and returns it with both of them
+func InsertBook(ctx context.Context, book Book) (BookDetails, error) {
 	cl := getBooksCollection()
+	defer InvalidateBookCache()
 
-	ctx, cancel := ctxhelper.CtxWithNormalTimeout()
+	ctx, cancel := ctxhelper.ForWrite(ctx)
 	defer cancel()
 
-	res, err := cl.InsertOne(ctx, book)
-	if err != nil {
-		return primitive.NilObjectID, err
-	}
+	book.Version = 1
+	var details BookDetails
+	err := inTransaction(ctx, func(sc mongo.SessionContext) error {
+		var err error
+		if details, err = lockBookRefs(sc, book); err != nil {
+			return err
+		}
 
-	return res.InsertedID.(primitive.ObjectID), nil
+		res, err := cl.InsertOne(sc, book)
+		if err != nil {
+			return err
+		}
+		details.ID = res.InsertedID.(primitive.ObjectID)
+		return nil
+	})
+	return details, err
 }
 
-func UpdateBook(isbn string, book Book) (Book, error) {
+// UpdateBook replaces the book fields only if the stored version still matches book.Version.
+// When the stored version has moved on, the current stored book is returned along with
+// apperr.ErrVersionConflict. The author and publisher are checked in the same transaction.
+func UpdateBook(ctx context.Context, isbn string, book Book) (Book, error) {
 	cl := getBooksCollection()
+	defer InvalidateBookCache()
 
-	ctx, cancel := ctxhelper.CtxWithNormalTimeout()
+	ctx, cancel := ctxhelper.ForWrite(ctx)
 	defer cancel()
 
-	bk := Book{}
-	upOpts := options.FindOneAndUpdate().SetReturnDocument(options.After)
-	res := cl.FindOneAndUpdate(ctx, bson.M{"isbn": isbn}, bson.M{"$set": book}, upOpts)
-	if err := res.Err(); err != nil {
-		return bk, err
+	filter := notDeleted(bson.M{"isbn": isbn, "version": book.Version})
+	if book.Version == 0 {
+		// Documents created before versioning have no version field
+		filter["version"] = bson.M{"$exists": false}
 	}
+	update := bookUpdate(book)
+	update["$inc"] = bson.M{"version": 1}
 
-	if err := res.Decode(&bk); err != nil {
-		return bk, err
-	}
+	var bk Book
+	err := inTransaction(ctx, func(sc mongo.SessionContext) error {
+		bk = Book{}
+		if _, err := lockBookRefs(sc, book); err != nil {
+			return err
+		}
 
-	return bk, nil
+		upOpts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		err := cl.FindOneAndUpdate(sc, filter, update, upOpts).Decode(&bk)
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return err
+		}
+
+		// Either the book is gone or someone else updated it in the meantime
+		if err := cl.FindOne(sc, notDeleted(bson.M{"isbn": isbn})).Decode(&bk); err != nil {
+			return err
+		}
+		return apperr.ErrVersionConflict
+	})
+	return bk, err
 }
 
-func DeleteBook(isbn string) error {
+// DeleteBook moves the book to the trash by setting its deletedAt timestamp.
+// Use PurgeBook to remove it permanently.
+func DeleteBook(ctx context.Context, isbn string) error {
 	cl := getBooksCollection()
+	defer InvalidateBookCache()
 
-	ctx, cancel := ctxhelper.CtxWithNormalTimeout()
+	ctx, cancel := ctxhelper.ForWrite(ctx)
 	defer cancel()
 
-	res, err := cl.DeleteOne(ctx, bson.M{"isbn": isbn}, nil)
+	update := bson.M{
+		"$set": bson.M{"deletedAt": time.Now().UTC()},
+		"$inc": bson.M{"version": 1},
+	}
+	res, err := cl.UpdateOne(ctx, notDeleted(bson.M{"isbn": isbn}), update)
 	if err != nil {
 		return err
 	}
 
-	if res.DeletedCount == 0 {
+	if res.MatchedCount == 0 {
 		return apperr.ErrDeleteItemFailed
 	}
 
 	return nil
 }
 
+// AdjustStock adds delta (negative to remove) to the stock of a book, refusing to go below zero
+func AdjustStock(ctx context.Context, isbn string, delta int) (Book, error) {
+	cl := getBooksCollection()
+	defer InvalidateBookCache()
+
+	ctx, cancel := ctxhelper.ForWrite(ctx)
+	defer cancel()
+
+	bk := Book{}
+	filter := notDeleted(bson.M{"isbn": isbn})
+	if delta < 0 {
+		filter["stock"] = bson.M{"$gte": -delta}
+	}
+
+	upOpts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	res := cl.FindOneAndUpdate(ctx, filter, bson.M{"$inc": bson.M{"stock": delta}}, upOpts)
+	if err := res.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) && delta < 0 {
+			if _, err := FetchBook(ctx, isbn); err == nil {
+				return bk, apperr.ErrOutOfStock
+			}
+		}
+		return bk, err
+	}
+
+	err := res.Decode(&bk)
+	return bk, err
+}
+
+// bookUpdate sets the editable fields of a book, an empty publisher is removed
+func bookUpdate(book Book) bson.M {
+	update := bson.M{
+		"$set": bson.M{
+			"isbn":     book.Isbn,
+			"title":    book.Title,
+			"authorId": book.AuthorID,
+			"price":    book.Price,
+		},
+	}
+
+	if book.PublisherID.IsZero() {
+		update["$unset"] = bson.M{"publisherId": ""}
+	} else {
+		update["$set"].(bson.M)["publisherId"] = book.PublisherID
+	}
+
+	return update
+}
+
+// notDeleted narrows the filter to books that are not in the trash.
+// {deletedAt: null} matches both a missing and a null field.
+func notDeleted(filter bson.M) bson.M {
+	filter["deletedAt"] = nil
+	return filter
+}
+
 func getBooksCollection() *mongo.Collection {
 	// Get collection
-	return db.MongoClient.Database("bookstore").Collection("books")
+	return db.Database().Collection(booksCollection)
 }
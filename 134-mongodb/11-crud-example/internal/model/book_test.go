@@ -0,0 +1,114 @@
+package model
+
+import (
+	"context"
+	"crud-example/internal/db/dbtest"
+	apperr "crud-example/pkg/util/app_err"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// findAndModifyReply answers a findAndModify with doc, nil when nothing matched
+func findAndModifyReply(doc any) dbtest.Reply {
+	return func(bson.Raw) bson.D {
+		return bson.D{{Key: "value", Value: doc}, {Key: "ok", Value: 1.0}}
+	}
+}
+
+// onCollection answers with the reply of the collection the command targets, ok for the others
+func onCollection(replies map[string]dbtest.Reply) dbtest.Reply {
+	return func(cmd bson.Raw) bson.D {
+		if reply, ok := replies[cmd.Index(0).Value().StringValue()]; ok {
+			return reply(cmd)
+		}
+		return bson.D{{Key: "ok", Value: 1.0}}
+	}
+}
+
+// withAuthor answers the lock of the author by lockBookRefs, and the books with books
+func withAuthor(books dbtest.Reply) dbtest.Reply {
+	return onCollection(map[string]dbtest.Reply{
+		authorsCollection: findAndModifyReply(bson.M{"name": "Frank Herbert"}),
+		booksCollection:   books,
+	})
+}
+
+func cursorReply(docs ...any) dbtest.Reply {
+	return func(bson.Raw) bson.D { return dbtest.Cursor("test."+booksCollection, docs...) }
+}
+
+// lastCommand is the last cmd command the server received
+func lastCommand(t *testing.T, srv *dbtest.Server, cmd string) bson.Raw {
+	t.Helper()
+	docs := srv.Received(cmd)
+	if len(docs) == 0 {
+		t.Fatalf("no %s was sent", cmd)
+	}
+	return docs[len(docs)-1]
+}
+
+func TestUpdateBookMatchesVersion(t *testing.T) {
+	srv := dbtest.Connect(t)
+	srv.On("findAndModify", withAuthor(findAndModifyReply(bson.M{"isbn": "1", "title": "New", "version": int64(4)})))
+
+	bk, err := UpdateBook(context.Background(), "1", Book{Isbn: "1", Title: "New", Version: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bk.Version != 4 {
+		t.Errorf("got version %d, want 4", bk.Version)
+	}
+
+	cmd := lastCommand(t, srv, "findAndModify")
+	if v := cmd.Lookup("query", "version").AsInt64(); v != 3 {
+		t.Errorf("query version %d, want 3", v)
+	}
+	if v := cmd.Lookup("query", "deletedAt"); v.Type != bson.TypeNull {
+		t.Errorf("query deletedAt %v, want null so trashed books are left alone", v)
+	}
+	if v := cmd.Lookup("update", "$inc", "version").AsInt64(); v != 1 {
+		t.Errorf("version incremented by %d, want 1", v)
+	}
+}
+
+func TestUpdateBookWithoutVersionField(t *testing.T) {
+	srv := dbtest.Connect(t)
+	srv.On("findAndModify", withAuthor(findAndModifyReply(bson.M{"isbn": "1", "version": int64(1)})))
+
+	if _, err := UpdateBook(context.Background(), "1", Book{Isbn: "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := lastCommand(t, srv, "findAndModify")
+	if exists, ok := cmd.Lookup("query", "version", "$exists").BooleanOK(); !ok || exists {
+		t.Errorf("query version %v, want {$exists: false} for documents created before versioning", cmd.Lookup("query", "version"))
+	}
+}
+
+func TestUpdateBookVersionConflict(t *testing.T) {
+	srv := dbtest.Connect(t)
+	srv.On("findAndModify", withAuthor(findAndModifyReply(nil)))
+	srv.On("find", cursorReply(bson.M{"isbn": "1", "title": "Theirs", "version": int64(7)}))
+
+	bk, err := UpdateBook(context.Background(), "1", Book{Isbn: "1", Title: "Mine", Version: 3})
+	if !errors.Is(err, apperr.ErrVersionConflict) {
+		t.Fatalf("got %v, want %v", err, apperr.ErrVersionConflict)
+	}
+	if bk.Version != 7 || bk.Title != "Theirs" {
+		t.Errorf("got %+v, want the stored book to show the conflict", bk)
+	}
+}
+
+func TestUpdateBookGone(t *testing.T) {
+	srv := dbtest.Connect(t)
+	srv.On("findAndModify", withAuthor(findAndModifyReply(nil)))
+	srv.On("find", cursorReply())
+
+	_, err := UpdateBook(context.Background(), "1", Book{Isbn: "1", Version: 3})
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Errorf("got %v, want %v rather than a conflict", err, mongo.ErrNoDocuments)
+	}
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"context"
+	"crud-example/internal/db"
+	ctxhelper "crud-example/pkg/util/context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type UpsertResult struct {
+	Inserted int64
+	Updated  int64
+}
+
+// UpsertBooks writes the batch with a single unordered bulk write keyed by ISBN.
+// Existing books get their fields replaced and their version bumped,
+// a book sitting in the trash is brought back.
+// The authors and publishers of the batch are checked in the same transaction as the write,
+// the batch fails when one of them was deleted since its id was looked up.
+func UpsertBooks(ctx context.Context, bks []Book) (UpsertResult, error) {
+	if len(bks) == 0 {
+		return UpsertResult{}, nil
+	}
+
+	cl := getBooksCollection()
+	defer InvalidateBookCache()
+
+	ctx, cancel := ctxhelper.ForWrite(ctx)
+	defer cancel()
+
+	models := make([]mongo.WriteModel, 0, len(bks))
+	for _, bk := range bks {
+		update := bookUpdate(bk)
+		unset, _ := update["$unset"].(bson.M)
+		if unset == nil {
+			unset = bson.M{}
+		}
+		unset["deletedAt"] = ""
+		update["$unset"] = unset
+		// $inc on a missing field sets it, so inserted books start at version 1
+		update["$inc"] = bson.M{"version": 1}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"isbn": bk.Isbn}).
+			SetUpdate(update).
+			SetUpsert(true))
+	}
+
+	var res *mongo.BulkWriteResult
+	err := inTransaction(ctx, func(sc mongo.SessionContext) error {
+		if err := lockBatchRefs(sc, bks); err != nil {
+			return err
+		}
+
+		var err error
+		res, err = cl.BulkWrite(sc, models, options.BulkWrite().SetOrdered(false))
+		return err
+	})
+	// * an aborted transaction wrote nothing, without one the unordered write kept what succeeded
+	if res == nil || err != nil && db.SupportsTransactions() {
+		return UpsertResult{}, err
+	}
+
+	return UpsertResult{Inserted: res.UpsertedCount, Updated: res.MatchedCount}, err
+}
+
+// StreamBooks calls fn for every book that is not in the trash, ordered by ISBN,
+// without loading the whole catalog in memory. Iteration stops at the first error returned by fn.
+func StreamBooks(ctx context.Context, fn func(BookDetails) error) error {
+	cl := getBooksCollection()
+
+	ctx, cancel := ctxhelper.ForLong(ctx)
+	defer cancel()
+
+	sort := bson.D{{Key: "$sort", Value: bson.D{{Key: "isbn", Value: 1}}}}
+	cur, err := cl.Aggregate(ctx, detailsPipeline(notDeleted(bson.M{}), sort))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		bk := BookDetails{}
+		if err := cur.Decode(&bk); err != nil {
+			return err
+		}
+		if err := fn(bk); err != nil {
+			return err
+		}
+	}
+
+	return cur.Err()
+}
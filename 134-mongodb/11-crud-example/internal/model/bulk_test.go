@@ -0,0 +1,86 @@
+package model
+
+import (
+	"context"
+	"crud-example/internal/db/dbtest"
+	apperr "crud-example/pkg/util/app_err"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// commandsOn are the cmd commands that ran on a collection
+func commandsOn(srv *dbtest.Server, cmd, collection string) []bson.Raw {
+	var out []bson.Raw
+	for _, c := range srv.Received(cmd) {
+		if c.Index(0).Value().StringValue() == collection {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func TestUpsertBooksLocksRefs(t *testing.T) {
+	srv := dbtest.Connect(t)
+	frank, ursula, chilton := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	srv.On("update", onCollection(map[string]dbtest.Reply{
+		authorsCollection:    writeReply(2),
+		publishersCollection: writeReply(1),
+		booksCollection:      writeReply(3),
+	}))
+
+	res, err := UpsertBooks(context.Background(), []Book{
+		{Isbn: "1", AuthorID: frank, PublisherID: chilton},
+		{Isbn: "2", AuthorID: frank},
+		{Isbn: "3", AuthorID: ursula, PublisherID: chilton},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Updated != 3 {
+		t.Errorf("got %+v, want 3 updated", res)
+	}
+
+	authors, publishers := commandsOn(srv, "update", authorsCollection), commandsOn(srv, "update", publishersCollection)
+	books := commandsOn(srv, "update", booksCollection)
+	if len(authors) != 1 || len(publishers) != 1 || len(books) != 1 {
+		t.Fatalf("got %d author, %d publisher and %d book updates, want one of each", len(authors), len(publishers), len(books))
+	}
+	ids, err := authors[0].Lookup("updates", "0", "q", "_id", "$in").Array().Values()
+	if err != nil || len(ids) != 2 {
+		t.Errorf("locked authors %v, want each of the 2 once", ids)
+	}
+	if _, err := authors[0].LookupErr("updates", "0", "u", "$currentDate"); err != nil {
+		t.Error("the authors were read without a write, a concurrent delete wouldn't conflict")
+	}
+	for _, lock := range []bson.Raw{authors[0], publishers[0]} {
+		if txnNumber(lock) == 0 || txnNumber(lock) != txnNumber(books[0]) {
+			t.Errorf("lock in transaction %d, books written in %d, want the same one", txnNumber(lock), txnNumber(books[0]))
+		}
+	}
+}
+
+// An author deleted after the import looked it up fails the batch instead of leaving books
+// pointing at it
+func TestUpsertBooksAuthorDeleted(t *testing.T) {
+	srv := dbtest.Connect(t)
+	srv.On("update", onCollection(map[string]dbtest.Reply{
+		authorsCollection: writeReply(1),
+	}))
+
+	_, err := UpsertBooks(context.Background(), []Book{
+		{Isbn: "1", AuthorID: primitive.NewObjectID()},
+		{Isbn: "2", AuthorID: primitive.NewObjectID()},
+	})
+	if !errors.Is(err, apperr.ErrAuthorNotFound) {
+		t.Fatalf("got %v, want %v", err, apperr.ErrAuthorNotFound)
+	}
+	if n := len(commandsOn(srv, "update", booksCollection)); n != 0 {
+		t.Errorf("the books were written %d times", n)
+	}
+	if n := len(srv.Received("commitTransaction")); n != 0 {
+		t.Error("the transaction was committed")
+	}
+}
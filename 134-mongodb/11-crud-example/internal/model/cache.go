@@ -0,0 +1,92 @@
+package model
+
+import (
+	"slices"
+	"sync"
+)
+
+// bookCache is a read-through cache in front of FetchBook and FetchAllBooks.
+// It stays disabled until something keeps it fresh across processes (see EnableBookCache),
+// so an instance without a change watcher always reads from MongoDB.
+// Local writes invalidate it right away, the watcher takes care of everybody else's.
+type bookCache struct {
+	mu      sync.RWMutex
+	enabled bool
+	// gen is bumped on every invalidation, a fetch that started before it must not be stored
+	gen    uint64
+	all    []BookDetails
+	hasAll bool
+	byIsbn map[string]Book
+}
+
+var booksCache = &bookCache{byIsbn: map[string]Book{}}
+
+// EnableBookCache must only be called while something invalidates the cache on every change
+func EnableBookCache() {
+	booksCache.mu.Lock()
+	defer booksCache.mu.Unlock()
+
+	booksCache.enabled = true
+	booksCache.reset()
+}
+
+func DisableBookCache() {
+	booksCache.mu.Lock()
+	defer booksCache.mu.Unlock()
+
+	booksCache.enabled = false
+	booksCache.reset()
+}
+
+func InvalidateBookCache() {
+	booksCache.mu.Lock()
+	defer booksCache.mu.Unlock()
+
+	booksCache.reset()
+}
+
+func (c *bookCache) reset() {
+	c.gen++
+	c.all, c.hasAll = nil, false
+	clear(c.byIsbn)
+}
+
+// getAll returns a copy so callers can't alter the cached slice
+func (c *bookCache) getAll() ([]BookDetails, bool, uint64) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.enabled || !c.hasAll {
+		return nil, false, c.gen
+	}
+	return slices.Clone(c.all), true, c.gen
+}
+
+func (c *bookCache) putAll(gen uint64, bks []BookDetails) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.enabled && c.gen == gen {
+		c.all, c.hasAll = slices.Clone(bks), true
+	}
+}
+
+func (c *bookCache) get(isbn string) (Book, bool, uint64) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.enabled {
+		return Book{}, false, c.gen
+	}
+	bk, ok := c.byIsbn[isbn]
+	return bk, ok, c.gen
+}
+
+func (c *bookCache) put(gen uint64, bk Book) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.enabled && c.gen == gen {
+		c.byIsbn[bk.Isbn] = bk
+	}
+}
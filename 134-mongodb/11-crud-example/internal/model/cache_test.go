@@ -0,0 +1,123 @@
+package model
+
+import (
+	"context"
+	"crud-example/internal/db/dbtest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// enableCache turns the shared cache on for the test
+func enableCache(t *testing.T) {
+	t.Helper()
+	EnableBookCache()
+	t.Cleanup(DisableBookCache)
+}
+
+func TestBookCacheDisabled(t *testing.T) {
+	c := &bookCache{byIsbn: map[string]Book{}}
+	_, _, gen := c.get("1")
+	c.put(gen, Book{Isbn: "1"})
+	if _, ok, _ := c.get("1"); ok {
+		t.Error("a disabled cache stored a book")
+	}
+
+	_, _, gen = c.getAll()
+	c.putAll(gen, []BookDetails{{}})
+	if _, ok, _ := c.getAll(); ok {
+		t.Error("a disabled cache stored the list")
+	}
+}
+
+func TestBookCacheGet(t *testing.T) {
+	c := &bookCache{enabled: true, byIsbn: map[string]Book{}}
+	if _, ok, _ := c.get("1"); ok {
+		t.Fatal("hit on an empty cache")
+	}
+
+	_, _, gen := c.get("1")
+	c.put(gen, Book{Isbn: "1", Title: "Dune"})
+	if bk, ok, _ := c.get("1"); !ok || bk.Title != "Dune" {
+		t.Errorf("got %+v, %t", bk, ok)
+	}
+}
+
+// An invalidation between the read from MongoDB and the put must win, the book read before it
+// may be stale
+func TestBookCachePutAfterInvalidation(t *testing.T) {
+	c := &bookCache{enabled: true, byIsbn: map[string]Book{}}
+
+	_, _, gen := c.get("1")
+	c.reset()
+	c.put(gen, Book{Isbn: "1", Title: "stale"})
+	if bk, ok, _ := c.get("1"); ok {
+		t.Errorf("stored %+v read before the invalidation", bk)
+	}
+
+	_, _, gen = c.getAll()
+	c.reset()
+	c.putAll(gen, []BookDetails{{Book: Book{Title: "stale"}}})
+	if _, ok, _ := c.getAll(); ok {
+		t.Error("stored the list read before the invalidation")
+	}
+
+	// * the generation read after the invalidation stores fine
+	_, _, gen = c.get("1")
+	c.put(gen, Book{Isbn: "1", Title: "fresh"})
+	if bk, ok, _ := c.get("1"); !ok || bk.Title != "fresh" {
+		t.Errorf("got %+v, %t", bk, ok)
+	}
+}
+
+func TestBookCacheGetAllCopies(t *testing.T) {
+	c := &bookCache{enabled: true, byIsbn: map[string]Book{}}
+	bks := []BookDetails{{Book: Book{Title: "Dune"}}}
+	_, _, gen := c.getAll()
+	c.putAll(gen, bks)
+	bks[0].Title = "changed by the caller"
+
+	got, ok, _ := c.getAll()
+	if !ok || got[0].Title != "Dune" {
+		t.Fatalf("got %+v, %t", got, ok)
+	}
+	got[0].Title = "changed again"
+	if again, _, _ := c.getAll(); again[0].Title != "Dune" {
+		t.Errorf("the cached list was changed through a copy: %+v", again)
+	}
+}
+
+func TestFetchBookInvalidatedWhileReading(t *testing.T) {
+	srv := dbtest.Connect(t)
+	enableCache(t)
+
+	// * the book changes while the first read is on its way
+	title := "stale"
+	srv.On("find", func(cmd bson.Raw) bson.D {
+		reply := cursorReply(bson.M{"isbn": "1", "title": title})(cmd)
+		if title == "stale" {
+			InvalidateBookCache()
+			title = "fresh"
+		}
+		return reply
+	})
+
+	ctx := context.Background()
+	if bk, err := FetchBook(ctx, "1"); err != nil || bk.Title != "stale" {
+		t.Fatalf("got %+v, %v", bk, err)
+	}
+	if bk, err := FetchBook(ctx, "1"); err != nil || bk.Title != "fresh" {
+		t.Fatalf("got %+v, %v, want the book read again", bk, err)
+	}
+	if n := len(srv.Received("find")); n != 2 {
+		t.Errorf("read %d times, want 2", n)
+	}
+
+	// * nothing invalidated the second read, the third one is served from the cache
+	if bk, err := FetchBook(ctx, "1"); err != nil || bk.Title != "fresh" {
+		t.Fatalf("got %+v, %v", bk, err)
+	}
+	if n := len(srv.Received("find")); n != 2 {
+		t.Errorf("read %d times, want the cached book", n)
+	}
+}
@@ -0,0 +1,111 @@
+package model
+
+import (
+	"context"
+	"crud-example/config"
+	"crud-example/internal/db/dbtest"
+	ctxhelper "crud-example/pkg/util/context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// withBudgets swaps the data layer budgets for the duration of a test
+func withBudgets(t *testing.T, b ctxhelper.Budgets) {
+	t.Helper()
+	ctxhelper.Configure(b)
+	t.Cleanup(func() { ctxhelper.Configure(config.Default().Mongo.Budgets()) })
+}
+
+// cancelInFlight runs call, cancels its context once the server holds the command
+// and returns the error of call
+func cancelInFlight(t *testing.T, srv *dbtest.Server, call func(ctx context.Context) error) error {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- call(ctx) }()
+
+	select {
+	case <-srv.Hung():
+	case err := <-done:
+		t.Fatalf("call returned before reaching the server: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the command never reached the server")
+	}
+
+	start := time.Now()
+	cancel()
+
+	select {
+	case err := <-done:
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("call returned %s after cancel, want it aborted right away", elapsed)
+		}
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("call still running after its context was cancelled")
+		return nil
+	}
+}
+
+func TestCancelAbortsInFlightFind(t *testing.T) {
+	srv := dbtest.Connect(t, "find")
+	// Budgets far above the test duration, only the cancellation may end the calls
+	withBudgets(t, ctxhelper.Budgets{Read: time.Minute, Write: time.Minute, Long: time.Minute})
+
+	tests := []struct {
+		name string
+		call func(ctx context.Context) error
+	}{
+		{"FetchBook", func(ctx context.Context) error {
+			_, err := FetchBook(ctx, "9780000000000")
+			return err
+		}},
+		{"FetchAllAuthors", func(ctx context.Context) error {
+			_, err := FetchAllAuthors(ctx)
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := cancelInFlight(t, srv, tt.call)
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("got %v, want %v", err, context.Canceled)
+			}
+		})
+	}
+}
+
+func TestCancelAbortsInFlightAggregate(t *testing.T) {
+	srv := dbtest.Connect(t, "aggregate")
+	withBudgets(t, ctxhelper.Budgets{Read: time.Minute, Write: time.Minute, Long: time.Minute})
+
+	err := cancelInFlight(t, srv, func(ctx context.Context) error {
+		_, err := FetchAllBooks(ctx)
+		return err
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestReadBudgetEndsHangingFind(t *testing.T) {
+	dbtest.Connect(t, "find")
+	withBudgets(t, ctxhelper.Budgets{Read: 100 * time.Millisecond, Write: time.Minute, Long: time.Minute})
+
+	start := time.Now()
+	_, err := FetchBook(context.Background(), "9780000000000")
+
+	if !mongo.IsTimeout(err) {
+		t.Errorf("got %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("took %s, want about the 100ms read budget", elapsed)
+	}
+}
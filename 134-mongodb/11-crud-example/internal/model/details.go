@@ -0,0 +1,65 @@
+package model
+
+import (
+	"context"
+	ctxhelper "crud-example/pkg/util/context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// FetchBookDetails returns the book together with its author and publisher
+func FetchBookDetails(ctx context.Context, isbn string) (BookDetails, error) {
+	bk := BookDetails{}
+
+	cl := getBooksCollection()
+
+	ctx, cancel := ctxhelper.ForRead(ctx)
+	defer cancel()
+
+	cur, err := cl.Aggregate(ctx, detailsPipeline(notDeleted(bson.M{"isbn": isbn})))
+	if err != nil {
+		return bk, err
+	}
+	defer cur.Close(ctx)
+
+	if !cur.Next(ctx) {
+		if err := cur.Err(); err != nil {
+			return bk, err
+		}
+		return bk, mongo.ErrNoDocuments
+	}
+
+	err = cur.Decode(&bk)
+	return bk, err
+}
+
+// detailsPipeline matches books and embeds their author and publisher.
+// $unwind keeps books whose publisher is unset (or whose author is missing) in the result.
+func detailsPipeline(match bson.M, stages ...bson.D) mongo.Pipeline {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		lookupOne(authorsCollection, "authorId", "author"),
+		unwindOptional("$author"),
+		lookupOne(publishersCollection, "publisherId", "publisher"),
+		unwindOptional("$publisher"),
+	}
+
+	return append(pipeline, stages...)
+}
+
+func lookupOne(from, localField, as string) bson.D {
+	return bson.D{{Key: "$lookup", Value: bson.D{
+		{Key: "from", Value: from},
+		{Key: "localField", Value: localField},
+		{Key: "foreignField", Value: "_id"},
+		{Key: "as", Value: as},
+	}}}
+}
+
+func unwindOptional(path string) bson.D {
+	return bson.D{{Key: "$unwind", Value: bson.D{
+		{Key: "path", Value: path},
+		{Key: "preserveNullAndEmptyArrays", Value: true},
+	}}}
+}
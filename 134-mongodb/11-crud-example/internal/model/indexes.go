@@ -0,0 +1,61 @@
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Index declarations of the collections owned by this package. They are created and dropped
+// by the migrations in internal/migrate, every index is named so it can be dropped again.
+
+// Indexes backs the isbn lookups, the reference guards of authors and publishers and the trash purge
+func (Book) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			// Trashed books keep their isbn, restoring and re-importing them relies on it staying unique
+			Keys:    bson.D{{Key: "isbn", Value: 1}},
+			Options: options.Index().SetName("isbn_unique").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "authorId", Value: 1}},
+			Options: options.Index().SetName("authorId"),
+		},
+		{
+			Keys:    bson.D{{Key: "publisherId", Value: 1}},
+			Options: options.Index().SetName("publisherId").SetSparse(true),
+		},
+		{
+			// Only trashed books carry deletedAt
+			Keys:    bson.D{{Key: "deletedAt", Value: 1}},
+			Options: options.Index().SetName("deletedAt").SetSparse(true),
+		},
+	}
+}
+
+// Indexes makes ensureNamed race free, two concurrent upserts of a new name can't both insert
+func (Author) Indexes() []mongo.IndexModel {
+	return nameKeyIndexes()
+}
+
+func (Publisher) Indexes() []mongo.IndexModel {
+	return nameKeyIndexes()
+}
+
+func nameKeyIndexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "nameKey", Value: 1}},
+			Options: options.Index().SetName("nameKey_unique").SetUnique(true),
+		},
+	}
+}
+
+// CollectionIndexes maps each collection of this package to its declared indexes
+func CollectionIndexes() map[string][]mongo.IndexModel {
+	return map[string][]mongo.IndexModel{
+		booksCollection:      Book{}.Indexes(),
+		authorsCollection:    Author{}.Indexes(),
+		publishersCollection: Publisher{}.Indexes(),
+	}
+}
@@ -0,0 +1,100 @@
+package model
+
+import (
+	"context"
+	"crud-example/internal/db"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Authors and publishers are both plain named entities referenced by books.
+// The helpers below hold what they have in common.
+
+var spaces = regexp.MustCompile(`\s+`)
+
+// nameKey normalizes a name so "H. G.  Wells" and "h. g. wells" end up as the same entity
+func nameKey(name string) string {
+	return strings.ToLower(spaces.ReplaceAllString(strings.TrimSpace(name), " "))
+}
+
+func cleanName(name string) string {
+	return spaces.ReplaceAllString(strings.TrimSpace(name), " ")
+}
+
+// ensureNamed returns the id of the entity with the given name, creating it when missing
+func ensureNamed(ctx context.Context, cl *mongo.Collection, name string) (primitive.ObjectID, error) {
+	update := bson.M{"$setOnInsert": bson.M{"name": cleanName(name), "nameKey": nameKey(name)}}
+	upOpts := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.After).
+		SetProjection(bson.M{"_id": 1})
+
+	res := cl.FindOneAndUpdate(ctx, bson.M{"nameKey": nameKey(name)}, update, upOpts)
+	doc := struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}{}
+	if err := res.Decode(&doc); err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return doc.ID, nil
+}
+
+// deleteUnreferenced removes the entity unless a book (trashed ones included) still points at it.
+// The count and the delete share a transaction: a book written meanwhile touches the entity
+// (see lockBookRefs), the two transactions conflict and the retry counts that book.
+func deleteUnreferenced(ctx context.Context, cl *mongo.Collection, id primitive.ObjectID, refField string, errInUse error) error {
+	return inTransaction(ctx, func(sc mongo.SessionContext) error {
+		n, err := getBooksCollection().CountDocuments(sc, bson.M{refField: id}, options.Count().SetLimit(1))
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return errInUse
+		}
+
+		res, err := cl.DeleteOne(sc, bson.M{"_id": id})
+		if err != nil {
+			return err
+		}
+		if res.DeletedCount == 0 {
+			return mongo.ErrNoDocuments
+		}
+
+		return nil
+	})
+}
+
+// touchNamed loads the entity and writes to it, so a transaction deleting it conflicts with ours
+func touchNamed(ctx context.Context, cl *mongo.Collection, id primitive.ObjectID, out any) error {
+	update := bson.M{"$currentDate": bson.M{"referencedAt": true}}
+	return cl.FindOneAndUpdate(ctx, bson.M{"_id": id}, update).Decode(out)
+}
+
+// inTransaction runs fn in a transaction. WithTransaction retries fn on transient errors such
+// as write conflicts, so fn only relies on what it reads inside the transaction.
+// A standalone mongod has no transactions, fn then runs its writes one by one.
+func inTransaction(ctx context.Context, fn func(sc mongo.SessionContext) error) error {
+	if db.MongoClient == nil {
+		return db.ErrNotConnected
+	}
+	if !db.SupportsTransactions() {
+		return db.MongoClient.UseSession(ctx, fn)
+	}
+
+	sess, err := db.MongoClient.StartSession()
+	if err != nil {
+		return err
+	}
+	defer sess.EndSession(ctx)
+
+	_, err = sess.WithTransaction(ctx, func(sc mongo.SessionContext) (any, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
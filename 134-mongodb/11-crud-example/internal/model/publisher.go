@@ -0,0 +1,85 @@
+package model
+
+import (
+	"context"
+	"crud-example/internal/db"
+	apperr "crud-example/pkg/util/app_err"
+	ctxhelper "crud-example/pkg/util/context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const publishersCollection = "publishers"
+
+type Publisher struct {
+	ID      primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name    string             `json:"name" bson:"name"`
+	NameKey string             `json:"-" bson:"nameKey"` // normalized name, unique per publisher
+}
+
+func FetchAllPublishers(ctx context.Context) ([]Publisher, error) {
+	pubs := []Publisher{}
+
+	cl := getPublishersCollection()
+
+	ctx, cancel := ctxhelper.ForRead(ctx)
+	defer cancel()
+
+	findOpts := options.Find().SetSort(bson.D{{Key: "nameKey", Value: 1}})
+	cur, err := cl.Find(ctx, bson.M{}, findOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cur.All(ctx, &pubs)
+	if err != nil {
+		return nil, err
+	}
+
+	return pubs, nil
+}
+
+func FetchPublisher(ctx context.Context, id primitive.ObjectID) (Publisher, error) {
+	pub := Publisher{}
+
+	cl := getPublishersCollection()
+
+	ctx, cancel := ctxhelper.ForRead(ctx)
+	defer cancel()
+
+	err := cl.FindOne(ctx, bson.M{"_id": id}).Decode(&pub)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return pub, apperr.ErrPublisherNotFound
+	}
+
+	return pub, err
+}
+
+// EnsurePublisher returns the publisher with that name (ignoring case and extra spaces), creating it when missing
+func EnsurePublisher(ctx context.Context, name string) (primitive.ObjectID, error) {
+	ctx, cancel := ctxhelper.ForWrite(ctx)
+	defer cancel()
+
+	return ensureNamed(ctx, getPublishersCollection(), name)
+}
+
+// DeletePublisher refuses with apperr.ErrPublisherInUse while books still reference the publisher
+func DeletePublisher(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := ctxhelper.ForWrite(ctx)
+	defer cancel()
+
+	err := deleteUnreferenced(ctx, getPublishersCollection(), id, "publisherId", apperr.ErrPublisherInUse)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return apperr.ErrDeleteItemFailed
+	}
+
+	return err
+}
+
+func getPublishersCollection() *mongo.Collection {
+	return db.Database().Collection(publishersCollection)
+}
@@ -0,0 +1,149 @@
+package model
+
+import (
+	"context"
+	apperr "crud-example/pkg/util/app_err"
+	ctxhelper "crud-example/pkg/util/context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ResolveBookRefs makes sure the author and the optional publisher of a book exist and returns them.
+// It only validates a form, InsertBook and UpdateBook check the refs again in their transaction.
+func ResolveBookRefs(ctx context.Context, bk Book) (BookDetails, error) {
+	details := BookDetails{Book: bk}
+
+	au, err := FetchAuthor(ctx, bk.AuthorID)
+	if err != nil {
+		return details, err
+	}
+	details.Author = au
+
+	if !bk.PublisherID.IsZero() {
+		pub, err := FetchPublisher(ctx, bk.PublisherID)
+		if err != nil {
+			return details, err
+		}
+		details.Publisher = &pub
+	}
+
+	return details, nil
+}
+
+// lockBookRefs is ResolveBookRefs inside a transaction. It writes to the author and the
+// publisher, so deleting either of them concurrently conflicts with the transaction.
+func lockBookRefs(sc mongo.SessionContext, bk Book) (BookDetails, error) {
+	details := BookDetails{Book: bk}
+
+	err := touchNamed(sc, getAuthorsCollection(), bk.AuthorID, &details.Author)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return details, apperr.ErrAuthorNotFound
+	}
+	if err != nil {
+		return details, err
+	}
+
+	if !bk.PublisherID.IsZero() {
+		pub := Publisher{}
+		err := touchNamed(sc, getPublishersCollection(), bk.PublisherID, &pub)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return details, apperr.ErrPublisherNotFound
+		}
+		if err != nil {
+			return details, err
+		}
+		details.Publisher = &pub
+	}
+
+	return details, nil
+}
+
+// lockBatchRefs is lockBookRefs for every distinct author and publisher of a batch of books,
+// each one is written once
+func lockBatchRefs(sc mongo.SessionContext, bks []Book) error {
+	var authors, publishers []primitive.ObjectID
+	seen := map[primitive.ObjectID]bool{}
+	for _, bk := range bks {
+		if !seen[bk.AuthorID] {
+			seen[bk.AuthorID] = true
+			authors = append(authors, bk.AuthorID)
+		}
+		if !bk.PublisherID.IsZero() && !seen[bk.PublisherID] {
+			seen[bk.PublisherID] = true
+			publishers = append(publishers, bk.PublisherID)
+		}
+	}
+
+	if err := touchAll(sc, getAuthorsCollection(), authors, apperr.ErrAuthorNotFound); err != nil {
+		return err
+	}
+	return touchAll(sc, getPublishersCollection(), publishers, apperr.ErrPublisherNotFound)
+}
+
+// touchAll is touchNamed for several entities, errGone reports the ones that don't exist
+func touchAll(ctx context.Context, cl *mongo.Collection, ids []primitive.ObjectID, errGone error) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	update := bson.M{"$currentDate": bson.M{"referencedAt": true}}
+	res, err := cl.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, update)
+	if err != nil {
+		return err
+	}
+	if missing := int64(len(ids)) - res.MatchedCount; missing > 0 {
+		return fmt.Errorf("%w: %d of the %d referenced by the batch", errGone, missing, len(ids))
+	}
+	return nil
+}
+
+// LinkLegacyAuthors converts books that still carry a free-text author into a reference
+// to an Author document, merging spellings that only differ by case or spacing.
+func LinkLegacyAuthors(ctx context.Context) (int64, error) {
+	cl := getBooksCollection()
+	defer InvalidateBookCache()
+
+	ctx, cancel := ctxhelper.ForLong(ctx)
+	defer cancel()
+
+	legacy := bson.M{"author": bson.M{"$type": "string"}}
+	names, err := cl.Distinct(ctx, "author", legacy)
+	if err != nil {
+		return 0, err
+	}
+
+	var linked int64
+	for _, n := range names {
+		name, _ := n.(string)
+		if cleanName(name) == "" {
+			continue
+		}
+
+		id, err := ensureNamed(ctx, getAuthorsCollection(), name)
+		if err != nil {
+			return linked, err
+		}
+
+		res, err := cl.UpdateMany(ctx,
+			bson.M{"author": name},
+			bson.M{"$set": bson.M{"authorId": id}, "$unset": bson.M{"author": ""}})
+		if err != nil {
+			return linked, err
+		}
+		linked += res.ModifiedCount
+	}
+
+	return linked, nil
+}
+
+// ParseObjectID returns primitive.NilObjectID for an empty string
+func ParseObjectID(hex string) (primitive.ObjectID, error) {
+	if hex == "" {
+		return primitive.NilObjectID, nil
+	}
+	return primitive.ObjectIDFromHex(hex)
+}
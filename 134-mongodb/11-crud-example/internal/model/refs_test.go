@@ -0,0 +1,242 @@
+package model
+
+import (
+	"context"
+	"crud-example/internal/db/dbtest"
+	apperr "crud-example/pkg/util/app_err"
+	"errors"
+	"sync"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// countReply answers the aggregate of CountDocuments
+func countReply(n int) dbtest.Reply {
+	if n == 0 {
+		return cursorReply()
+	}
+	return cursorReply(bson.M{"_id": 1, "n": n})
+}
+
+// txnNumber is the transaction a command ran in, 0 outside of one
+func txnNumber(cmd bson.Raw) int64 {
+	n, _ := cmd.Lookup("txnNumber").AsInt64OK()
+	return n
+}
+
+func TestDeleteAuthorUnreferenced(t *testing.T) {
+	srv := dbtest.Connect(t)
+	srv.On("aggregate", countReply(0))
+	srv.On("delete", writeReply(1))
+
+	if err := DeleteAuthor(context.Background(), primitive.NewObjectID()); err != nil {
+		t.Fatal(err)
+	}
+
+	count, del := lastCommand(t, srv, "aggregate"), lastCommand(t, srv, "delete")
+	if txnNumber(count) == 0 || txnNumber(count) != txnNumber(del) {
+		t.Errorf("count in transaction %d, delete in %d, want both in the same one", txnNumber(count), txnNumber(del))
+	}
+	if len(srv.Received("commitTransaction")) != 1 {
+		t.Error("the transaction wasn't committed")
+	}
+}
+
+func TestDeleteAuthorInUse(t *testing.T) {
+	srv := dbtest.Connect(t)
+	srv.On("aggregate", countReply(1))
+
+	id := primitive.NewObjectID()
+	if err := DeleteAuthor(context.Background(), id); !errors.Is(err, apperr.ErrAuthorInUse) {
+		t.Fatalf("got %v, want %v", err, apperr.ErrAuthorInUse)
+	}
+	if len(srv.Received("delete")) != 0 {
+		t.Error("the author was deleted")
+	}
+
+	match := lastCommand(t, srv, "aggregate").Lookup("pipeline", "0", "$match", "authorId")
+	if got, ok := match.ObjectIDOK(); !ok || got != id {
+		t.Errorf("counted books with authorId %v, want %v", match, id)
+	}
+}
+
+func TestDeletePublisherMissing(t *testing.T) {
+	srv := dbtest.Connect(t)
+	srv.On("aggregate", countReply(0))
+	srv.On("delete", writeReply(0))
+
+	if err := DeletePublisher(context.Background(), primitive.NewObjectID()); !errors.Is(err, apperr.ErrDeleteItemFailed) {
+		t.Errorf("got %v, want %v", err, apperr.ErrDeleteItemFailed)
+	}
+}
+
+// A book written between the count and the delete makes the delete conflict with it,
+// the retried transaction then counts that book
+func TestDeleteAuthorRetriedAfterConflict(t *testing.T) {
+	srv := dbtest.Connect(t)
+
+	var mu sync.Mutex
+	bookWritten := false
+	srv.On("aggregate", func(cmd bson.Raw) bson.D {
+		mu.Lock()
+		defer mu.Unlock()
+		if bookWritten {
+			return countReply(1)(cmd)
+		}
+		return countReply(0)(cmd)
+	})
+	srv.On("delete", func(bson.Raw) bson.D {
+		mu.Lock()
+		defer mu.Unlock()
+		bookWritten = true
+		return bson.D{
+			{Key: "ok", Value: 0.0},
+			{Key: "code", Value: 112},
+			{Key: "codeName", Value: "WriteConflict"},
+			{Key: "errmsg", Value: "write conflict"},
+			{Key: "errorLabels", Value: bson.A{"TransientTransactionError"}},
+		}
+	})
+
+	if err := DeleteAuthor(context.Background(), primitive.NewObjectID()); !errors.Is(err, apperr.ErrAuthorInUse) {
+		t.Fatalf("got %v, want %v", err, apperr.ErrAuthorInUse)
+	}
+	if n := len(srv.Received("aggregate")); n != 2 {
+		t.Errorf("counted %d times, want the transaction retried once", n)
+	}
+}
+
+func TestInsertBookLocksRefs(t *testing.T) {
+	srv := dbtest.Connect(t)
+	srv.On("findAndModify", onCollection(map[string]dbtest.Reply{
+		authorsCollection:    findAndModifyReply(bson.M{"name": "Frank Herbert"}),
+		publishersCollection: findAndModifyReply(bson.M{"name": "Chilton"}),
+	}))
+	srv.On("insert", writeReply(1))
+
+	bk := Book{Isbn: "1", Title: "Dune", AuthorID: primitive.NewObjectID(), PublisherID: primitive.NewObjectID()}
+	details, err := InsertBook(context.Background(), bk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.Author.Name != "Frank Herbert" || details.Publisher == nil || details.Publisher.Name != "Chilton" || details.ID.IsZero() {
+		t.Errorf("got %+v", details)
+	}
+
+	locks, insert := srv.Received("findAndModify"), lastCommand(t, srv, "insert")
+	if len(locks) != 2 {
+		t.Fatalf("got %d findAndModify, want the author and the publisher locked", len(locks))
+	}
+	for _, lock := range locks {
+		if _, err := lock.LookupErr("update", "$currentDate"); err != nil {
+			t.Errorf("%s was read without a write, a concurrent delete wouldn't conflict", lock.Index(0).Value())
+		}
+		if txnNumber(lock) == 0 || txnNumber(lock) != txnNumber(insert) {
+			t.Errorf("lock in transaction %d, insert in %d, want the same one", txnNumber(lock), txnNumber(insert))
+		}
+	}
+	if v := insert.Lookup("documents", "0", "version").AsInt64(); v != 1 {
+		t.Errorf("inserted version %d, want 1", v)
+	}
+}
+
+func TestInsertBookMissingAuthor(t *testing.T) {
+	srv := dbtest.Connect(t)
+	srv.On("findAndModify", findAndModifyReply(nil))
+
+	_, err := InsertBook(context.Background(), Book{Isbn: "1", AuthorID: primitive.NewObjectID()})
+	if !errors.Is(err, apperr.ErrAuthorNotFound) {
+		t.Fatalf("got %v, want %v", err, apperr.ErrAuthorNotFound)
+	}
+	if len(srv.Received("insert")) != 0 {
+		t.Error("a book was inserted for a missing author")
+	}
+	if len(srv.Received("commitTransaction")) != 0 {
+		t.Error("the transaction was committed")
+	}
+}
+
+func TestDetailsPipeline(t *testing.T) {
+	pipeline := detailsPipeline(bson.M{"isbn": "1"})
+	raw, err := bson.Marshal(bson.M{"pipeline": pipeline})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := bson.Raw(raw)
+
+	lookups := []struct {
+		lookup, unwind, from, local, as string
+	}{
+		{"1", "2", authorsCollection, "authorId", "author"},
+		{"3", "4", publishersCollection, "publisherId", "publisher"},
+	}
+	for _, l := range lookups {
+		lookup := doc.Lookup("pipeline", l.lookup, "$lookup").Document()
+		if lookup.Lookup("from").StringValue() != l.from || lookup.Lookup("localField").StringValue() != l.local ||
+			lookup.Lookup("foreignField").StringValue() != "_id" || lookup.Lookup("as").StringValue() != l.as {
+			t.Errorf("stage %s: got %v", l.lookup, lookup)
+		}
+
+		// * a book without a publisher, or whose author is missing, is still listed
+		unwind := doc.Lookup("pipeline", l.unwind, "$unwind").Document()
+		if unwind.Lookup("path").StringValue() != "$"+l.as || !unwind.Lookup("preserveNullAndEmptyArrays").Boolean() {
+			t.Errorf("stage %s: got %v", l.unwind, unwind)
+		}
+	}
+	if match := doc.Lookup("pipeline", "0", "$match", "isbn"); match.StringValue() != "1" {
+		t.Errorf("the first stage matches %v", match)
+	}
+}
+
+func TestFetchBookDetails(t *testing.T) {
+	srv := dbtest.Connect(t)
+	authorID := primitive.NewObjectID()
+	srv.On("aggregate", cursorReply(bson.M{
+		"isbn":     "1",
+		"title":    "Dune",
+		"authorId": authorID,
+		"author":   bson.M{"_id": authorID, "name": "Frank Herbert"},
+	}))
+
+	bk, err := FetchBookDetails(context.Background(), "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bk.Title != "Dune" || bk.Author.ID != authorID || bk.Author.Name != "Frank Herbert" || bk.Publisher != nil {
+		t.Errorf("got %+v", bk)
+	}
+
+	srv.On("aggregate", cursorReply())
+	if _, err := FetchBookDetails(context.Background(), "2"); !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Errorf("got %v, want %v", err, mongo.ErrNoDocuments)
+	}
+}
+
+// A standalone mongod has no transactions, the writes work as they did without them
+func TestWritesWithoutTransactions(t *testing.T) {
+	srv := dbtest.ConnectStandalone(t)
+	srv.On("findAndModify", findAndModifyReply(bson.M{"name": "Frank Herbert"}))
+	srv.On("insert", writeReply(1))
+	srv.On("aggregate", countReply(0))
+	srv.On("delete", writeReply(1))
+
+	ctx := context.Background()
+	if _, err := InsertBook(ctx, Book{Isbn: "1", AuthorID: primitive.NewObjectID()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteAuthor(ctx, primitive.NewObjectID()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cmd := range []string{"findAndModify", "insert", "aggregate", "delete"} {
+		if txnNumber(lastCommand(t, srv, cmd)) != 0 {
+			t.Errorf("%s ran in a transaction", cmd)
+		}
+	}
+	if n := len(srv.Received("commitTransaction")); n != 0 {
+		t.Errorf("got %d commits on a standalone server", n)
+	}
+}
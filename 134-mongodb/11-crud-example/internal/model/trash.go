@@ -0,0 +1,98 @@
+package model
+
+import (
+	"context"
+	apperr "crud-example/pkg/util/app_err"
+	ctxhelper "crud-example/pkg/util/context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// FetchDeletedBooks returns the books in the trash, most recently deleted first
+func FetchDeletedBooks(ctx context.Context) ([]BookDetails, error) {
+	bks := []BookDetails{}
+
+	cl := getBooksCollection()
+
+	ctx, cancel := ctxhelper.ForRead(ctx)
+	defer cancel()
+
+	sort := bson.D{{Key: "$sort", Value: bson.D{{Key: "deletedAt", Value: -1}}}}
+	cur, err := cl.Aggregate(ctx, detailsPipeline(inTrash(bson.M{}), sort))
+	if err != nil {
+		return nil, err
+	}
+
+	err = cur.All(ctx, &bks)
+	if err != nil {
+		return nil, err
+	}
+
+	return bks, nil
+}
+
+// RestoreBook takes the book out of the trash
+func RestoreBook(ctx context.Context, isbn string) error {
+	cl := getBooksCollection()
+	defer InvalidateBookCache()
+
+	ctx, cancel := ctxhelper.ForWrite(ctx)
+	defer cancel()
+
+	update := bson.M{
+		"$unset": bson.M{"deletedAt": ""},
+		"$inc":   bson.M{"version": 1},
+	}
+	res, err := cl.UpdateOne(ctx, inTrash(bson.M{"isbn": isbn}), update)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return apperr.ErrRestoreItemFailed
+	}
+
+	return nil
+}
+
+// PurgeBook permanently removes a book that is already in the trash
+func PurgeBook(ctx context.Context, isbn string) error {
+	cl := getBooksCollection()
+	defer InvalidateBookCache()
+
+	ctx, cancel := ctxhelper.ForWrite(ctx)
+	defer cancel()
+
+	res, err := cl.DeleteOne(ctx, inTrash(bson.M{"isbn": isbn}), nil)
+	if err != nil {
+		return err
+	}
+
+	if res.DeletedCount == 0 {
+		return apperr.ErrDeleteItemFailed
+	}
+
+	return nil
+}
+
+// PurgeDeletedBefore permanently removes every book deleted before the cutoff
+func PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	cl := getBooksCollection()
+	defer InvalidateBookCache()
+
+	ctx, cancel := ctxhelper.ForWrite(ctx)
+	defer cancel()
+
+	res, err := cl.DeleteMany(ctx, bson.M{"deletedAt": bson.M{"$lt": cutoff}})
+	if err != nil {
+		return 0, err
+	}
+
+	return res.DeletedCount, nil
+}
+
+func inTrash(filter bson.M) bson.M {
+	filter["deletedAt"] = bson.M{"$ne": nil}
+	return filter
+}
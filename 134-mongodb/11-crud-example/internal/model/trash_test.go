@@ -0,0 +1,135 @@
+package model
+
+import (
+	"context"
+	"crud-example/internal/db/dbtest"
+	apperr "crud-example/pkg/util/app_err"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// writeReply answers an update or a delete as if n documents matched
+func writeReply(n int) dbtest.Reply {
+	return func(bson.Raw) bson.D {
+		return bson.D{{Key: "n", Value: n}, {Key: "nModified", Value: n}, {Key: "ok", Value: 1.0}}
+	}
+}
+
+func isNull(v bson.RawValue) bool { return v.Type == bson.TypeNull }
+
+func isNotNull(v bson.RawValue) bool {
+	ne, err := v.Document().LookupErr("$ne")
+	return err == nil && ne.Type == bson.TypeNull
+}
+
+func TestDefaultReadsExcludeTrash(t *testing.T) {
+	srv := dbtest.Connect(t)
+	srv.On("find", cursorReply())
+	srv.On("aggregate", cursorReply())
+	ctx := context.Background()
+
+	FetchBook(ctx, "1")
+	if v := lastCommand(t, srv, "find").Lookup("filter", "deletedAt"); !isNull(v) {
+		t.Errorf("FetchBook filters deletedAt on %v, want null", v)
+	}
+
+	reads := []struct {
+		name string
+		call func() error
+	}{
+		{"FetchAllBooks", func() error { _, err := FetchAllBooks(ctx); return err }},
+		{"FetchBookDetails", func() error { _, err := FetchBookDetails(ctx, "1"); return err }},
+	}
+	for _, r := range reads {
+		r.call()
+		if v := lastCommand(t, srv, "aggregate").Lookup("pipeline", "0", "$match", "deletedAt"); !isNull(v) {
+			t.Errorf("%s matches deletedAt on %v, want null", r.name, v)
+		}
+	}
+
+	FetchDeletedBooks(ctx)
+	if v := lastCommand(t, srv, "aggregate").Lookup("pipeline", "0", "$match", "deletedAt"); !isNotNull(v) {
+		t.Errorf("FetchDeletedBooks matches deletedAt on %v, want {$ne: null}", v)
+	}
+}
+
+func TestDeleteBookMovesToTrash(t *testing.T) {
+	srv := dbtest.Connect(t)
+	srv.On("update", writeReply(1))
+
+	before := time.Now().Add(-time.Second)
+	if err := DeleteBook(context.Background(), "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	stmt := lastCommand(t, srv, "update").Lookup("updates", "0").Document()
+	if v := stmt.Lookup("q", "deletedAt"); !isNull(v) {
+		t.Errorf("filter deletedAt %v, want null so a trashed book isn't deleted again", v)
+	}
+	if at := stmt.Lookup("u", "$set", "deletedAt").Time(); at.Before(before) {
+		t.Errorf("deletedAt set to %s, want now", at)
+	}
+	if len(srv.Received("delete")) != 0 {
+		t.Error("DeleteBook removed the document")
+	}
+}
+
+func TestRestoreBook(t *testing.T) {
+	srv := dbtest.Connect(t)
+	srv.On("update", writeReply(1))
+
+	if err := RestoreBook(context.Background(), "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	stmt := lastCommand(t, srv, "update").Lookup("updates", "0").Document()
+	if v := stmt.Lookup("q", "deletedAt"); !isNotNull(v) {
+		t.Errorf("filter deletedAt %v, want {$ne: null}, only trashed books are restored", v)
+	}
+	if _, err := stmt.LookupErr("u", "$unset", "deletedAt"); err != nil {
+		t.Errorf("update %v doesn't unset deletedAt", stmt.Lookup("u"))
+	}
+
+	// * a purged book, or one never deleted, can't be restored
+	srv.On("update", writeReply(0))
+	if err := RestoreBook(context.Background(), "1"); !errors.Is(err, apperr.ErrRestoreItemFailed) {
+		t.Errorf("got %v, want %v", err, apperr.ErrRestoreItemFailed)
+	}
+}
+
+func TestPurgeBookOnlyTrashed(t *testing.T) {
+	srv := dbtest.Connect(t)
+	srv.On("delete", writeReply(1))
+
+	if err := PurgeBook(context.Background(), "1"); err != nil {
+		t.Fatal(err)
+	}
+	if v := lastCommand(t, srv, "delete").Lookup("deletes", "0", "q", "deletedAt"); !isNotNull(v) {
+		t.Errorf("filter deletedAt %v, want {$ne: null}, a live book is never purged", v)
+	}
+
+	srv.On("delete", writeReply(0))
+	if err := PurgeBook(context.Background(), "1"); !errors.Is(err, apperr.ErrDeleteItemFailed) {
+		t.Errorf("got %v, want %v", err, apperr.ErrDeleteItemFailed)
+	}
+}
+
+func TestPurgeDeletedBefore(t *testing.T) {
+	srv := dbtest.Connect(t)
+	srv.On("delete", writeReply(3))
+
+	cutoff := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	n, err := PurgeDeletedBefore(context.Background(), cutoff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("got %d purged, want 3", n)
+	}
+	if lt := lastCommand(t, srv, "delete").Lookup("deletes", "0", "q", "deletedAt", "$lt").Time(); !lt.Equal(cutoff) {
+		t.Errorf("purged books deleted before %s, want before %s", lt, cutoff)
+	}
+}
@@ -0,0 +1,167 @@
+package shop
+
+import (
+	"context"
+	"crud-example/internal/model"
+	apperr "crud-example/pkg/util/app_err"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MemoryStore is the in-memory counterpart of MongoStore for tests.
+// A single mutex plays the role of the transaction: PlaceOrder checks every line
+// before changing anything, so a failed order leaves stock and cart untouched.
+type MemoryStore struct {
+	mu     sync.Mutex
+	books  map[string]model.Book
+	carts  map[string]Cart
+	orders map[primitive.ObjectID]Order
+}
+
+func NewMemoryStore(bks ...model.Book) *MemoryStore {
+	s := &MemoryStore{
+		books:  map[string]model.Book{},
+		carts:  map[string]Cart{},
+		orders: map[primitive.ObjectID]Order{},
+	}
+	for _, bk := range bks {
+		s.books[bk.Isbn] = bk
+	}
+	return s
+}
+
+// Stock reports the stock of a book, for assertions in tests
+func (s *MemoryStore) Stock(isbn string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.books[isbn].Stock
+}
+
+func (s *MemoryStore) Cart(_ context.Context, sessionID string) (Cart, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cart := Cart{SessionID: sessionID, Items: []CartItem{}, UpdatedAt: s.carts[sessionID].UpdatedAt}
+	for _, it := range s.carts[sessionID].Items {
+		if bk, ok := s.book(it.Isbn); ok {
+			it.Title, it.Price, it.Stock = bk.Title, bk.Price, bk.Stock
+		}
+		cart.Items = append(cart.Items, it)
+	}
+
+	return cart, nil
+}
+
+func (s *MemoryStore) AddToCart(_ context.Context, sessionID, isbn string, qty int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.book(isbn); !ok {
+		return mongo.ErrNoDocuments
+	}
+
+	cart := s.carts[sessionID]
+	cart.SessionID = sessionID
+	cart.UpdatedAt = time.Now().UTC()
+
+	items := make([]CartItem, 0, len(cart.Items)+1)
+	found := false
+	for _, it := range cart.Items {
+		if it.Isbn == isbn {
+			it.Qty += qty
+			found = true
+		}
+		if it.Qty > 0 {
+			items = append(items, it)
+		}
+	}
+	if !found && qty > 0 {
+		items = append(items, CartItem{Isbn: isbn, Qty: qty})
+	}
+	cart.Items = items
+	s.carts[sessionID] = cart
+
+	return nil
+}
+
+func (s *MemoryStore) RemoveFromCart(_ context.Context, sessionID, isbn string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cart, ok := s.carts[sessionID]
+	if !ok {
+		return nil
+	}
+
+	items := make([]CartItem, 0, len(cart.Items))
+	for _, it := range cart.Items {
+		if it.Isbn != isbn {
+			items = append(items, it)
+		}
+	}
+	cart.Items = items
+	cart.UpdatedAt = time.Now().UTC()
+	s.carts[sessionID] = cart
+
+	return nil
+}
+
+func (s *MemoryStore) PlaceOrder(_ context.Context, sessionID string) (Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cart := s.carts[sessionID]
+	if len(cart.Items) == 0 {
+		return Order{}, apperr.ErrEmptyCart
+	}
+
+	// Check every line first, nothing is written unless all of them can be served
+	items := make([]OrderItem, 0, len(cart.Items))
+	needed := map[string]int{}
+	for _, it := range cart.Items {
+		needed[it.Isbn] += it.Qty
+		bk, ok := s.book(it.Isbn)
+		if !ok || bk.Stock < needed[it.Isbn] {
+			return Order{}, fmt.Errorf("%w: %s", apperr.ErrOutOfStock, it.Isbn)
+		}
+		items = append(items, OrderItem{Isbn: bk.Isbn, Title: bk.Title, Price: bk.Price, Qty: it.Qty})
+	}
+
+	for _, it := range items {
+		bk := s.books[it.Isbn]
+		bk.Stock -= it.Qty
+		s.books[it.Isbn] = bk
+	}
+
+	order := newOrder(sessionID, items)
+	s.orders[order.ID] = order
+	delete(s.carts, sessionID)
+
+	return order, nil
+}
+
+// book looks up a book that is not in the trash, like the deletedAt: nil filters of MongoStore.
+// The caller holds s.mu.
+func (s *MemoryStore) book(isbn string) (model.Book, bool) {
+	bk, ok := s.books[isbn]
+	if !ok || bk.DeletedAt != nil {
+		return model.Book{}, false
+	}
+	return bk, true
+}
+
+func (s *MemoryStore) Order(_ context.Context, id primitive.ObjectID) (Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.orders[id]
+	if !ok {
+		return o, mongo.ErrNoDocuments
+	}
+	return o, nil
+}
@@ -0,0 +1,150 @@
+package shop
+
+import (
+	"context"
+	"crud-example/internal/model"
+	apperr "crud-example/pkg/util/app_err"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func newTestStore() *MemoryStore {
+	return NewMemoryStore(
+		model.Book{Isbn: "111", Title: "The Time Machine", Price: 10, Stock: 3},
+		model.Book{Isbn: "222", Title: "The War of the Worlds", Price: 12.5, Stock: 1},
+	)
+}
+
+func TestPlaceOrder(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore()
+
+	s.AddToCart(ctx, "alice", "111", 2)
+	s.AddToCart(ctx, "alice", "222", 1)
+
+	order, err := s.PlaceOrder(ctx, "alice")
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+
+	if order.Total != 32.5 {
+		t.Errorf("total = %v, want %v", order.Total, 32.5)
+	}
+	if got := s.Stock("111"); got != 1 {
+		t.Errorf("stock of 111 = %d, want %d", got, 1)
+	}
+	if got := s.Stock("222"); got != 0 {
+		t.Errorf("stock of 222 = %d, want %d", got, 0)
+	}
+
+	cart, _ := s.Cart(ctx, "alice")
+	if len(cart.Items) != 0 {
+		t.Errorf("cart still has %d items after checkout", len(cart.Items))
+	}
+
+	if _, err := s.Order(ctx, order.ID); err != nil {
+		t.Errorf("order was not saved: %v", err)
+	}
+}
+
+// A single line out of stock must leave every stock and the cart untouched
+func TestPlaceOrderIsAllOrNothing(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore()
+
+	s.AddToCart(ctx, "bob", "111", 2)
+	s.AddToCart(ctx, "bob", "222", 2)
+
+	_, err := s.PlaceOrder(ctx, "bob")
+	if !errors.Is(err, apperr.ErrOutOfStock) {
+		t.Fatalf("got error %v, want %v", err, apperr.ErrOutOfStock)
+	}
+
+	if got := s.Stock("111"); got != 3 {
+		t.Errorf("stock of 111 = %d, want %d", got, 3)
+	}
+	if got := s.Stock("222"); got != 1 {
+		t.Errorf("stock of 222 = %d, want %d", got, 1)
+	}
+
+	cart, _ := s.Cart(ctx, "bob")
+	if len(cart.Items) != 2 {
+		t.Errorf("cart has %d items, want %d", len(cart.Items), 2)
+	}
+}
+
+func TestPlaceOrderEmptyCart(t *testing.T) {
+	_, err := newTestStore().PlaceOrder(context.Background(), "nobody")
+	if !errors.Is(err, apperr.ErrEmptyCart) {
+		t.Errorf("got error %v, want %v", err, apperr.ErrEmptyCart)
+	}
+}
+
+func TestConcurrentOrdersNeverOversell(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore()
+
+	const buyers = 20
+	for i := 0; i < buyers; i++ {
+		s.AddToCart(ctx, fmt.Sprint("buyer-", i), "111", 1)
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		placed int
+	)
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func(session string) {
+			defer wg.Done()
+			if _, err := s.PlaceOrder(ctx, session); err == nil {
+				mu.Lock()
+				placed++
+				mu.Unlock()
+			}
+		}(fmt.Sprint("buyer-", i))
+	}
+	wg.Wait()
+
+	if placed != 3 {
+		t.Errorf("placed %d orders, want %d", placed, 3)
+	}
+	if got := s.Stock("111"); got != 0 {
+		t.Errorf("stock of 111 = %d, want %d", got, 0)
+	}
+}
+
+// A book in the trash can neither be added to a cart nor ordered from one
+func TestTrashedBookIsNotSold(t *testing.T) {
+	ctx := context.Background()
+	deleted := time.Now()
+	s := NewMemoryStore(
+		model.Book{Isbn: "111", Title: "The Time Machine", Price: 10, Stock: 3},
+		model.Book{Isbn: "333", Title: "The Invisible Man", Price: 8, Stock: 5, DeletedAt: &deleted},
+	)
+
+	if err := s.AddToCart(ctx, "alice", "333", 1); !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Errorf("adding a trashed book: got %v, want %v", err, mongo.ErrNoDocuments)
+	}
+
+	// Trashed after it was carted
+	s.AddToCart(ctx, "alice", "111", 1)
+	s.mu.Lock()
+	bk := s.books["111"]
+	bk.DeletedAt = &deleted
+	s.books["111"] = bk
+	s.mu.Unlock()
+
+	if _, err := s.PlaceOrder(ctx, "alice"); !errors.Is(err, apperr.ErrOutOfStock) {
+		t.Errorf("ordering a trashed book: got %v, want %v", err, apperr.ErrOutOfStock)
+	}
+	if got := s.Stock("111"); got != 3 {
+		t.Errorf("stock of 111 = %d, want %d", got, 3)
+	}
+}
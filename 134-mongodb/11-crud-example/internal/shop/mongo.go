@@ -0,0 +1,197 @@
+package shop
+
+import (
+	"context"
+	"crud-example/internal/db"
+	"crud-example/internal/model"
+	apperr "crud-example/pkg/util/app_err"
+	ctxhelper "crud-example/pkg/util/context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoStore applies the data layer deadline budgets on top of the caller's context.
+// It runs PlaceOrder in a multi-document transaction,
+// which needs MongoDB to run as a replica set (a single node one is enough).
+type MongoStore struct {
+}
+
+func NewMongoStore() *MongoStore {
+	return &MongoStore{}
+}
+
+func (s *MongoStore) Cart(ctx context.Context, sessionID string) (Cart, error) {
+	ctx, cancel := ctxhelper.ForRead(ctx)
+	defer cancel()
+
+	cart := Cart{SessionID: sessionID, Items: []CartItem{}}
+
+	err := carts().FindOne(ctx, bson.M{"_id": sessionID}).Decode(&cart)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return cart, err
+	}
+	if len(cart.Items) == 0 {
+		return cart, nil
+	}
+
+	isbns := make([]string, 0, len(cart.Items))
+	for _, it := range cart.Items {
+		isbns = append(isbns, it.Isbn)
+	}
+
+	cur, err := books().Find(ctx, bson.M{"isbn": bson.M{"$in": isbns}, "deletedAt": nil})
+	if err != nil {
+		return cart, err
+	}
+
+	var found []struct {
+		Isbn  string  `bson:"isbn"`
+		Title string  `bson:"title"`
+		Price float64 `bson:"price"`
+		Stock int     `bson:"stock"`
+	}
+	if err := cur.All(ctx, &found); err != nil {
+		return cart, err
+	}
+
+	for i := range cart.Items {
+		for _, bk := range found {
+			if bk.Isbn == cart.Items[i].Isbn {
+				cart.Items[i].Title, cart.Items[i].Price, cart.Items[i].Stock = bk.Title, bk.Price, bk.Stock
+			}
+		}
+	}
+
+	return cart, nil
+}
+
+func (s *MongoStore) AddToCart(ctx context.Context, sessionID, isbn string, qty int) error {
+	ctx, cancel := ctxhelper.ForWrite(ctx)
+	defer cancel()
+
+	if err := books().FindOne(ctx, bson.M{"isbn": isbn, "deletedAt": nil}).Err(); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	res, err := carts().UpdateOne(ctx,
+		bson.M{"_id": sessionID, "items.isbn": isbn},
+		bson.M{"$inc": bson.M{"items.$.qty": qty}, "$set": bson.M{"updatedAt": now}})
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		if qty <= 0 {
+			return nil
+		}
+		_, err = carts().UpdateOne(ctx,
+			bson.M{"_id": sessionID},
+			bson.M{"$push": bson.M{"items": CartItem{Isbn: isbn, Qty: qty}}, "$set": bson.M{"updatedAt": now}},
+			options.Update().SetUpsert(true))
+		return err
+	}
+
+	// Drop the line once it reached zero copies
+	_, err = carts().UpdateOne(ctx,
+		bson.M{"_id": sessionID},
+		bson.M{"$pull": bson.M{"items": bson.M{"qty": bson.M{"$lte": 0}}}})
+	return err
+}
+
+func (s *MongoStore) RemoveFromCart(ctx context.Context, sessionID, isbn string) error {
+	ctx, cancel := ctxhelper.ForWrite(ctx)
+	defer cancel()
+
+	_, err := carts().UpdateOne(ctx,
+		bson.M{"_id": sessionID},
+		bson.M{"$pull": bson.M{"items": bson.M{"isbn": isbn}}, "$set": bson.M{"updatedAt": time.Now().UTC()}})
+	return err
+}
+
+// PlaceOrder decrements the stock of every cart line, writes the order and empties the cart
+// inside one transaction. WithTransaction retries the callback on transient errors,
+// so the callback only relies on what it reads inside the transaction.
+func (s *MongoStore) PlaceOrder(ctx context.Context, sessionID string) (Order, error) {
+	// The budget covers every retry of the transaction
+	ctx, cancel := ctxhelper.ForWrite(ctx)
+	defer cancel()
+
+	sess, err := db.MongoClient.StartSession()
+	if err != nil {
+		return Order{}, err
+	}
+	defer sess.EndSession(ctx)
+
+	res, err := sess.WithTransaction(ctx, func(sc mongo.SessionContext) (any, error) {
+		cart := Cart{}
+		err := carts().FindOne(sc, bson.M{"_id": sessionID}).Decode(&cart)
+		if errors.Is(err, mongo.ErrNoDocuments) || (err == nil && len(cart.Items) == 0) {
+			return nil, apperr.ErrEmptyCart
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		items := make([]OrderItem, 0, len(cart.Items))
+		for _, it := range cart.Items {
+			bk := OrderItem{}
+			err := books().FindOneAndUpdate(sc,
+				bson.M{"isbn": it.Isbn, "deletedAt": nil, "stock": bson.M{"$gte": it.Qty}},
+				bson.M{"$inc": bson.M{"stock": -it.Qty}}).Decode(&bk)
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				// Returning an error aborts the transaction, earlier decrements are rolled back
+				return nil, fmt.Errorf("%w: %s", apperr.ErrOutOfStock, it.Isbn)
+			}
+			if err != nil {
+				return nil, err
+			}
+			bk.Qty = it.Qty
+			items = append(items, bk)
+		}
+
+		order := newOrder(sessionID, items)
+		if _, err := orders().InsertOne(sc, order); err != nil {
+			return nil, err
+		}
+		if _, err := carts().DeleteOne(sc, bson.M{"_id": sessionID}); err != nil {
+			return nil, err
+		}
+
+		return order, nil
+	})
+	if err != nil {
+		return Order{}, err
+	}
+
+	// Stock changed outside of the model package
+	model.InvalidateBookCache()
+	return res.(Order), nil
+}
+
+func (s *MongoStore) Order(ctx context.Context, id primitive.ObjectID) (Order, error) {
+	ctx, cancel := ctxhelper.ForRead(ctx)
+	defer cancel()
+
+	o := Order{}
+	err := orders().FindOne(ctx, bson.M{"_id": id}).Decode(&o)
+	return o, err
+}
+
+func books() *mongo.Collection {
+	return db.Database().Collection("books")
+}
+
+func carts() *mongo.Collection {
+	return db.Database().Collection("carts")
+}
+
+func orders() *mongo.Collection {
+	return db.Database().Collection("orders")
+}
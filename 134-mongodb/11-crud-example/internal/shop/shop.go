@@ -0,0 +1,71 @@
+package shop
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CartItem struct {
+	Isbn string `json:"isbn" bson:"isbn"`
+	Qty  int    `json:"qty" bson:"qty"`
+	// Filled in from the catalog when the cart is read, never stored
+	Title string  `json:"title" bson:"-"`
+	Price float64 `json:"price" bson:"-"`
+	Stock int     `json:"stock" bson:"-"`
+}
+
+type Cart struct {
+	SessionID string     `json:"sessionId" bson:"_id"`
+	Items     []CartItem `json:"items" bson:"items"`
+	UpdatedAt time.Time  `json:"updatedAt" bson:"updatedAt"`
+}
+
+func (c Cart) Total() float64 {
+	total := 0.0
+	for _, it := range c.Items {
+		total += it.Price * float64(it.Qty)
+	}
+	return total
+}
+
+// OrderItem keeps the title and price the book had when the order was placed
+type OrderItem struct {
+	Isbn  string  `json:"isbn" bson:"isbn"`
+	Title string  `json:"title" bson:"title"`
+	Price float64 `json:"price" bson:"price"`
+	Qty   int     `json:"qty" bson:"qty"`
+}
+
+type Order struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id"`
+	SessionID string             `json:"sessionId" bson:"sessionId"`
+	Items     []OrderItem        `json:"items" bson:"items"`
+	Total     float64            `json:"total" bson:"total"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+// Store keeps carts and orders. PlaceOrder must be atomic: either every stock decrement,
+// the order and the emptied cart are saved, or nothing is.
+type Store interface {
+	Cart(ctx context.Context, sessionID string) (Cart, error)
+	// AddToCart adds qty copies, a negative qty removes copies and the line goes away at zero
+	AddToCart(ctx context.Context, sessionID, isbn string, qty int) error
+	RemoveFromCart(ctx context.Context, sessionID, isbn string) error
+	PlaceOrder(ctx context.Context, sessionID string) (Order, error)
+	Order(ctx context.Context, id primitive.ObjectID) (Order, error)
+}
+
+func newOrder(sessionID string, items []OrderItem) Order {
+	o := Order{
+		ID:        primitive.NewObjectID(),
+		SessionID: sessionID,
+		Items:     items,
+		CreatedAt: time.Now().UTC(),
+	}
+	for _, it := range items {
+		o.Total += it.Price * float64(it.Qty)
+	}
+	return o
+}
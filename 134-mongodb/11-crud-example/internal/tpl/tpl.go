@@ -1,17 +1,18 @@
 package tpl
 
 import (
-	"log"
+	"fmt"
 	"path/filepath"
 	"text/template"
 )
 
 var Tpl *template.Template
 
-func LoadTemplates() {
+func LoadTemplates() error {
 	var err error
 	Tpl, err = template.ParseGlob(filepath.Join("web", "template", "*.gohtml"))
 	if err != nil {
-		log.Fatalf("Error parsing templates: %v\r\n", err)
+		return fmt.Errorf("parsing templates: %w", err)
 	}
+	return nil
 }
@@ -1,6 +1,7 @@
 package apperr
 
 import (
+	"context"
 	"errors"
 	"net/http"
 
@@ -22,6 +23,26 @@ func HandleHttpMongoErr(w http.ResponseWriter, r *http.Request, err error) {
 		http.NotFound(w, r)
 	case errors.Is(err, ErrNoItemFoundToUpdate):
 		http.NotFound(w, r)
+	case errors.Is(err, ErrNoItemFoundToRestore):
+		http.NotFound(w, r)
+	case errors.Is(err, ErrVersionConflict):
+		http.Error(w, http.StatusText(http.StatusConflict), http.StatusConflict)
+	case errors.Is(err, ErrAuthorNotFound), errors.Is(err, ErrPublisherNotFound):
+		HandleBadRequest(w, err.Error())
+	case errors.Is(err, ErrEmptyCart):
+		HandleBadRequest(w, err.Error())
+	case errors.Is(err, ErrOutOfStock):
+		http.Error(w, http.StatusText(http.StatusConflict)+" "+err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrAuthorInUse), errors.Is(err, ErrPublisherInUse):
+		http.Error(w, http.StatusText(http.StatusConflict)+" "+err.Error(), http.StatusConflict)
+	case mongo.IsDuplicateKeyError(err):
+		// e.g. the unique isbn index
+		http.Error(w, http.StatusText(http.StatusConflict)+" "+ErrAlreadyExists.Error(), http.StatusConflict)
+	case errors.Is(err, context.Canceled):
+		// The client went away, nobody reads the response
+	case mongo.IsTimeout(err):
+		// The operation ran out of its deadline budget
+		http.Error(w, http.StatusText(http.StatusGatewayTimeout), http.StatusGatewayTimeout)
 	default:
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 	}
@@ -38,3 +59,7 @@ func HandleNotAcceptable(w http.ResponseWriter, message string) {
 func HandleInternalServerError(w http.ResponseWriter, message string) {
 	http.Error(w, http.StatusText(http.StatusInternalServerError)+" "+message, http.StatusInternalServerError)
 }
+
+func HandlePreconditionRequired(w http.ResponseWriter, message string) {
+	http.Error(w, http.StatusText(http.StatusPreconditionRequired)+" "+message, http.StatusPreconditionRequired)
+}
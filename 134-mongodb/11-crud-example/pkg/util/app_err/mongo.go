@@ -7,8 +7,30 @@ import (
 
 // Define custom error variables
 var (
-	ErrNoItemFoundToDelete = errors.New("no item found to delete")
-	ErrNoItemFoundToUpdate = errors.New("no item found to update")
-	ErrDeleteItemFailed    = fmt.Errorf("failed to delete the item: %w", ErrNoItemFoundToDelete)
-	ErrUpdateItemFailed    = fmt.Errorf("failed to update the item: %w", ErrNoItemFoundToUpdate)
+	ErrNoItemFoundToDelete  = errors.New("no item found to delete")
+	ErrNoItemFoundToUpdate  = errors.New("no item found to update")
+	ErrNoItemFoundToRestore = errors.New("no item found to restore")
+	ErrDeleteItemFailed     = fmt.Errorf("failed to delete the item: %w", ErrNoItemFoundToDelete)
+	ErrUpdateItemFailed     = fmt.Errorf("failed to update the item: %w", ErrNoItemFoundToUpdate)
+	ErrRestoreItemFailed    = fmt.Errorf("failed to restore the item: %w", ErrNoItemFoundToRestore)
+	ErrAlreadyExists        = errors.New("an item with the same key already exists")
+)
+
+// Optimistic concurrency errors
+var (
+	ErrVersionConflict = errors.New("the item was modified by someone else")
+)
+
+// Referential integrity errors
+var (
+	ErrAuthorNotFound    = errors.New("author does not exist")
+	ErrPublisherNotFound = errors.New("publisher does not exist")
+	ErrAuthorInUse       = errors.New("author is still referenced by books")
+	ErrPublisherInUse    = errors.New("publisher is still referenced by books")
+)
+
+// Checkout errors
+var (
+	ErrOutOfStock = errors.New("not enough copies in stock")
+	ErrEmptyCart  = errors.New("the cart is empty")
 )
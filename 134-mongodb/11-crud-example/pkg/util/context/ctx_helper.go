@@ -5,10 +5,37 @@ import (
 	"time"
 )
 
-var (
-	CtxDefaultTimeout = 5 * time.Second
-)
+// Budgets are the deadlines of the data layer, one per kind of operation.
+// They are set once at startup from the configuration, see Configure.
+type Budgets struct {
+	Read  time.Duration // finds and aggregations returning a handful of documents
+	Write time.Duration // inserts, updates, deletes and transactions
+	Long  time.Duration // operations walking a whole collection, e.g. exports
+}
+
+var budgets = Budgets{
+	Read:  5 * time.Second,
+	Write: 5 * time.Second,
+	Long:  5 * time.Minute,
+}
+
+// Configure replaces the budgets, call it before serving requests
+func Configure(b Budgets) {
+	budgets = b
+}
+
+// The helpers below derive the context of one database operation from the caller's context,
+// usually the http request's. The operation ends at its budget, at an earlier deadline of ctx,
+// or as soon as ctx is cancelled, e.g. when the client goes away.
+
+func ForRead(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, budgets.Read)
+}
+
+func ForWrite(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, budgets.Write)
+}
 
-func CtxWithNormalTimeout() (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.Background(), CtxDefaultTimeout)
+func ForLong(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, budgets.Long)
 }
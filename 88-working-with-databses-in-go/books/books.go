@@ -0,0 +1,58 @@
+// Package books stores books behind BookRepo. SQLRepo writes the SQL by hand, GormRepo lets
+// GORM write it. Both behave the same, the tests run one suite against each.
+package books
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Book is a row of the books table
+type Book struct {
+	ID        uint      `gorm:"primaryKey"`
+	Title     string    `json:"title" gorm:"not null"`
+	Author    string    `json:"author" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Filter selects the books of List, ordered by ID. Zero values select everything.
+type Filter struct {
+	Author string
+	Limit  int
+	Offset int
+}
+
+type BookRepo interface {
+	// Create inserts b and sets its ID and CreatedAt
+	Create(ctx context.Context, b *Book) error
+	Get(ctx context.Context, id uint) (Book, error)
+	List(ctx context.Context, f Filter) ([]Book, error)
+	// Update changes the title and author of the book with b.ID
+	Update(ctx context.Context, b Book) error
+	Delete(ctx context.Context, id uint) error
+}
+
+var (
+	ErrNotFound = errors.New("book not found")
+	ErrInvalid  = errors.New("invalid book")
+)
+
+// validate is checked before either repo talks to the database, so both refuse the same books
+func validate(b Book) error {
+	switch {
+	case strings.TrimSpace(b.Title) == "":
+		return fmt.Errorf("%w: title is empty", ErrInvalid)
+	case strings.TrimSpace(b.Author) == "":
+		return fmt.Errorf("%w: author is empty", ErrInvalid)
+	}
+	return nil
+}
+
+// now is the creation time of a book. Postgres keeps microseconds, so both repos drop the rest
+// and a book reads back as it was written.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
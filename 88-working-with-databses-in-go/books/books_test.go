@@ -0,0 +1,157 @@
+package books
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// testRepo runs the same steps against a repo with an empty books table, the _test files of
+// each implementation call it
+func testRepo(t *testing.T, repo BookRepo) {
+	ctx := context.Background()
+
+	seed := []Book{
+		{Title: "The Go Programming Language", Author: "Donovan"},
+		{Title: "Go in Action", Author: "Kennedy"},
+		{Title: "Go Programming", Author: "John Doe"},
+		{Title: "Concurrency in Go", Author: "Cox-Buday"},
+		{Title: "Go Web Programming", Author: "John Doe"},
+	}
+	for i := range seed {
+		if err := repo.Create(ctx, &seed[i]); err != nil {
+			t.Fatalf("create %q: %v", seed[i].Title, err)
+		}
+		if seed[i].ID == 0 || seed[i].CreatedAt.IsZero() {
+			t.Fatalf("create didn't set ID and CreatedAt: %+v", seed[i])
+		}
+	}
+
+	t.Run("get", func(t *testing.T) {
+		got, err := repo.Get(ctx, seed[2].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertBook(t, got, seed[2])
+
+		if _, err := repo.Get(ctx, 1000); !errors.Is(err, ErrNotFound) {
+			t.Errorf("get missing: err = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("create invalid", func(t *testing.T) {
+		for _, b := range []Book{{Author: "Nobody"}, {Title: "Anonymous", Author: " "}} {
+			if err := repo.Create(ctx, &b); !errors.Is(err, ErrInvalid) {
+				t.Errorf("create %+v: err = %v, want ErrInvalid", b, err)
+			}
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		cases := []struct {
+			filter Filter
+			want   []Book
+		}{
+			{Filter{}, seed},
+			{Filter{Author: "John Doe"}, []Book{seed[2], seed[4]}},
+			{Filter{Limit: 2}, seed[:2]},
+			{Filter{Offset: 3}, seed[3:]},
+			{Filter{Limit: 2, Offset: 1}, seed[1:3]},
+			{Filter{Author: "John Doe", Offset: 1}, seed[4:]},
+			{Filter{Author: "Nobody"}, []Book{}},
+		}
+		for _, c := range cases {
+			got, err := repo.List(ctx, c.filter)
+			if err != nil {
+				t.Fatalf("%+v: %v", c.filter, err)
+			}
+			if got == nil || len(got) != len(c.want) {
+				t.Errorf("%+v: got %d books (%v), want %d", c.filter, len(got), got, len(c.want))
+				continue
+			}
+			for i := range got {
+				assertBook(t, got[i], c.want[i])
+			}
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		b := seed[1]
+		b.Title, b.Author = "Go in Action, Second Edition", "Kennedy, Ketelsen"
+		if err := repo.Update(ctx, b); err != nil {
+			t.Fatal(err)
+		}
+		got, err := repo.Get(ctx, b.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertBook(t, got, b)
+
+		if err := repo.Update(ctx, Book{ID: 1000, Title: "X", Author: "Y"}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("update missing: err = %v, want ErrNotFound", err)
+		}
+		if err := repo.Update(ctx, Book{ID: b.ID, Title: "X"}); !errors.Is(err, ErrInvalid) {
+			t.Errorf("update without author: err = %v, want ErrInvalid", err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := repo.Delete(ctx, seed[0].ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.Get(ctx, seed[0].ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("get deleted: err = %v, want ErrNotFound", err)
+		}
+		if err := repo.Delete(ctx, seed[0].ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("delete twice: err = %v, want ErrNotFound", err)
+		}
+		if got, _ := repo.List(ctx, Filter{}); len(got) != len(seed)-1 {
+			t.Errorf("%d books left, want %d", len(got), len(seed)-1)
+		}
+	})
+}
+
+func assertBook(t *testing.T, got, want Book) {
+	t.Helper()
+	if got.ID != want.ID || got.Title != want.Title || got.Author != want.Author || !got.CreatedAt.Equal(want.CreatedAt) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+// benchmarkRepo measures the common calls, newRepo returns a repo with an empty books table
+func benchmarkRepo(b *testing.B, newRepo func(b *testing.B) BookRepo) {
+	ctx := context.Background()
+
+	b.Run("create", func(b *testing.B) {
+		repo := newRepo(b)
+		b.ResetTimer()
+		for i := range b.N {
+			if err := repo.Create(ctx, &Book{Title: fmt.Sprint("Book ", i), Author: "Bench"}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	repo := newRepo(b)
+	for i := range 100 {
+		if err := repo.Create(ctx, &Book{Title: fmt.Sprint("Book ", i), Author: fmt.Sprint("Author ", i%10)}); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.Run("get", func(b *testing.B) {
+		for i := range b.N {
+			if _, err := repo.Get(ctx, uint(i%100+1)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("list", func(b *testing.B) {
+		for range b.N {
+			if _, err := repo.List(ctx, Filter{Author: "Author 3", Limit: 5}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
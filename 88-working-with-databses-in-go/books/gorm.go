@@ -0,0 +1,80 @@
+package books
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// GormRepo lets GORM build the queries, it needs the books table of AutoMigrate(&Book{})
+type GormRepo struct {
+	db *gorm.DB
+}
+
+func NewGormRepo(db *gorm.DB) *GormRepo {
+	return &GormRepo{db: db}
+}
+
+func (r *GormRepo) Create(ctx context.Context, b *Book) error {
+	if err := validate(*b); err != nil {
+		return err
+	}
+	// * GORM fills a zero CreatedAt with its own clock, set here to get the precision of SQLRepo
+	if b.CreatedAt.IsZero() {
+		b.CreatedAt = now()
+	}
+	return r.db.WithContext(ctx).Create(b).Error
+}
+
+func (r *GormRepo) Get(ctx context.Context, id uint) (Book, error) {
+	var b Book
+	err := r.db.WithContext(ctx).Take(&b, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Book{}, ErrNotFound
+	}
+	return b, err
+}
+
+func (r *GormRepo) List(ctx context.Context, f Filter) ([]Book, error) {
+	q := r.db.WithContext(ctx).Order("id")
+	if f.Author != "" {
+		q = q.Where("author = ?", f.Author)
+	}
+	if f.Limit > 0 {
+		q = q.Limit(f.Limit)
+	}
+	if f.Offset > 0 {
+		q = q.Offset(f.Offset)
+	}
+
+	books := []Book{}
+	if err := q.Find(&books).Error; err != nil {
+		return nil, err
+	}
+	return books, nil
+}
+
+func (r *GormRepo) Update(ctx context.Context, b Book) error {
+	if err := validate(b); err != nil {
+		return err
+	}
+	// * a map, as Updates with a struct skips zero fields and Save inserts a missing row
+	res := r.db.WithContext(ctx).Model(&Book{}).Where("id = ?", b.ID).
+		Updates(map[string]any{"title": b.Title, "author": b.Author})
+	return rowsAffected(res)
+}
+
+func (r *GormRepo) Delete(ctx context.Context, id uint) error {
+	return rowsAffected(r.db.WithContext(ctx).Delete(&Book{}, id))
+}
+
+func rowsAffected(res *gorm.DB) error {
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package books
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go/go-examples/133-postgres/17-typed-queries/dbq"
+	"math"
+	"strings"
+)
+
+// SQLRepo runs hand written SQL through database/sql. The queries use $n parameters and
+// RETURNING, they run on Postgres and SQLite alike.
+type SQLRepo struct {
+	db dbq.Querier
+}
+
+// NewSQLRepo takes a *sql.DB, a *sqldb.DB or a *sql.Tx
+func NewSQLRepo(db dbq.Querier) *SQLRepo {
+	return &SQLRepo{db: db}
+}
+
+func (r *SQLRepo) Create(ctx context.Context, b *Book) error {
+	if err := validate(*b); err != nil {
+		return err
+	}
+	if b.CreatedAt.IsZero() {
+		b.CreatedAt = now()
+	}
+
+	id, err := dbq.QueryOne[uint](ctx, r.db,
+		"INSERT INTO books (title, author, created_at) VALUES ($1, $2, $3) RETURNING id",
+		b.Title, b.Author, b.CreatedAt)
+	if err != nil {
+		return err
+	}
+	b.ID = id
+	return nil
+}
+
+func (r *SQLRepo) Get(ctx context.Context, id uint) (Book, error) {
+	b, err := dbq.QueryOne[Book](ctx, r.db, "SELECT id, title, author, created_at FROM books WHERE id = $1", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Book{}, ErrNotFound
+	}
+	return b, err
+}
+
+func (r *SQLRepo) List(ctx context.Context, f Filter) ([]Book, error) {
+	var query strings.Builder
+	var args []any
+	query.WriteString("SELECT id, title, author, created_at FROM books")
+	if f.Author != "" {
+		args = append(args, f.Author)
+		fmt.Fprintf(&query, " WHERE author = $%d", len(args))
+	}
+	query.WriteString(" ORDER BY id")
+
+	if f.Limit > 0 || f.Offset > 0 {
+		// * OFFSET needs a LIMIT in SQLite, and the "no limit" of Postgres (ALL) and SQLite (-1) differ
+		limit := f.Limit
+		if limit <= 0 {
+			limit = math.MaxInt64
+		}
+		args = append(args, limit, f.Offset)
+		fmt.Fprintf(&query, " LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	}
+	return dbq.QueryAll[Book](ctx, r.db, query.String(), args...)
+}
+
+func (r *SQLRepo) Update(ctx context.Context, b Book) error {
+	if err := validate(b); err != nil {
+		return err
+	}
+	res, err := dbq.Exec(ctx, r.db, "UPDATE books SET title = $1, author = $2 WHERE id = $3", b.Title, b.Author, b.ID)
+	return affected(res, err)
+}
+
+func (r *SQLRepo) Delete(ctx context.Context, id uint) error {
+	res, err := dbq.Exec(ctx, r.db, "DELETE FROM books WHERE id = $1", id)
+	return affected(res, err)
+}
+
+// affected turns an update or delete of no row into ErrNotFound
+func affected(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
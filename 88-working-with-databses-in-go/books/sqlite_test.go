@@ -0,0 +1,69 @@
+//go:build cgo
+
+package books
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// the table of createBooksTableIfNotExists in main.go, in SQLite
+const sqliteBooksTable = `CREATE TABLE books (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	title TEXT NOT NULL,
+	author TEXT NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)`
+
+func newSQLRepo(tb testing.TB) BookRepo {
+	db, err := sql.Open("sqlite3", "file:"+tb.TempDir()+"/books.db")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(sqliteBooksTable); err != nil {
+		tb.Fatal(err)
+	}
+	return NewSQLRepo(db)
+}
+
+func newGormRepo(tb testing.TB) BookRepo {
+	db, err := gorm.Open(sqlite.Open("file:"+tb.TempDir()+"/books.db"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent), // * it logs every "record not found" otherwise
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&Book{}); err != nil {
+		tb.Fatal(err)
+	}
+	return NewGormRepo(db)
+}
+
+func TestSQLRepo(t *testing.T) {
+	testRepo(t, newSQLRepo(t))
+}
+
+func TestGormRepo(t *testing.T) {
+	testRepo(t, newGormRepo(t))
+}
+
+func BenchmarkSQLRepo(b *testing.B) {
+	benchmarkRepo(b, func(b *testing.B) BookRepo { return newSQLRepo(b) })
+}
+
+func BenchmarkGormRepo(b *testing.B) {
+	benchmarkRepo(b, func(b *testing.B) BookRepo { return newGormRepo(b) })
+}